@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"os"
 	"os/signal"
 	"syscall"
 	"time"
@@ -42,6 +43,13 @@ func gracefulShutdown(apiServer *http.Server, done chan bool) {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		os.Exit(runMigrate(os.Args[2:], os.Stdout, os.Stderr))
+	}
+	if len(os.Args) > 1 && os.Args[1] == "reclassify" {
+		os.Exit(runReclassify(os.Args[2:], os.Stdout, os.Stderr))
+	}
+
 	server, err := server.NewServer()
 	if err != nil {
 		log.Fatalf("failed to initialize server: %v", err)
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"clawtivity/internal/database"
+)
+
+const migrateUsage = `usage: api migrate <command>
+
+commands:
+  status           list migrations and when each was applied
+  up [--dry-run]   apply pending migrations; --dry-run prints their SQL instead
+`
+
+// runMigrate handles "api migrate ..." against the database in
+// BLUEPRINT_DB_URL and returns the process exit code.
+func runMigrate(args []string, stdout, stderr io.Writer) int {
+	if len(args) == 0 {
+		fmt.Fprint(stderr, migrateUsage)
+		return 2
+	}
+
+	command := args[0]
+	flags := flag.NewFlagSet("migrate "+command, flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	dryRun := flags.Bool("dry-run", false, "print pending migration SQL without applying it")
+	if err := flags.Parse(args[1:]); err != nil {
+		return 2
+	}
+	if command != "status" && command != "up" {
+		fmt.Fprint(stderr, migrateUsage)
+		return 2
+	}
+
+	runner, err := database.NewMigrationRunner(os.Getenv("BLUEPRINT_DB_URL"))
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer runner.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
+
+	switch command {
+	case "status":
+		statuses, err := runner.Status(ctx)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to read migration status: %v\n", err)
+			return 1
+		}
+		fmt.Fprintf(stdout, "database: %s\n", runner.Target())
+		writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(writer, "VERSION\tNAME\tAPPLIED AT")
+		for _, status := range statuses {
+			appliedAt := "pending"
+			if status.AppliedAt != nil {
+				appliedAt = status.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(writer, "%s\t%s\t%s\n", status.Version, status.Name, appliedAt)
+		}
+		_ = writer.Flush()
+	case "up":
+		plans, err := runner.Up(ctx, *dryRun)
+		if err != nil {
+			fmt.Fprintf(stderr, "failed to apply migrations: %v\n", err)
+			return 1
+		}
+		if len(plans) == 0 {
+			fmt.Fprintln(stdout, "database is up to date")
+			return 0
+		}
+		for _, plan := range plans {
+			if !*dryRun {
+				fmt.Fprintf(stdout, "applied %s_%s\n", plan.Version, plan.Name)
+				continue
+			}
+			fmt.Fprintf(stdout, "-- %s_%s\n", plan.Version, plan.Name)
+			for _, statement := range plan.Statements {
+				fmt.Fprintf(stdout, "%s;\n", statement)
+			}
+		}
+	}
+	return 0
+}
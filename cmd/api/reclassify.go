@@ -0,0 +1,111 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"clawtivity/internal/database"
+	"clawtivity/internal/server"
+)
+
+const reclassifyUsage = `usage: api reclassify [flags]
+
+Re-run the current classifier rules over stored classifier signals. Provided
+categories, user corrections, explicit overrides that the stored signals no
+longer contain and activities stored without signal storage are skipped.
+
+flags:
+`
+
+// runReclassify handles "api reclassify ..." against the database in
+// BLUEPRINT_DB_URL and returns the process exit code.
+func runReclassify(args []string, stdout, stderr io.Writer) int {
+	flags := flag.NewFlagSet("reclassify", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	flags.Usage = func() {
+		fmt.Fprint(stderr, reclassifyUsage)
+		flags.PrintDefaults()
+	}
+	projects := flags.String("project", "", "comma-separated project slugs")
+	categories := flags.String("category", "", "comma-separated current categories")
+	from := flags.String("from", "", "inclusive RFC3339 lower bound on created_at")
+	to := flags.String("to", "", "exclusive RFC3339 upper bound on created_at")
+	dryRun := flags.Bool("dry-run", false, "report the changes without writing them")
+	batchSize := flags.Int("batch-size", 0, "activities per batch (default 500)")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if flags.NArg() > 0 {
+		flags.Usage()
+		return 2
+	}
+
+	db, err := database.New()
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to open database: %v\n", err)
+		return 1
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Minute)
+	defer cancel()
+
+	report, err := server.Reclassify(ctx, db, database.ReclassifyRequest{
+		Filters: database.ActivityFilters{
+			ProjectTags: splitFlagList(*projects),
+			Categories:  splitFlagList(*categories),
+			From:        *from,
+			To:          *to,
+		},
+		DryRun:    *dryRun,
+		BatchSize: *batchSize,
+	})
+	if err != nil {
+		fmt.Fprintf(stderr, "failed to reclassify activities: %v\n", err)
+		return 1
+	}
+
+	mode := "applied"
+	if report.DryRun {
+		mode = "dry run"
+	}
+	fmt.Fprintf(stdout, "%s: scanned %d, changed %d, skipped %d preserved and %d without signals\n",
+		mode, report.Scanned, report.Changed, report.SkippedPreserved, report.SkippedNoSignals)
+
+	names := map[string]struct{}{}
+	for category := range report.Before {
+		names[category] = struct{}{}
+	}
+	for category := range report.After {
+		names[category] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for category := range names {
+		sorted = append(sorted, category)
+	}
+	sort.Strings(sorted)
+
+	writer := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(writer, "CATEGORY\tBEFORE\tAFTER")
+	for _, category := range sorted {
+		fmt.Fprintf(writer, "%s\t%d\t%d\n", category, report.Before[category], report.After[category])
+	}
+	_ = writer.Flush()
+	return 0
+}
+
+func splitFlagList(value string) []string {
+	var values []string
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			values = append(values, trimmed)
+		}
+	}
+	return values
+}
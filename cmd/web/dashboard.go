@@ -180,6 +180,14 @@ func DashboardHandler(w http.ResponseWriter, _ *http.Request) {
           <label for="date-to">To</label>
           <input id="date-to" type="date" />
         </div>
+        <div>
+          <label for="currency-filter">Currency</label>
+          <select id="currency-filter">
+            <option value="USD">USD</option>
+            <option value="EUR">EUR</option>
+            <option value="GBP">GBP</option>
+          </select>
+        </div>
         <div class="full">
           <label for="refresh-btn">Action</label>
           <button id="refresh-btn" type="button">Refresh</button>
@@ -226,6 +234,7 @@ func DashboardHandler(w http.ResponseWriter, _ *http.Request) {
       model: document.getElementById('model-filter'),
       from: document.getElementById('date-from'),
       to: document.getElementById('date-to'),
+      currency: document.getElementById('currency-filter'),
       refresh: document.getElementById('refresh-btn'),
       statCount: document.getElementById('stat-count'),
       statIn: document.getElementById('stat-in'),
@@ -241,7 +250,7 @@ func DashboardHandler(w http.ResponseWriter, _ *http.Request) {
     }
 
     function formatCurrency(value) {
-      return new Intl.NumberFormat(undefined, { style: 'currency', currency: 'USD', maximumFractionDigits: 4 }).format(value || 0);
+      return new Intl.NumberFormat(undefined, { style: 'currency', currency: els.currency.value || 'USD', maximumFractionDigits: 4 }).format(value || 0);
     }
 
     function inDateRange(createdAt, from, to) {
@@ -262,6 +271,7 @@ func DashboardHandler(w http.ResponseWriter, _ *http.Request) {
       const params = new URLSearchParams();
       if (project) params.set('project', project);
       if (model) params.set('model', model);
+      params.set('currency', els.currency.value || 'USD');
       const res = await fetch('/api/activity/summary?' + params.toString());
       if (!res.ok) throw new Error('summary fetch failed');
       return await res.json();
@@ -271,6 +281,7 @@ func DashboardHandler(w http.ResponseWriter, _ *http.Request) {
       const params = new URLSearchParams();
       if (project) params.set('project', project);
       if (model) params.set('model', model);
+      params.set('currency', els.currency.value || 'USD');
       const res = await fetch('/api/activity?' + params.toString());
       if (!res.ok) throw new Error('activity fetch failed');
       return await res.json();
@@ -361,7 +372,7 @@ func DashboardHandler(w http.ResponseWriter, _ *http.Request) {
       }
       const rows = Object.entries(totals).sort((a, b) => b[1] - a[1]);
       if (rows.length === 0) {
-        els.costByProject.innerHTML = '<div class="cost-item"><span>No project costs yet</span><span>$0.00</span></div>';
+        els.costByProject.innerHTML = '<div class="cost-item"><span>No project costs yet</span><span>' + formatCurrency(0) + '</span></div>';
         return;
       }
       els.costByProject.innerHTML = rows.map(([project, cost]) => '<div class="cost-item"><span>' + project + '</span><strong>' + formatCurrency(cost) + '</strong></div>').join('');
@@ -391,13 +402,14 @@ func DashboardHandler(w http.ResponseWriter, _ *http.Request) {
         updateSelectOptions(state.allActivities);
         renderAll();
       } catch (err) {
-        els.timeline.innerHTML = '<div class="timeline-item">Failed to load activity data.</div>';
+        els.timeline.innerHTML = '<div class="timeline-item">Failed to load activity data. Non-USD currencies need exchange rates (POST /api/exchange-rates).</div>';
       }
     }
 
     els.refresh.addEventListener('click', refreshData);
     els.project.addEventListener('change', refreshData);
     els.model.addEventListener('change', refreshData);
+    els.currency.addEventListener('change', refreshData);
     els.from.addEventListener('change', renderAll);
     els.to.addEventListener('change', renderAll);
 
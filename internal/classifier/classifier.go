@@ -1,154 +1,175 @@
 package classifier
 
 import (
-	_ "embed"
-	"encoding/json"
-	"fmt"
 	"regexp"
 	"sort"
 	"strings"
 )
 
-//go:embed category_rules.json
-var embeddedRules []byte
-
-type categoryRule struct {
-	Keywords []string `json:"keywords"`
-	Tools    []string `json:"tools"`
+type Signals struct {
+	PromptText    string   `json:"prompt_text"`
+	AssistantText string   `json:"assistant_text"`
+	ToolsUsed     []string `json:"tools_used"`
 }
 
-type rules struct {
-	DefaultCategory string                  `json:"default_category"`
-	Categories      map[string]categoryRule `json:"categories"`
+var explicitCategoryPattern = regexp.MustCompile(`(?i)category\s*:\s*([a-z_]+)`)
+
+const minKeywordScore = 2
+
+// Label is one category of a classification with its normalized score.
+type Label struct {
+	Category string  `json:"category"`
+	Score    float64 `json:"score"`
 }
 
-type Signals struct {
-	PromptText    string
-	AssistantText string
-	ToolsUsed     []string
+// Classification is the primary category and reason Classify returns, plus
+// every category the signals support ranked with the primary first. Label
+// scores sum to 1.
+type Classification struct {
+	Category string  `json:"category"`
+	Reason   string  `json:"reason"`
+	Labels   []Label `json:"labels"`
 }
 
-var explicitCategoryPattern = regexp.MustCompile(`(?i)category\s*:\s*([a-z_]+)`)
-const minKeywordScore = 2
+const (
+	// assistantEvidenceWeight discounts assistant keyword hits against prompt
+	// keyword hits and tool matches when ranking labels.
+	assistantEvidenceWeight = 0.5
+	// minSecondaryEvidence is the weighted evidence a category besides the
+	// primary needs to become a label, e.g. one prompt keyword hit.
+	minSecondaryEvidence = 1.0
+)
 
-var loadedRules = mustLoadRules()
+// Classify uses the active rules and learned model; a concurrent reload or
+// retrain never mixes two of them within one call.
+func Classify(s Signals) (string, string) {
+	classification := ClassifyLabels(s)
+	return classification.Category, classification.Reason
+}
 
-func mustLoadRules() rules {
-	var r rules
-	if err := json.Unmarshal(embeddedRules, &r); err != nil {
-		panic(err)
-	}
-	if strings.TrimSpace(r.DefaultCategory) == "" {
-		r.DefaultCategory = "general"
+// ClassifyLabels is Classify with the ranked labels.
+func ClassifyLabels(s Signals) Classification {
+	return Explain(s).Decision
+}
+
+// rankLabels weighs prompt keyword hits, tool matches and discounted assistant
+// keyword hits per category, including ties the primary decision gives up on.
+// The primary always ranks first: it gets at least the top evidence, which
+// also covers overrides and learned predictions that have no keyword hits.
+func rankLabels(primary string, prompt KeywordTrace, tools ToolTrace, assistant KeywordTrace) []Label {
+	evidence := map[string]float64{}
+	for category, score := range prompt.Scores {
+		evidence[category] += float64(score)
 	}
-	if r.Categories == nil {
-		r.Categories = map[string]categoryRule{}
+	for category, matches := range tools.Matches {
+		evidence[category] += float64(len(matches))
 	}
-	if _, ok := r.Categories[r.DefaultCategory]; !ok {
-		r.Categories[r.DefaultCategory] = categoryRule{}
+	for category, score := range assistant.Scores {
+		evidence[category] += assistantEvidenceWeight * float64(score)
 	}
-	return r
-}
 
-func Classify(s Signals) (string, string) {
-	if category, ok := detectExplicitOverride(s); ok {
-		return category, fmt.Sprintf("explicit_override:category=%s", category)
+	top := 1.0
+	for _, value := range evidence {
+		top = max(top, value)
 	}
-
-	if category, score, ok := detectKeywordScore(strings.ToLower(s.PromptText)); ok {
-		return category, fmt.Sprintf("prompt_keyword_score:%s=%d", category, score)
+	labels := []Label{{Category: primary, Score: max(evidence[primary], top)}}
+	for category, value := range evidence {
+		if category == primary || value < minSecondaryEvidence {
+			continue
+		}
+		labels = append(labels, Label{Category: category, Score: value})
 	}
+	secondary := labels[1:]
+	sort.Slice(secondary, func(i, j int) bool {
+		if secondary[i].Score == secondary[j].Score {
+			return secondary[i].Category < secondary[j].Category
+		}
+		return secondary[i].Score > secondary[j].Score
+	})
 
-	if category, toolName, ok := detectToolSignal(s); ok {
-		return category, fmt.Sprintf("tool_signal:%s", toolName)
+	total := 0.0
+	for _, label := range labels {
+		total += label.Score
 	}
-
-	if category, score, ok := detectKeywordScore(strings.ToLower(s.AssistantText)); ok {
-		return category, fmt.Sprintf("assistant_keyword_score:%s=%d", category, score)
+	for i := range labels {
+		labels[i].Score /= total
 	}
-
-	return loadedRules.DefaultCategory, "fallback:insufficient_signals"
+	return labels
 }
 
-func detectExplicitOverride(s Signals) (string, bool) {
+func traceExplicitOverride(loadedRules Rules, s Signals) OverrideTrace {
 	joined := strings.ToLower(strings.TrimSpace(s.PromptText + "\n" + s.AssistantText))
 	if joined == "" {
-		return "", false
+		return OverrideTrace{}
 	}
 
 	match := explicitCategoryPattern.FindStringSubmatch(joined)
 	if len(match) < 2 {
-		return "", false
+		return OverrideTrace{}
 	}
 
 	candidate := strings.TrimSpace(match[1])
-	if _, ok := loadedRules.Categories[candidate]; !ok {
-		return "", false
-	}
-	return candidate, true
+	_, known := loadedRules.Categories[candidate]
+	return OverrideTrace{Candidate: candidate, Decided: known}
 }
 
-func detectToolSignal(s Signals) (string, string, bool) {
+// traceToolSignal records every category each used tool matches. The first
+// used tool that matches decides, with categories tried in name order.
+func traceToolSignal(loadedRules Rules, s Signals) ToolTrace {
+	trace := ToolTrace{Matches: map[string][]string{}}
+	categories := make([]string, 0, len(loadedRules.Categories))
+	for category := range loadedRules.Categories {
+		if category != loadedRules.DefaultCategory {
+			categories = append(categories, category)
+		}
+	}
+	sort.Strings(categories)
+
 	for _, rawTool := range s.ToolsUsed {
 		tool := strings.ToLower(strings.TrimSpace(rawTool))
 		if tool == "" {
 			continue
 		}
-		for category, rule := range loadedRules.Categories {
-			if category == loadedRules.DefaultCategory {
+		for _, category := range categories {
+			if !toolMatchesRule(tool, loadedRules.Categories[category]) {
 				continue
 			}
-			for _, expected := range rule.Tools {
-				candidate := strings.ToLower(strings.TrimSpace(expected))
-				if candidate == "" {
-					continue
-				}
-				if strings.Contains(tool, candidate) {
-					return category, tool, true
-				}
+			trace.Matches[category] = append(trace.Matches[category], tool)
+			if !trace.Decided {
+				trace.Category, trace.Tool, trace.Decided = category, tool, true
 			}
 		}
 	}
-
-	return "", "", false
+	return trace
 }
 
-func detectKeywordScore(text string) (string, int, bool) {
-	if strings.TrimSpace(text) == "" {
-		return "", 0, false
-	}
-
-	scores := map[string]int{}
-	for category, rule := range loadedRules.Categories {
-		if category == loadedRules.DefaultCategory {
-			continue
-		}
-		total := 0
-		for _, keyword := range rule.Keywords {
-			needle := strings.ToLower(strings.TrimSpace(keyword))
-			if needle == "" {
-				continue
-			}
-			if containsKeyword(text, needle) {
-				total++
-			}
-		}
-		if total > 0 {
-			scores[category] = total
+func toolMatchesRule(tool string, rule CategoryRule) bool {
+	for _, expected := range rule.Tools {
+		candidate := strings.ToLower(strings.TrimSpace(expected))
+		if candidate != "" && strings.Contains(tool, candidate) {
+			return true
 		}
 	}
+	return false
+}
 
-	if len(scores) == 0 {
-		return "", 0, false
+// traceKeywordScore scores text by the number of distinct keywords of each
+// category it contains. A single top category with at least minKeywordScore
+// hits decides; a tie for the top score decides nothing.
+func traceKeywordScore(loadedRules Rules, text string) KeywordTrace {
+	trace := KeywordTrace{Hits: keywordHits(loadedRules, text), Scores: map[string]int{}, MinScore: minKeywordScore}
+	if len(trace.Hits) == 0 {
+		return trace
 	}
 
 	type scored struct {
 		category string
 		score    int
 	}
-	all := make([]scored, 0, len(scores))
-	for category, score := range scores {
-		all = append(all, scored{category: category, score: score})
+	all := make([]scored, 0, len(trace.Hits))
+	for category, hits := range trace.Hits {
+		trace.Scores[category] = len(hits)
+		all = append(all, scored{category: category, score: len(hits)})
 	}
 	sort.Slice(all, func(i, j int) bool {
 		if all[i].score == all[j].score {
@@ -158,13 +179,45 @@ func detectKeywordScore(text string) (string, int, bool) {
 	})
 
 	if len(all) > 1 && all[0].score == all[1].score {
-		return "", 0, false
+		for _, candidate := range all {
+			if candidate.score == all[0].score {
+				trace.Tied = append(trace.Tied, candidate.category)
+			}
+		}
+		return trace
 	}
 	if all[0].score < minKeywordScore {
-		return "", 0, false
+		return trace
+	}
+
+	trace.Category, trace.Score, trace.Decided = all[0].category, all[0].score, true
+	return trace
+}
+
+// keywordHits lists the keywords of each category found in text, in rule
+// order; categories without hits are left out.
+func keywordHits(loadedRules Rules, text string) map[string][]string {
+	hits := map[string][]string{}
+	text = strings.ToLower(text)
+	if strings.TrimSpace(text) == "" {
+		return hits
 	}
 
-	return all[0].category, all[0].score, true
+	for category, rule := range loadedRules.Categories {
+		if category == loadedRules.DefaultCategory {
+			continue
+		}
+		for _, keyword := range rule.Keywords {
+			needle := strings.ToLower(strings.TrimSpace(keyword))
+			if needle == "" {
+				continue
+			}
+			if containsKeyword(text, needle) {
+				hits[category] = append(hits[category], needle)
+			}
+		}
+	}
+	return hits
 }
 
 func containsKeyword(text, keyword string) bool {
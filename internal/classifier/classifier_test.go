@@ -1,6 +1,10 @@
 package classifier
 
-import "testing"
+import (
+	"math"
+	"strings"
+	"testing"
+)
 
 func TestClassifyExplicitOverrideWins(t *testing.T) {
 	gotCategory, gotReason := Classify(Signals{
@@ -116,3 +120,48 @@ func TestClassifyOpenClawReleasePromptMapsToAdmin(t *testing.T) {
 		t.Fatal("expected reason")
 	}
 }
+
+func TestClassifyLabelsRanksSecondaryCategories(t *testing.T) {
+	got := ClassifyLabels(Signals{
+		PromptText: "fix the bug and update the docs",
+		ToolsUsed:  []string{"edit_file"},
+	})
+
+	if got.Category != "code" || got.Reason != "tool_signal:edit_file" {
+		t.Fatalf("expected code from tool signal, got %q %q", got.Category, got.Reason)
+	}
+	want := []Label{{Category: "code", Score: 0.6}, {Category: "admin", Score: 0.4}}
+	if len(got.Labels) != len(want) {
+		t.Fatalf("expected labels %v, got %v", want, got.Labels)
+	}
+	for i := range want {
+		if got.Labels[i].Category != want[i].Category || math.Abs(got.Labels[i].Score-want[i].Score) > 1e-9 {
+			t.Fatalf("expected labels %v, got %v", want, got.Labels)
+		}
+	}
+}
+
+func TestClassifyLabelsKeepsTiedCategoriesBehindFallback(t *testing.T) {
+	got := ClassifyLabels(Signals{PromptText: "fix the bug and update the docs"})
+
+	if got.Category != "general" {
+		t.Fatalf("expected tie to fall back to general, got %q", got.Category)
+	}
+	categories := []string{}
+	total := 0.0
+	for _, label := range got.Labels {
+		categories = append(categories, label.Category)
+		total += label.Score
+	}
+	if strings.Join(categories, ",") != "general,admin,code" || math.Abs(total-1) > 1e-9 {
+		t.Fatalf("expected general first then the tied categories, got %v", got.Labels)
+	}
+}
+
+func TestClassifyLabelsSingleLabelWithoutEvidence(t *testing.T) {
+	got := ClassifyLabels(Signals{PromptText: "category: research"})
+
+	if len(got.Labels) != 1 || got.Labels[0] != (Label{Category: "research", Score: 1}) {
+		t.Fatalf("expected a single research label, got %v", got.Labels)
+	}
+}
@@ -0,0 +1,106 @@
+package classifier
+
+import "fmt"
+
+// OverrideTrace is the explicit "category: name" check over prompt and
+// assistant text. Candidate is the first named category, which only decides
+// when the rules define it.
+type OverrideTrace struct {
+	Candidate string `json:"candidate,omitempty"`
+	Decided   bool   `json:"decided"`
+	Applied   bool   `json:"applied"`
+}
+
+// LearnedTrace is the learned model stage. Enabled reports whether the model
+// has enough examples over at least two categories to predict at all.
+type LearnedTrace struct {
+	Enabled       bool    `json:"enabled"`
+	Examples      int     `json:"examples"`
+	MinExamples   int     `json:"min_examples"`
+	Category      string  `json:"category,omitempty"`
+	Confidence    float64 `json:"confidence,omitempty"`
+	MinConfidence float64 `json:"min_confidence"`
+	Decided       bool    `json:"decided"`
+	Applied       bool    `json:"applied"`
+}
+
+// KeywordTrace is a keyword scoring stage over prompt or assistant text. Hits
+// lists the matched keywords per category; Tied lists the categories sharing
+// the top score when a tie kept the stage from deciding.
+type KeywordTrace struct {
+	Hits     map[string][]string `json:"hits"`
+	Scores   map[string]int      `json:"scores"`
+	MinScore int                 `json:"min_score"`
+	Tied     []string            `json:"tied,omitempty"`
+	Category string              `json:"category,omitempty"`
+	Score    int                 `json:"score,omitempty"`
+	Decided  bool                `json:"decided"`
+	Applied  bool                `json:"applied"`
+}
+
+// ToolTrace is the tool signal stage. Matches lists the used tools matching
+// each category; Tool is the one that decided.
+type ToolTrace struct {
+	Matches  map[string][]string `json:"matches"`
+	Tool     string              `json:"tool,omitempty"`
+	Category string              `json:"category,omitempty"`
+	Decided  bool                `json:"decided"`
+	Applied  bool                `json:"applied"`
+}
+
+// Explanation is the full decision trace of Classify. Every stage is
+// evaluated; Decided marks stages that could decide on their own and Applied
+// the first of them, which produced Decision. With none, Decision is the
+// default category.
+type Explanation struct {
+	RulesSource string         `json:"rules_source"`
+	Override    OverrideTrace  `json:"explicit_override"`
+	Learned     LearnedTrace   `json:"learned_model"`
+	Prompt      KeywordTrace   `json:"prompt_keywords"`
+	Tools       ToolTrace      `json:"tool_signal"`
+	Assistant   KeywordTrace   `json:"assistant_keywords"`
+	Decision    Classification `json:"decision"`
+}
+
+// Explain classifies s like Classify and returns how each stage voted.
+func Explain(s Signals) Explanation {
+	ruleSet := activeRules.Load()
+	return explain(ruleSet.Source, ruleSet.Rules, LearnedModel(), s)
+}
+
+func explain(source string, loadedRules Rules, model *NaiveBayes, s Signals) Explanation {
+	e := Explanation{
+		RulesSource: source,
+		Override:    traceExplicitOverride(loadedRules, s),
+		Learned:     traceLearnedModel(loadedRules, model, s),
+		Prompt:      traceKeywordScore(loadedRules, s.PromptText),
+		Tools:       traceToolSignal(loadedRules, s),
+		Assistant:   traceKeywordScore(loadedRules, s.AssistantText),
+	}
+
+	category, reason := loadedRules.DefaultCategory, "fallback:insufficient_signals"
+	switch {
+	case e.Override.Decided:
+		e.Override.Applied = true
+		category, reason = e.Override.Candidate, fmt.Sprintf("explicit_override:category=%s", e.Override.Candidate)
+	case e.Learned.Decided:
+		e.Learned.Applied = true
+		category, reason = e.Learned.Category, fmt.Sprintf("learned_model:%s=%.2f", e.Learned.Category, e.Learned.Confidence)
+	case e.Prompt.Decided:
+		e.Prompt.Applied = true
+		category, reason = e.Prompt.Category, fmt.Sprintf("prompt_keyword_score:%s=%d", e.Prompt.Category, e.Prompt.Score)
+	case e.Tools.Decided:
+		e.Tools.Applied = true
+		category, reason = e.Tools.Category, fmt.Sprintf("tool_signal:%s", e.Tools.Tool)
+	case e.Assistant.Decided:
+		e.Assistant.Applied = true
+		category, reason = e.Assistant.Category, fmt.Sprintf("assistant_keyword_score:%s=%d", e.Assistant.Category, e.Assistant.Score)
+	}
+
+	e.Decision = Classification{
+		Category: category,
+		Reason:   reason,
+		Labels:   rankLabels(category, e.Prompt, e.Tools, e.Assistant),
+	}
+	return e
+}
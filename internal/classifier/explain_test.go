@@ -0,0 +1,63 @@
+package classifier
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExplainReportsTieAndToolDecision(t *testing.T) {
+	got := Explain(Signals{
+		PromptText:    "fix the bug and update the docs",
+		AssistantText: "patched and documented",
+		ToolsUsed:     []string{"Edit_File", "web_search"},
+	})
+
+	if got.RulesSource != EmbeddedSource || got.Override.Decided || got.Learned.Enabled {
+		t.Fatalf("expected no override or learned model, got %#v", got)
+	}
+	wantHits := map[string][]string{"admin": {"update", "docs"}, "code": {"bug", "fix"}}
+	if !reflect.DeepEqual(got.Prompt.Hits, wantHits) {
+		t.Fatalf("expected prompt hits %v, got %v", wantHits, got.Prompt.Hits)
+	}
+	if got.Prompt.Decided || !reflect.DeepEqual(got.Prompt.Tied, []string{"admin", "code"}) {
+		t.Fatalf("expected a prompt tie between admin and code, got %#v", got.Prompt)
+	}
+	wantMatches := map[string][]string{"code": {"edit_file"}, "research": {"web_search"}}
+	if !reflect.DeepEqual(got.Tools.Matches, wantMatches) {
+		t.Fatalf("expected tool matches %v, got %v", wantMatches, got.Tools.Matches)
+	}
+	if !got.Tools.Applied || got.Tools.Tool != "edit_file" || got.Tools.Category != "code" {
+		t.Fatalf("expected the first matching tool to decide, got %#v", got.Tools)
+	}
+	if len(got.Assistant.Hits) != 0 || got.Assistant.Applied {
+		t.Fatalf("expected no assistant hits, got %#v", got.Assistant)
+	}
+
+	category, reason := Classify(Signals{
+		PromptText:    "fix the bug and update the docs",
+		AssistantText: "patched and documented",
+		ToolsUsed:     []string{"Edit_File", "web_search"},
+	})
+	if got.Decision.Category != category || got.Decision.Reason != reason || reason != "tool_signal:edit_file" {
+		t.Fatalf("expected the decision to match Classify, got %#v vs %q %q", got.Decision, category, reason)
+	}
+}
+
+func TestExplainMarksOnlyTheFirstDecidingStageApplied(t *testing.T) {
+	got := Explain(Signals{PromptText: "category: research then fix the bug and run the build"})
+
+	if !got.Override.Applied || got.Override.Candidate != "research" {
+		t.Fatalf("expected the override to apply, got %#v", got.Override)
+	}
+	if !got.Prompt.Decided || got.Prompt.Applied || got.Prompt.Category != "code" || got.Prompt.Score != 3 {
+		t.Fatalf("expected prompt keywords to decide code without applying, got %#v", got.Prompt)
+	}
+	if got.Decision.Reason != "explicit_override:category=research" {
+		t.Fatalf("unexpected decision %#v", got.Decision)
+	}
+
+	got = Explain(Signals{PromptText: "category: astrology"})
+	if got.Override.Candidate != "astrology" || got.Override.Decided || got.Decision.Reason != "fallback:insufficient_signals" {
+		t.Fatalf("expected an unknown override candidate to fall through, got %#v", got)
+	}
+}
@@ -0,0 +1,199 @@
+package classifier
+
+import (
+	"math"
+	"regexp"
+	"sort"
+	"strings"
+	"sync/atomic"
+)
+
+// Example is one labelled prompt the learned model trains on, typically a
+// user-corrected activity and its stored prompt tokens.
+type Example struct {
+	Category string
+	Tokens   []string
+}
+
+// NaiveBayes is a multinomial Naive Bayes model over prompt tokens with
+// Laplace smoothing. A trained model is never mutated; retraining replaces it.
+type NaiveBayes struct {
+	examples   int
+	classes    map[string]*naiveBayesClass
+	vocabulary map[string]struct{}
+}
+
+type naiveBayesClass struct {
+	documents   int
+	tokenCounts map[string]int
+	tokenTotal  int
+}
+
+const (
+	// The learned stage stays silent until it has seen this many corrections
+	// spread over at least two categories.
+	minLearnedExamples = 5
+	// minLearnedConfidence is the posterior probability a prediction needs to
+	// be used instead of falling through to keyword scoring.
+	minLearnedConfidence = 0.7
+	minTokenLength       = 3
+)
+
+var tokenPattern = regexp.MustCompile(`[a-z0-9_]+`)
+
+var tokenStopwords = map[string]struct{}{
+	"and": {}, "are": {}, "but": {}, "can": {}, "for": {}, "from": {},
+	"has": {}, "have": {}, "how": {}, "into": {}, "its": {}, "not": {},
+	"please": {}, "that": {}, "the": {}, "then": {}, "this": {}, "was": {},
+	"what": {}, "when": {}, "where": {}, "which": {}, "will": {}, "with": {},
+	"you": {}, "your": {},
+}
+
+var learnedModel atomic.Pointer[NaiveBayes]
+
+// Tokenize returns the distinct lowercase word tokens of text in order of
+// first appearance, without stopwords and very short words. These are the
+// prompt features stored per activity and used by the learned model.
+func Tokenize(text string) []string {
+	seen := map[string]struct{}{}
+	tokens := []string{}
+	for _, token := range tokenPattern.FindAllString(strings.ToLower(text), -1) {
+		if len(token) < minTokenLength {
+			continue
+		}
+		if _, ok := tokenStopwords[token]; ok {
+			continue
+		}
+		if _, ok := seen[token]; ok {
+			continue
+		}
+		seen[token] = struct{}{}
+		tokens = append(tokens, token)
+	}
+	return tokens
+}
+
+// TrainNaiveBayes builds a model from examples. Examples without a category
+// or without tokens are skipped.
+func TrainNaiveBayes(examples []Example) *NaiveBayes {
+	model := &NaiveBayes{
+		classes:    map[string]*naiveBayesClass{},
+		vocabulary: map[string]struct{}{},
+	}
+	for _, example := range examples {
+		category := strings.ToLower(strings.TrimSpace(example.Category))
+		if category == "" || len(example.Tokens) == 0 {
+			continue
+		}
+		class, ok := model.classes[category]
+		if !ok {
+			class = &naiveBayesClass{tokenCounts: map[string]int{}}
+			model.classes[category] = class
+		}
+		model.examples++
+		class.documents++
+		for _, token := range example.Tokens {
+			class.tokenCounts[token]++
+			class.tokenTotal++
+			model.vocabulary[token] = struct{}{}
+		}
+	}
+	return model
+}
+
+// Examples reports how many examples the model was trained on.
+func (m *NaiveBayes) Examples() int {
+	if m == nil {
+		return 0
+	}
+	return m.examples
+}
+
+// Categories lists the categories the model can predict, sorted.
+func (m *NaiveBayes) Categories() []string {
+	if m == nil {
+		return nil
+	}
+	categories := make([]string, 0, len(m.classes))
+	for category := range m.classes {
+		categories = append(categories, category)
+	}
+	sort.Strings(categories)
+	return categories
+}
+
+// Predict returns the most probable category for tokens and its posterior
+// probability. It reports false when none of the tokens were seen in training,
+// since the prediction would only reflect the class priors.
+func (m *NaiveBayes) Predict(tokens []string) (string, float64, bool) {
+	if m == nil || len(m.classes) == 0 {
+		return "", 0, false
+	}
+
+	known := make([]string, 0, len(tokens))
+	for _, token := range tokens {
+		if _, ok := m.vocabulary[token]; ok {
+			known = append(known, token)
+		}
+	}
+	if len(known) == 0 {
+		return "", 0, false
+	}
+
+	vocabularySize := float64(len(m.vocabulary))
+	logScores := make(map[string]float64, len(m.classes))
+	best := ""
+	for _, category := range m.Categories() {
+		class := m.classes[category]
+		score := math.Log(float64(class.documents) / float64(m.examples))
+		denominator := float64(class.tokenTotal) + vocabularySize
+		for _, token := range known {
+			score += math.Log((float64(class.tokenCounts[token]) + 1) / denominator)
+		}
+		logScores[category] = score
+		if best == "" || score > logScores[best] {
+			best = category
+		}
+	}
+
+	// Normalize with log-sum-exp so the confidence is a probability.
+	total := 0.0
+	for _, score := range logScores {
+		total += math.Exp(score - logScores[best])
+	}
+	return best, 1 / total, true
+}
+
+// SetLearnedModel makes model the learned stage of Classify; nil disables it.
+func SetLearnedModel(model *NaiveBayes) {
+	learnedModel.Store(model)
+}
+
+// LearnedModel returns the model Classify currently uses, or nil.
+func LearnedModel() *NaiveBayes {
+	return learnedModel.Load()
+}
+
+// traceLearnedModel predicts a category from the prompt tokens. It decides
+// only with enough training data and confidence, and never for a category
+// the active rules no longer define.
+func traceLearnedModel(loadedRules Rules, model *NaiveBayes, s Signals) LearnedTrace {
+	trace := LearnedTrace{
+		Examples:      model.Examples(),
+		MinExamples:   minLearnedExamples,
+		MinConfidence: minLearnedConfidence,
+	}
+	if model == nil || trace.Examples < minLearnedExamples || len(model.classes) < 2 {
+		return trace
+	}
+	trace.Enabled = true
+
+	category, confidence, ok := model.Predict(Tokenize(s.PromptText))
+	if !ok {
+		return trace
+	}
+	trace.Category, trace.Confidence = category, confidence
+	_, known := loadedRules.Categories[category]
+	trace.Decided = known && confidence >= minLearnedConfidence
+	return trace
+}
@@ -0,0 +1,75 @@
+package classifier
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func useLearnedModel(t *testing.T, examples []Example) {
+	t.Helper()
+	SetLearnedModel(TrainNaiveBayes(examples))
+	t.Cleanup(func() { SetLearnedModel(nil) })
+}
+
+func correctionExamples() []Example {
+	return []Example{
+		{Category: "research", Tokens: Tokenize("summarize the quarterly vendor landscape")},
+		{Category: "research", Tokens: Tokenize("vendor landscape for observability tools")},
+		{Category: "research", Tokens: Tokenize("collect vendor pricing pages into a landscape")},
+		{Category: "admin", Tokens: Tokenize("rotate the staging credentials")},
+		{Category: "admin", Tokens: Tokenize("rotate credentials for the billing account")},
+		{Category: "admin", Tokens: Tokenize("renew staging certificates and credentials")},
+	}
+}
+
+func TestTokenizeDropsStopwordsShortWordsAndDuplicates(t *testing.T) {
+	got := Tokenize("Please fix the FLAKY test, then fix it again: flaky_test #42 go")
+	want := []string{"fix", "flaky", "test", "again", "flaky_test"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected %v, got %v", want, got)
+	}
+}
+
+func TestNaiveBayesPredictsCorrectedCategory(t *testing.T) {
+	model := TrainNaiveBayes(correctionExamples())
+	if model.Examples() != 6 {
+		t.Fatalf("expected 6 examples, got %d", model.Examples())
+	}
+	if got := model.Categories(); !reflect.DeepEqual(got, []string{"admin", "research"}) {
+		t.Fatalf("unexpected categories %v", got)
+	}
+
+	category, confidence, ok := model.Predict(Tokenize("map the vendor landscape"))
+	if !ok || category != "research" || confidence < minLearnedConfidence || confidence > 1 {
+		t.Fatalf("expected confident research prediction, got %q %.3f %v", category, confidence, ok)
+	}
+
+	if _, _, ok := model.Predict(Tokenize("completely unrelated words")); ok {
+		t.Fatal("expected no prediction without known tokens")
+	}
+}
+
+func TestClassifyLearnedModelRunsBeforeKeywords(t *testing.T) {
+	useLearnedModel(t, correctionExamples())
+
+	// Keyword scoring alone would pick research here.
+	category, reason := Classify(Signals{PromptText: "research and compare how to rotate credentials"})
+	if category != "admin" || !strings.HasPrefix(reason, "learned_model:admin=") {
+		t.Fatalf("expected learned admin, got %q %q", category, reason)
+	}
+
+	category, reason = Classify(Signals{PromptText: "rotate credentials, category: code"})
+	if category != "code" || reason != "explicit_override:category=code" {
+		t.Fatalf("expected explicit override to win, got %q %q", category, reason)
+	}
+}
+
+func TestClassifyIgnoresLearnedModelWithTooFewExamples(t *testing.T) {
+	useLearnedModel(t, correctionExamples()[2:4])
+
+	category, reason := Classify(Signals{PromptText: "rotate credentials"})
+	if strings.HasPrefix(reason, "learned_model:") {
+		t.Fatalf("expected learned stage to stay silent, got %q %q", category, reason)
+	}
+}
@@ -0,0 +1,170 @@
+package classifier
+
+import (
+	"bytes"
+	"context"
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+//go:embed category_rules.json
+var embeddedRules []byte
+
+// EmbeddedSource is the RuleSet source of the rules compiled into the binary.
+const EmbeddedSource = "embedded"
+
+type CategoryRule struct {
+	Keywords []string `json:"keywords"`
+	Tools    []string `json:"tools"`
+}
+
+// Rules is the category_rules.json format. Rules in use are never mutated;
+// reloading replaces them as a whole.
+type Rules struct {
+	DefaultCategory string                  `json:"default_category"`
+	Categories      map[string]CategoryRule `json:"categories"`
+}
+
+// RuleSet describes the active rules and where they came from. Error is set
+// when a configured rules file was rejected and the embedded rules are used
+// instead.
+type RuleSet struct {
+	Source   string    `json:"source"`
+	LoadedAt time.Time `json:"loaded_at"`
+	Error    string    `json:"error,omitempty"`
+	Rules
+}
+
+var categoryNamePattern = regexp.MustCompile(`^[a-z_]+$`)
+
+var defaultRules = mustParseRules(embeddedRules)
+
+var activeRules atomic.Pointer[RuleSet]
+
+func init() {
+	UseEmbeddedRules()
+}
+
+func mustParseRules(data []byte) Rules {
+	r, err := ParseRules(data)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+// ActiveRules returns the rules Classify currently uses.
+func ActiveRules() RuleSet {
+	return *activeRules.Load()
+}
+
+// ParseRules decodes and validates a rules file. Category names must match
+// what an explicit "category: name" override can select, and at least one
+// category besides the default is required.
+func ParseRules(data []byte) (Rules, error) {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+
+	var r Rules
+	if err := decoder.Decode(&r); err != nil {
+		return Rules{}, fmt.Errorf("invalid rules json: %w", err)
+	}
+
+	r.DefaultCategory = strings.TrimSpace(r.DefaultCategory)
+	if r.DefaultCategory == "" {
+		r.DefaultCategory = "general"
+	}
+	if r.Categories == nil {
+		r.Categories = map[string]CategoryRule{}
+	}
+	if _, ok := r.Categories[r.DefaultCategory]; !ok {
+		r.Categories[r.DefaultCategory] = CategoryRule{}
+	}
+
+	for name, rule := range r.Categories {
+		if !categoryNamePattern.MatchString(name) {
+			return Rules{}, fmt.Errorf("category %q: names must be lowercase letters and underscores", name)
+		}
+		for _, values := range [][]string{rule.Keywords, rule.Tools} {
+			for _, value := range values {
+				if strings.TrimSpace(value) == "" {
+					return Rules{}, fmt.Errorf("category %q: keywords and tools must not be blank", name)
+				}
+			}
+		}
+	}
+	if len(r.Categories) < 2 {
+		return Rules{}, fmt.Errorf("rules need at least one category besides %q", r.DefaultCategory)
+	}
+	return r, nil
+}
+
+// UseEmbeddedRules switches back to the rules compiled into the binary.
+func UseEmbeddedRules() {
+	activeRules.Store(&RuleSet{Source: EmbeddedSource, LoadedAt: time.Now().UTC(), Rules: defaultRules})
+}
+
+// LoadRulesFile makes the rules in path active. When the file cannot be read
+// or is invalid the embedded rules become active instead and the error is
+// returned and reported by ActiveRules.
+func LoadRulesFile(path string) error {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		var r Rules
+		if r, err = ParseRules(data); err == nil {
+			activeRules.Store(&RuleSet{Source: path, LoadedAt: time.Now().UTC(), Rules: r})
+			return nil
+		}
+	}
+
+	err = fmt.Errorf("classifier rules %s: %w", path, err)
+	activeRules.Store(&RuleSet{Source: EmbeddedSource, LoadedAt: time.Now().UTC(), Error: err.Error(), Rules: defaultRules})
+	return err
+}
+
+// WatchRulesFile polls path every interval until ctx is done and reloads it
+// with LoadRulesFile whenever its size or modification time changes,
+// including when it is removed or recreated. onReload receives the outcome
+// of each reload.
+func WatchRulesFile(ctx context.Context, path string, interval time.Duration, onReload func(RuleSet, error)) {
+	last := statRulesFile(path)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			current := statRulesFile(path)
+			if current == last {
+				continue
+			}
+			last = current
+			err := LoadRulesFile(path)
+			if onReload != nil {
+				onReload(ActiveRules(), err)
+			}
+		}
+	}
+}
+
+type rulesFileState struct {
+	exists  bool
+	size    int64
+	modTime time.Time
+}
+
+func statRulesFile(path string) rulesFileState {
+	info, err := os.Stat(path)
+	if err != nil {
+		return rulesFileState{}
+	}
+	return rulesFileState{exists: true, size: info.Size(), modTime: info.ModTime()}
+}
@@ -0,0 +1,112 @@
+package classifier
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+const deployRules = `{
+  "default_category": "general",
+  "categories": {
+    "deploy": {"keywords": ["deploy", "rollout", "helm"], "tools": ["kubectl"]}
+  }
+}`
+
+func TestParseRulesRejectsInvalidFiles(t *testing.T) {
+	for name, data := range map[string]string{
+		"malformed":       `{"categories": `,
+		"unknown field":   `{"categories": {"code": {"keywords": ["x"]}}, "fallback": "general"}`,
+		"only default":    `{"default_category": "general", "categories": {"general": {}}}`,
+		"bad name":        `{"categories": {"Code Review": {"keywords": ["review"]}}}`,
+		"blank keyword":   `{"categories": {"code": {"keywords": ["fix", " "]}}}`,
+		"trailing commas": `{"categories": {"code": {"keywords": ["fix",]}}}`,
+	} {
+		if _, err := ParseRules([]byte(data)); err == nil {
+			t.Fatalf("%s: expected rules to be rejected", name)
+		}
+	}
+
+	r, err := ParseRules([]byte(deployRules))
+	if err != nil {
+		t.Fatalf("expected rules to parse: %v", err)
+	}
+	if _, ok := r.Categories["general"]; !ok || len(r.Categories) != 2 {
+		t.Fatalf("expected deploy plus the default category, got %#v", r.Categories)
+	}
+}
+
+func TestLoadRulesFileSwapsRulesAndFallsBackWhenInvalid(t *testing.T) {
+	t.Cleanup(UseEmbeddedRules)
+	path := filepath.Join(t.TempDir(), "rules.json")
+	writeRulesFile(t, path, deployRules)
+
+	if err := LoadRulesFile(path); err != nil {
+		t.Fatalf("expected rules file to load: %v", err)
+	}
+	if active := ActiveRules(); active.Source != path || active.Error != "" {
+		t.Fatalf("expected file rules to be active, got %#v", active)
+	}
+	if category, _ := Classify(Signals{PromptText: "deploy the helm chart"}); category != "deploy" {
+		t.Fatalf("expected deploy from the file rules, got %q", category)
+	}
+	if category, _ := Classify(Signals{PromptText: "Category: research please"}); category == "research" {
+		t.Fatal("expected categories missing from the file to be unavailable")
+	}
+
+	writeRulesFile(t, path, `{"categories": {"deploy": {"keywords": [""]}}}`)
+	if err := LoadRulesFile(path); err == nil {
+		t.Fatal("expected invalid rules file to be rejected")
+	}
+	active := ActiveRules()
+	if active.Source != EmbeddedSource || !strings.Contains(active.Error, path) {
+		t.Fatalf("expected embedded rules with the load error, got %#v", active)
+	}
+	if category, _ := Classify(Signals{PromptText: "please research and compare this"}); category != "research" {
+		t.Fatalf("expected embedded rules to classify, got %q", category)
+	}
+}
+
+func TestWatchRulesFileReloadsOnChange(t *testing.T) {
+	t.Cleanup(UseEmbeddedRules)
+	path := filepath.Join(t.TempDir(), "rules.json")
+	writeRulesFile(t, path, deployRules)
+	if err := LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	reloads := make(chan RuleSet, 4)
+	go WatchRulesFile(ctx, path, 10*time.Millisecond, func(rules RuleSet, err error) {
+		reloads <- rules
+	})
+
+	// Give the watcher time to record the current state before changing it.
+	time.Sleep(30 * time.Millisecond)
+	writeRulesFile(t, path, `{"categories": {"support": {"keywords": ["customer", "ticket"]}}}`)
+	future := time.Now().Add(time.Minute)
+	if err := os.Chtimes(path, future, future); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case rules := <-reloads:
+		if _, ok := rules.Categories["support"]; !ok || rules.Source != path {
+			t.Fatalf("expected the edited rules to be active, got %#v", rules)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the watcher to reload the changed file")
+	}
+}
+
+func writeRulesFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,86 @@
+package database
+
+import (
+	"context"
+
+	"gorm.io/gorm"
+)
+
+// ActivityCategory is one ranked category label of an activity. Rank 0 is the
+// primary label and matches ActivityFeed.Category; scores of an activity's
+// labels sum to 1.
+type ActivityCategory struct {
+	ActivityID string  `gorm:"type:char(36);primaryKey" json:"-"`
+	Category   string  `gorm:"primaryKey;index:idx_activity_categories_category" json:"category"`
+	Rank       int     `json:"rank"`
+	Score      float64 `json:"score"`
+}
+
+func (ActivityCategory) TableName() string {
+	return "activity_categories"
+}
+
+// CategoryLabelCount counts the activities carrying a category as their
+// primary label and as a secondary label.
+type CategoryLabelCount struct {
+	Primary   int64 `json:"primary"`
+	Secondary int64 `json:"secondary"`
+}
+
+// preloadActivityRelations loads the project and the ranked category labels
+// of listed activities.
+func preloadActivityRelations(tx *gorm.DB) *gorm.DB {
+	return tx.Preload("Project").Preload("Categories", func(db *gorm.DB) *gorm.DB {
+		return db.Order("activity_categories.rank asc")
+	})
+}
+
+// summarizeCategoryLabels counts primary categories from activity_feed, which
+// also covers activities stored before labels existed, and secondary labels
+// from activity_categories.
+func (s *service) summarizeCategoryLabels(ctx context.Context, filters ActivityFilters) (map[string]CategoryLabelCount, error) {
+	primaryTx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
+	if err != nil {
+		return nil, err
+	}
+	var primary []struct {
+		Category string
+		Count    int64
+	}
+	if err := primaryTx.Select("activity_feed.category AS category, COUNT(*) AS count").Group("activity_feed.category").Scan(&primary).Error; err != nil {
+		return nil, err
+	}
+
+	secondaryTx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
+	if err != nil {
+		return nil, err
+	}
+	var secondary []struct {
+		Category string
+		Count    int64
+	}
+	if err := secondaryTx.
+		Joins("JOIN activity_categories ON activity_categories.activity_id = activity_feed.id").
+		Where("activity_categories.rank > 0").
+		Select("activity_categories.category AS category, COUNT(*) AS count").
+		Group("activity_categories.category").
+		Scan(&secondary).Error; err != nil {
+		return nil, err
+	}
+
+	counts := map[string]CategoryLabelCount{}
+	for _, row := range primary {
+		if row.Category == "" {
+			continue
+		}
+		count := counts[row.Category]
+		count.Primary = row.Count
+		counts[row.Category] = count
+	}
+	for _, row := range secondary {
+		count := counts[row.Category]
+		count.Secondary = row.Count
+		counts[row.Category] = count
+	}
+	return counts, nil
+}
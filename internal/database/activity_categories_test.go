@@ -0,0 +1,57 @@
+package database
+
+import "testing"
+
+func TestActivityCategoriesAreStoredListedAndSummarized(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	multi := &ActivityFeed{ProjectID: projectID, ProjectTag: "alpha", Model: "gpt-5", Category: "code", Categories: []ActivityCategory{
+		{Category: "code", Rank: 0, Score: 0.6},
+		{Category: "admin", Rank: 1, Score: 0.4},
+	}}
+	single := &ActivityFeed{ProjectID: projectID, ProjectTag: "alpha", Model: "gpt-5", Category: "admin", Categories: []ActivityCategory{
+		{Category: "admin", Rank: 0, Score: 1},
+	}}
+	mustCreateActivity(t, svc, multi)
+	if err := svc.CreateActivities(t.Context(), []*ActivityFeed{single}); err != nil {
+		t.Fatalf("expected batch insert to succeed: %v", err)
+	}
+
+	activities, err := svc.ListActivities(t.Context(), ActivityFilters{})
+	if err != nil {
+		t.Fatalf("expected list to succeed: %v", err)
+	}
+	for _, activity := range activities {
+		if activity.ID != multi.ID {
+			continue
+		}
+		if len(activity.Categories) != 2 || activity.Categories[0].Category != "code" || activity.Categories[1].Category != "admin" {
+			t.Fatalf("expected ranked labels, got %#v", activity.Categories)
+		}
+	}
+
+	summary, err := svc.SummarizeActivities(t.Context(), ActivityFilters{})
+	if err != nil {
+		t.Fatalf("expected summary to succeed: %v", err)
+	}
+	if summary.ByCategory["code"] != (CategoryLabelCount{Primary: 1}) || summary.ByCategory["admin"] != (CategoryLabelCount{Primary: 1, Secondary: 1}) {
+		t.Fatalf("unexpected category counts %#v", summary.ByCategory)
+	}
+
+	corrected, err := svc.CorrectActivityCategory(t.Context(), multi.ID, "research")
+	if err != nil {
+		t.Fatalf("expected correction to succeed: %v", err)
+	}
+	if len(corrected.Categories) != 1 || corrected.Categories[0] != (ActivityCategory{ActivityID: multi.ID, Category: "research", Score: 1}) {
+		t.Fatalf("expected the correction to replace the labels, got %#v", corrected.Categories)
+	}
+
+	summary, err = svc.SummarizeActivities(t.Context(), ActivityFilters{Categories: []string{"research"}})
+	if err != nil {
+		t.Fatalf("expected summary to succeed: %v", err)
+	}
+	if len(summary.ByCategory) != 1 || summary.ByCategory["research"] != (CategoryLabelCount{Primary: 1}) {
+		t.Fatalf("unexpected filtered category counts %#v", summary.ByCategory)
+	}
+}
@@ -0,0 +1,127 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrActivityNotFound = errors.New("activity not found")
+var ErrInvalidCategory = errors.New("invalid category")
+
+// CategoryReasonUserCorrection marks a category set through
+// CorrectActivityCategory. Corrected activities are the learned classifier's
+// training data.
+const CategoryReasonUserCorrection = "user_correction"
+
+// CategoryReasonProvided marks a category supplied by the client at ingest.
+const CategoryReasonProvided = "provided:category"
+
+// CategoryReasonExplicitOverridePrefix starts the reason of a category named
+// in the prompt or assistant text ("category: name").
+const CategoryReasonExplicitOverridePrefix = "explicit_override:"
+
+// Signal storage modes recorded in ActivityFeature.Signals. With neither,
+// only the prompt tokens are kept.
+const (
+	// SignalStorageDigest keeps a SHA-256 digest of the prompt instead of its
+	// text, plus the assistant text and tools.
+	SignalStorageDigest = "digest"
+	// SignalStorageText keeps the (truncated) prompt and assistant text and
+	// the tools.
+	SignalStorageText = "text"
+)
+
+// ActivityFeature stores the prompt tokens of an activity so corrections can
+// train the learned classifier without keeping the prompt itself. When signal
+// storage is enabled it also keeps the classifier signals so the activity can
+// be reclassified later.
+type ActivityFeature struct {
+	ActivityID    string     `gorm:"type:char(36);primaryKey" json:"activity_id"`
+	PromptTokens  StringList `gorm:"type:json" json:"prompt_tokens"`
+	Signals       string     `json:"signals"`
+	PromptDigest  string     `json:"prompt_digest,omitempty"`
+	PromptText    string     `json:"prompt_text,omitempty"`
+	AssistantText string     `json:"assistant_text,omitempty"`
+	ToolsUsed     StringList `gorm:"type:json" json:"tools_used"`
+	// Truncated reports that the stored prompt or assistant text was cut to
+	// the configured maximum.
+	Truncated bool      `gorm:"default:false" json:"truncated,omitempty"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+}
+
+func (ActivityFeature) TableName() string {
+	return "activity_features"
+}
+
+// CategoryCorrection is a user-corrected category with the prompt tokens of
+// the corrected activity.
+type CategoryCorrection struct {
+	ActivityID   string
+	Category     string
+	PromptTokens StringList
+}
+
+func createActivityFeatures(tx *gorm.DB, activity *ActivityFeed) error {
+	features := activity.Features
+	if features == nil || (len(features.PromptTokens) == 0 && features.Signals == "") {
+		return nil
+	}
+	features.ActivityID = activity.ID
+	return tx.Create(features).Error
+}
+
+// CorrectActivityCategory sets the category of an activity and records the
+// change as a user correction. Callers validate category against the active
+// classifier rules.
+func (s *service) CorrectActivityCategory(ctx context.Context, id, category string) (ActivityFeed, error) {
+	category = strings.ToLower(strings.TrimSpace(category))
+	if category == "" {
+		return ActivityFeed{}, fmt.Errorf("%w: category is required", ErrInvalidCategory)
+	}
+
+	var rows []ActivityFeed
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&ActivityFeed{}).Where("id = ?", id).Updates(map[string]any{
+			"category":        category,
+			"category_reason": CategoryReasonUserCorrection,
+		})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrActivityNotFound
+		}
+		// The correction replaces every label the classifier assigned.
+		if err := tx.Where("activity_id = ?", id).Delete(&ActivityCategory{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Create(&ActivityCategory{ActivityID: id, Category: category, Rank: 0, Score: 1}).Error; err != nil {
+			return err
+		}
+		return preloadActivityRelations(tx).Where("id = ?", id).Limit(1).Find(&rows).Error
+	})
+	if err != nil {
+		return ActivityFeed{}, err
+	}
+	populateProjectTags(rows)
+	return rows[0], nil
+}
+
+// ListCategoryCorrections returns every corrected activity that has stored
+// prompt tokens, oldest first.
+func (s *service) ListCategoryCorrections(ctx context.Context) ([]CategoryCorrection, error) {
+	var corrections []CategoryCorrection
+	err := s.db.WithContext(ctx).
+		Table(ActivityFeed{}.TableName()+" AS a").
+		Select("a.id AS activity_id, a.category, f.prompt_tokens").
+		Joins("JOIN "+ActivityFeature{}.TableName()+" AS f ON f.activity_id = a.id").
+		Where("a.category_reason = ?", CategoryReasonUserCorrection).
+		Order("a.created_at asc, a.id asc").
+		Scan(&corrections).Error
+	return corrections, err
+}
@@ -0,0 +1,49 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestCorrectActivityCategoryRecordsUserCorrection(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	corrected := &ActivityFeed{ProjectID: projectID, ProjectTag: "alpha", Model: "gpt-5", Category: "general", CategoryReason: "fallback:insufficient_signals", Features: &ActivityFeature{PromptTokens: StringList{"vendor", "landscape"}}}
+	untouched := &ActivityFeed{ProjectID: projectID, ProjectTag: "alpha", Model: "gpt-5", Category: "code", CategoryReason: "tool_signal:write_file", Features: &ActivityFeature{PromptTokens: StringList{"refactor"}}}
+	withoutFeatures := &ActivityFeed{ProjectID: projectID, ProjectTag: "alpha", Model: "gpt-5", Category: "general"}
+	mustCreateActivity(t, svc, corrected)
+	if err := svc.CreateActivities(t.Context(), []*ActivityFeed{untouched, withoutFeatures}); err != nil {
+		t.Fatalf("expected batch insert to succeed: %v", err)
+	}
+
+	activity, err := svc.CorrectActivityCategory(t.Context(), corrected.ID, " Research ")
+	if err != nil {
+		t.Fatalf("expected correction to succeed: %v", err)
+	}
+	if activity.Category != "research" || activity.CategoryReason != CategoryReasonUserCorrection || activity.ProjectTag != "alpha" {
+		t.Fatalf("unexpected corrected activity %#v", activity)
+	}
+	if _, err := svc.CorrectActivityCategory(t.Context(), withoutFeatures.ID, "admin"); err != nil {
+		t.Fatalf("expected correction to succeed: %v", err)
+	}
+
+	corrections, err := svc.ListCategoryCorrections(t.Context())
+	if err != nil {
+		t.Fatalf("expected corrections to list: %v", err)
+	}
+	if len(corrections) != 1 || corrections[0].ActivityID != corrected.ID || corrections[0].Category != "research" {
+		t.Fatalf("expected only the correction with features, got %#v", corrections)
+	}
+	if !reflect.DeepEqual([]string(corrections[0].PromptTokens), []string{"vendor", "landscape"}) {
+		t.Fatalf("unexpected prompt tokens %v", corrections[0].PromptTokens)
+	}
+
+	if _, err := svc.CorrectActivityCategory(t.Context(), "missing", "code"); !errors.Is(err, ErrActivityNotFound) {
+		t.Fatalf("expected ErrActivityNotFound, got %v", err)
+	}
+	if _, err := svc.CorrectActivityCategory(t.Context(), corrected.ID, " "); !errors.Is(err, ErrInvalidCategory) {
+		t.Fatalf("expected ErrInvalidCategory, got %v", err)
+	}
+}
@@ -0,0 +1,272 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestListActivityPageWalksAllRowsWithCursor(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 5; i++ {
+		createdAt := base.Add(time.Duration(i) * time.Minute)
+		if i == 4 {
+			// Two rows sharing a timestamp must still page deterministically.
+			createdAt = base.Add(3 * time.Minute)
+		}
+		mustCreateActivity(t, svc, &ActivityFeed{
+			SessionKey: fmt.Sprintf("page-%d", i),
+			Model:      "gpt-5",
+			ProjectID:  projectID,
+			ProjectTag: "clawtivity",
+			CreatedAt:  createdAt,
+		})
+	}
+
+	seen := map[string]bool{}
+	var previous *ActivityFeed
+	cursor := ""
+	pages := 0
+	for {
+		page, err := svc.ListActivityPage(t.Context(), ActivityFilters{Limit: 2, Cursor: cursor})
+		if err != nil {
+			t.Fatalf("expected page to load: %v", err)
+		}
+		pages++
+		for i := range page.Data {
+			row := page.Data[i]
+			if seen[row.ID] {
+				t.Fatalf("expected each row once, saw %s twice", row.SessionKey)
+			}
+			seen[row.ID] = true
+			if previous != nil && row.CreatedAt.After(previous.CreatedAt) {
+				t.Fatalf("expected descending order, %s came after %s", row.SessionKey, previous.SessionKey)
+			}
+			previous = &row
+		}
+		if page.NextCursor == "" {
+			break
+		}
+		cursor = page.NextCursor
+	}
+
+	if len(seen) != 5 {
+		t.Fatalf("expected to page through 5 rows, got %d", len(seen))
+	}
+	if pages != 3 {
+		t.Fatalf("expected 3 pages, got %d", pages)
+	}
+}
+
+func TestListActivitiesHonorsAscendingSortAndLimit(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	for i := 0; i < 3; i++ {
+		mustCreateActivity(t, svc, &ActivityFeed{
+			SessionKey: fmt.Sprintf("sort-%d", i),
+			ProjectID:  projectID,
+			CreatedAt:  base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	rows, err := svc.ListActivities(t.Context(), ActivityFilters{Sort: "created_at", Limit: 2})
+	if err != nil {
+		t.Fatalf("expected list to succeed: %v", err)
+	}
+	if len(rows) != 2 || rows[0].SessionKey != "sort-0" || rows[1].SessionKey != "sort-1" {
+		t.Fatalf("expected oldest two rows in ascending order, got %#v", rows)
+	}
+}
+
+func TestListActivityPageRejectsInvalidParameters(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	cases := []struct {
+		filters ActivityFilters
+		want    error
+	}{
+		{ActivityFilters{Cursor: "not-a-cursor"}, ErrInvalidCursor},
+		{ActivityFilters{Sort: "cost"}, ErrInvalidSort},
+		{ActivityFilters{Limit: maxActivityPageSize + 1}, ErrInvalidLimit},
+	}
+	for _, tc := range cases {
+		if _, err := svc.ListActivityPage(t.Context(), tc.filters); !errors.Is(err, tc.want) {
+			t.Fatalf("expected %v for %#v, got %v", tc.want, tc.filters, err)
+		}
+	}
+}
+
+func TestListActivitiesAppliesCombinedFilters(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	alpha := mustProjectID(t, svc, "alpha")
+	beta := mustProjectID(t, svc, "beta")
+
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	rows := []*ActivityFeed{
+		{SessionKey: "f-1", ProjectID: alpha, Model: "gpt-5", Channel: "slack", Status: "success", Reasoning: true, TokensIn: 100, TokensOut: 50, CreatedAt: base},
+		{SessionKey: "f-2", ProjectID: beta, Model: "gpt-5", Channel: "telegram", Status: "failed", TokensIn: 10, TokensOut: 5, CreatedAt: base.Add(time.Hour)},
+		{SessionKey: "f-3", ProjectID: alpha, Model: "claude", Channel: "slack", Status: "success", Reasoning: true, TokensIn: 400, TokensOut: 100, CreatedAt: base.Add(2 * time.Hour)},
+		{SessionKey: "f-4", ProjectID: beta, Model: "gpt-5", Channel: "slack", Status: "success", TokensIn: 200, TokensOut: 0, CreatedAt: base.Add(3 * time.Hour)},
+	}
+	for _, row := range rows {
+		mustCreateActivity(t, svc, row)
+	}
+
+	reasoning := true
+	minTokens := int64(100)
+	maxTokens := int64(300)
+	cases := []struct {
+		name    string
+		filters ActivityFilters
+		want    []string
+	}{
+		{"projects", ActivityFilters{ProjectTags: []string{"alpha", "beta"}, Models: []string{"gpt-5"}}, []string{"f-4", "f-2", "f-1"}},
+		{"channels and statuses", ActivityFilters{Channels: []string{"slack"}, Statuses: []string{"success"}, Models: []string{"gpt-5"}}, []string{"f-4", "f-1"}},
+		{"reasoning", ActivityFilters{Reasoning: &reasoning}, []string{"f-3", "f-1"}},
+		{"time range", ActivityFilters{From: "2026-02-18T11:00:00Z", To: "2026-02-18T13:00:00Z"}, []string{"f-3", "f-2"}},
+		{"token range", ActivityFilters{MinTokens: &minTokens, MaxTokens: &maxTokens}, []string{"f-4", "f-1"}},
+	}
+	for _, tc := range cases {
+		got, err := svc.ListActivities(t.Context(), tc.filters)
+		if err != nil {
+			t.Fatalf("%s: expected list to succeed: %v", tc.name, err)
+		}
+		keys := make([]string, 0, len(got))
+		for _, row := range got {
+			keys = append(keys, row.SessionKey)
+		}
+		if fmt.Sprint(keys) != fmt.Sprint(tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, keys)
+		}
+	}
+
+	stats, err := svc.ListProjectsWithStats(t.Context(), "", ActivityFilters{Channels: []string{"slack"}, ProjectTags: []string{"beta"}})
+	if err != nil {
+		t.Fatalf("expected project stats to succeed: %v", err)
+	}
+	if len(stats) != 1 || stats[0].Slug != "beta" || stats[0].ActivityCount != 1 || stats[0].TokensInTotal != 200 {
+		t.Fatalf("expected filtered beta stats, got %#v", stats)
+	}
+}
+
+func TestActivityFiltersRejectInvalidTimeRange(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	for _, filters := range []ActivityFilters{
+		{From: "yesterday"},
+		{From: "2026-02-19T00:00:00Z", To: "2026-02-18T00:00:00Z"},
+	} {
+		if _, err := svc.ListActivities(t.Context(), filters); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("expected ErrInvalidFilter for %#v, got %v", filters, err)
+		}
+	}
+}
+
+func TestSummarizeActivityGroupsByModelAndWeek(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	// 2026-02-16 is a Monday; the 22nd closes that week and the 23rd opens the next.
+	for _, row := range []*ActivityFeed{
+		{SessionKey: "g-1", ProjectID: projectID, Model: "gpt-5", TokensIn: 10, TokensOut: 1, DurationMS: 100, CreatedAt: time.Date(2026, 2, 16, 9, 0, 0, 0, time.UTC)},
+		{SessionKey: "g-2", ProjectID: projectID, Model: "gpt-5", TokensIn: 20, TokensOut: 2, DurationMS: 200, CreatedAt: time.Date(2026, 2, 22, 23, 0, 0, 0, time.UTC)},
+		{SessionKey: "g-3", ProjectID: projectID, Model: "gpt-5", TokensIn: 30, TokensOut: 3, CreatedAt: time.Date(2026, 2, 23, 1, 0, 0, 0, time.UTC)},
+		{SessionKey: "g-4", ProjectID: projectID, Model: "claude", TokensIn: 40, TokensOut: 4, CreatedAt: time.Date(2026, 2, 17, 1, 0, 0, 0, time.UTC)},
+	} {
+		mustCreateActivity(t, svc, row)
+	}
+
+	got, err := svc.SummarizeActivityGroups(t.Context(), ActivityFilters{}, []string{"model", "week"})
+	if err != nil {
+		t.Fatalf("expected grouped summary to succeed: %v", err)
+	}
+
+	want := []struct {
+		model, week string
+		count       int64
+		tokensIn    int64
+	}{
+		{"claude", "2026-02-16", 1, 40},
+		{"gpt-5", "2026-02-16", 2, 30},
+		{"gpt-5", "2026-02-23", 1, 30},
+	}
+	if len(got.Groups) != len(want) {
+		t.Fatalf("expected %d groups, got %#v", len(want), got.Groups)
+	}
+	for i, w := range want {
+		group := got.Groups[i]
+		if group.Keys["model"] != w.model || group.Keys["week"] != w.week || group.Count != w.count || group.TokensInTotal != w.tokensIn {
+			t.Fatalf("group %d: expected %+v, got %#v", i, w, group)
+		}
+	}
+	if got.Groups[1].DurationMSTotal != 300 {
+		t.Fatalf("expected duration total 300, got %d", got.Groups[1].DurationMSTotal)
+	}
+}
+
+func TestSummarizeActivityGroupsBySingleDimension(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	alphaID := mustProjectID(t, svc, "alpha")
+	betaID := mustProjectID(t, svc, "beta")
+
+	for _, row := range []*ActivityFeed{
+		{SessionKey: "s-1", ProjectID: alphaID, Model: "gpt-5", TokensIn: 10},
+		{SessionKey: "s-2", ProjectID: alphaID, Model: "gpt-5", TokensIn: 20},
+		{SessionKey: "s-3", ProjectID: betaID, Model: "gpt-5", TokensIn: 30},
+	} {
+		mustCreateActivity(t, svc, row)
+	}
+
+	got, err := svc.SummarizeActivityGroups(t.Context(), ActivityFilters{}, []string{"project"})
+	if err != nil {
+		t.Fatalf("expected single-dimension summary to succeed: %v", err)
+	}
+	if len(got.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %#v", got.Groups)
+	}
+	if got.Groups[0].Keys["project"] != "alpha" || got.Groups[0].Count != 2 || got.Groups[0].TokensInTotal != 30 {
+		t.Fatalf("unexpected alpha group %#v", got.Groups[0])
+	}
+	if got.Groups[1].Keys["project"] != "beta" || got.Groups[1].Count != 1 {
+		t.Fatalf("unexpected beta group %#v", got.Groups[1])
+	}
+}
+
+func TestSummarizeActivityGroupsRejectsInvalidDimensions(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	for _, groupBy := range [][]string{nil, {"cost"}, {"model", "model"}, {"day", "week"}} {
+		if _, err := svc.SummarizeActivityGroups(t.Context(), ActivityFilters{}, groupBy); !errors.Is(err, ErrInvalidGroupBy) {
+			t.Fatalf("expected ErrInvalidGroupBy for %v, got %v", groupBy, err)
+		}
+	}
+}
+
+func newActivityQueryTestService(t *testing.T) *service {
+	t.Helper()
+
+	disableOpenRouterBootstrap(t)
+	adapter, err := NewSQLiteAdapter(filepath.Join(t.TempDir(), "clawtivity.db"))
+	if err != nil {
+		t.Fatalf("expected adapter to initialize: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = adapter.Close()
+	})
+	return adapter.(*service)
+}
+
+func mustCreateActivity(t *testing.T, svc *service, activity *ActivityFeed) {
+	t.Helper()
+
+	if err := svc.CreateActivity(t.Context(), activity); err != nil {
+		t.Fatalf("expected activity insert to succeed: %v", err)
+	}
+}
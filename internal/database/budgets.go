@@ -0,0 +1,485 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var ErrBudgetNotFound = errors.New("budget not found")
+var ErrInvalidBudget = errors.New("invalid budget")
+var ErrBudgetConflict = errors.New("budget already exists for project and period")
+
+// Budget periods are calendar periods in UTC; weeks start on Monday like the
+// week bucket of grouped summaries.
+const (
+	BudgetPeriodDaily   = "daily"
+	BudgetPeriodWeekly  = "weekly"
+	BudgetPeriodMonthly = "monthly"
+)
+
+const defaultBudgetThresholds = "50,80,100"
+
+// Budget caps the spend of one project, or of every project when ProjectID is
+// nil, per period. Limit is in Currency.
+type Budget struct {
+	ID         string    `gorm:"type:char(36);primaryKey" json:"id"`
+	ProjectID  *string   `gorm:"type:char(36);index:idx_budgets_project_id" json:"project_id,omitempty"`
+	ProjectTag string    `gorm:"-" json:"project_tag,omitempty"`
+	Period     string    `json:"period"`
+	Limit      float64   `gorm:"column:limit_amount" json:"limit"`
+	Currency   string    `gorm:"default:USD" json:"currency"`
+	CreatedAt  time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt  time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Budget) TableName() string {
+	return "budgets"
+}
+
+func (b *Budget) BeforeCreate(_ *gorm.DB) error {
+	if b.ID == "" {
+		b.ID = generateUUIDv4()
+	}
+	return nil
+}
+
+// BudgetUpdate carries the fields a PATCH may change; nil fields are left
+// untouched. A budget cannot move to another project.
+type BudgetUpdate struct {
+	Period   *string  `json:"period,omitempty"`
+	Limit    *float64 `json:"limit,omitempty"`
+	Currency *string  `json:"currency,omitempty"`
+}
+
+// BudgetStatus is a budget's spend in the period containing the evaluation
+// time. ProjectedSpend extrapolates the spend so far linearly to PeriodEnd.
+type BudgetStatus struct {
+	Budget
+	PeriodStart       time.Time `json:"period_start"`
+	PeriodEnd         time.Time `json:"period_end"`
+	Spend             float64   `json:"spend"`
+	Remaining         float64   `json:"remaining"`
+	PercentUsed       float64   `json:"percent_used"`
+	ProjectedSpend    float64   `json:"projected_spend"`
+	ThresholdsCrossed []int     `json:"thresholds_crossed"`
+}
+
+// BudgetAlert records that a budget's spend reached Threshold percent of its
+// limit in the period starting at PeriodStart, so each crossing is reported
+// once.
+type BudgetAlert struct {
+	ID          string    `gorm:"type:char(36);primaryKey" json:"id"`
+	BudgetID    string    `gorm:"type:char(36);uniqueIndex:idx_budget_alerts_crossing,priority:1" json:"budget_id"`
+	PeriodStart time.Time `gorm:"uniqueIndex:idx_budget_alerts_crossing,priority:2" json:"period_start"`
+	Threshold   int       `gorm:"uniqueIndex:idx_budget_alerts_crossing,priority:3" json:"threshold"`
+	Spend       float64   `json:"spend"`
+	Limit       float64   `gorm:"column:limit_amount" json:"limit"`
+	Currency    string    `json:"currency"`
+	CreatedAt   time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ProjectTag  string    `gorm:"-" json:"project_tag,omitempty"`
+	Period      string    `gorm:"-" json:"period"`
+}
+
+func (BudgetAlert) TableName() string {
+	return "budget_alerts"
+}
+
+func (a *BudgetAlert) BeforeCreate(_ *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = generateUUIDv4()
+	}
+	return nil
+}
+
+// ListBudgetStatuses reports every budget against the period containing now,
+// global budgets first.
+func (s *service) ListBudgetStatuses(ctx context.Context, now time.Time) ([]BudgetStatus, error) {
+	var budgets []Budget
+	if err := s.db.WithContext(ctx).Order("project_id IS NOT NULL, project_id, period").Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	if err := populateBudgetProjectTags(ctx, s.db, budgets); err != nil {
+		return nil, err
+	}
+
+	statuses := make([]BudgetStatus, 0, len(budgets))
+	for _, budget := range budgets {
+		start, end := budgetPeriodWindow(budget.Period, now)
+		spend, err := s.budgetSpend(ctx, budget, start, end)
+		if err != nil {
+			return nil, err
+		}
+
+		status := BudgetStatus{
+			Budget:            budget,
+			PeriodStart:       start,
+			PeriodEnd:         end,
+			Spend:             spend,
+			Remaining:         budget.Limit - spend,
+			PercentUsed:       spend / budget.Limit * 100,
+			ProjectedSpend:    spend,
+			ThresholdsCrossed: []int{},
+		}
+		if elapsed := now.Sub(start); elapsed > 0 && now.Before(end) {
+			status.ProjectedSpend = spend * float64(end.Sub(start)) / float64(elapsed)
+		}
+		for _, threshold := range s.budgetThresholds {
+			if status.PercentUsed >= float64(threshold) {
+				status.ThresholdsCrossed = append(status.ThresholdsCrossed, threshold)
+			}
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+func (s *service) CreateBudget(ctx context.Context, budget *Budget) error {
+	if budget == nil {
+		return fmt.Errorf("%w: budget is required", ErrInvalidBudget)
+	}
+
+	budget.ProjectTag = normalizeProjectSlug(budget.ProjectTag)
+	budget.ProjectID = nil
+	if err := normalizeBudget(budget); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if budget.ProjectTag != "" {
+			var project Project
+			if err := tx.Where("slug = ?", budget.ProjectTag).First(&project).Error; err != nil {
+				if errors.Is(err, gorm.ErrRecordNotFound) {
+					return fmt.Errorf("%w: project %s does not exist", ErrInvalidBudget, budget.ProjectTag)
+				}
+				return err
+			}
+			budget.ProjectID = &project.ID
+		}
+		if err := ensureBudgetFree(tx, budget.ProjectID, budget.Period, ""); err != nil {
+			return err
+		}
+		return tx.Create(budget).Error
+	})
+}
+
+func (s *service) UpdateBudget(ctx context.Context, id string, update BudgetUpdate) (Budget, error) {
+	var budget Budget
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&budget, "id = ?", strings.TrimSpace(id)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrBudgetNotFound
+			}
+			return err
+		}
+
+		if update.Period != nil {
+			budget.Period = *update.Period
+		}
+		if update.Limit != nil {
+			budget.Limit = *update.Limit
+		}
+		if update.Currency != nil {
+			budget.Currency = *update.Currency
+		}
+		if err := normalizeBudget(&budget); err != nil {
+			return err
+		}
+		if update.Period != nil {
+			if err := ensureBudgetFree(tx, budget.ProjectID, budget.Period, budget.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&budget).Error
+	})
+	if err != nil {
+		return Budget{}, err
+	}
+
+	budgets := []Budget{budget}
+	if err := populateBudgetProjectTags(ctx, s.db, budgets); err != nil {
+		return Budget{}, err
+	}
+	return budgets[0], nil
+}
+
+func (s *service) DeleteBudget(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		trimmed := strings.TrimSpace(id)
+		result := tx.Delete(&Budget{}, "id = ?", trimmed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrBudgetNotFound
+		}
+		return tx.Delete(&BudgetAlert{}, "budget_id = ?", trimmed).Error
+	})
+}
+
+// EvaluateBudgets checks the budgets covering the activities' projects
+// against the periods containing their created_at and returns the thresholds
+// crossed for the first time in those periods. Each budget and period is
+// evaluated once however many activities fall into it, so a batch costs one
+// evaluation per period it touches. Every budget is evaluated even when one
+// fails; the first error is returned alongside the alerts.
+func (s *service) EvaluateBudgets(ctx context.Context, activities ...ActivityFeed) ([]BudgetAlert, error) {
+	if len(s.budgetThresholds) == 0 || len(activities) == 0 {
+		return nil, nil
+	}
+
+	projectIDs := make([]string, 0, len(activities))
+	seenProjects := map[string]struct{}{}
+	for _, activity := range activities {
+		if _, ok := seenProjects[activity.ProjectID]; !ok {
+			seenProjects[activity.ProjectID] = struct{}{}
+			projectIDs = append(projectIDs, activity.ProjectID)
+		}
+	}
+
+	var budgets []Budget
+	if err := s.db.WithContext(ctx).
+		Where("project_id IS NULL OR project_id IN ?", projectIDs).
+		Order("project_id IS NOT NULL, project_id, period").
+		Find(&budgets).Error; err != nil {
+		return nil, err
+	}
+	if len(budgets) == 0 {
+		return nil, nil
+	}
+	if err := populateBudgetProjectTags(ctx, s.db, budgets); err != nil {
+		return nil, err
+	}
+
+	now := time.Now().UTC()
+	var alerts []BudgetAlert
+	var firstErr error
+	for _, budget := range budgets {
+		for _, start := range budgetPeriodsTouched(budget, activities, now) {
+			crossed, err := s.evaluateBudget(ctx, budget, start)
+			if err != nil {
+				if firstErr == nil {
+					firstErr = fmt.Errorf("budget %s: %w", budget.ID, err)
+				}
+				continue
+			}
+			alerts = append(alerts, crossed...)
+		}
+	}
+	return alerts, firstErr
+}
+
+// budgetPeriodsTouched returns the distinct starts of budget's periods that
+// contain an activity it covers, oldest first. Activities without created_at
+// count as now.
+func budgetPeriodsTouched(budget Budget, activities []ActivityFeed, now time.Time) []time.Time {
+	seen := map[time.Time]struct{}{}
+	var starts []time.Time
+	for _, activity := range activities {
+		if budget.ProjectID != nil && *budget.ProjectID != activity.ProjectID {
+			continue
+		}
+		at := activity.CreatedAt
+		if at.IsZero() {
+			at = now
+		}
+		start, _ := budgetPeriodWindow(budget.Period, at)
+		if _, ok := seen[start]; ok {
+			continue
+		}
+		seen[start] = struct{}{}
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	return starts
+}
+
+func (s *service) evaluateBudget(ctx context.Context, budget Budget, at time.Time) ([]BudgetAlert, error) {
+	start, end := budgetPeriodWindow(budget.Period, at)
+	spend, err := s.budgetSpend(ctx, budget, start, end)
+	if err != nil {
+		return nil, err
+	}
+
+	var alerts []BudgetAlert
+	for _, threshold := range s.budgetThresholds {
+		if spend/budget.Limit*100 < float64(threshold) {
+			break
+		}
+		alert := BudgetAlert{
+			BudgetID:    budget.ID,
+			PeriodStart: start,
+			Threshold:   threshold,
+			Spend:       spend,
+			Limit:       budget.Limit,
+			Currency:    budget.Currency,
+		}
+		// The unique crossing index makes concurrent evaluations report a
+		// threshold once.
+		result := s.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).Create(&alert)
+		if result.Error != nil {
+			return alerts, result.Error
+		}
+		if result.RowsAffected == 0 {
+			continue
+		}
+		alert.ProjectTag = budget.ProjectTag
+		alert.Period = budget.Period
+		alerts = append(alerts, alert)
+	}
+	return alerts, nil
+}
+
+// budgetSpend sums the cost of budget's activities created in [start, end),
+// converted to the budget currency.
+func (s *service) budgetSpend(ctx context.Context, budget Budget, start, end time.Time) (float64, error) {
+	scope := func() *gorm.DB {
+		tx := s.db.WithContext(ctx).Model(&ActivityFeed{}).
+			Where("activity_feed.created_at >= ? AND activity_feed.created_at < ?", start, end)
+		if budget.ProjectID != nil {
+			tx = tx.Where("activity_feed.project_id = ?", *budget.ProjectID)
+		}
+		return tx
+	}
+
+	if err := ensureExchangeRates(ctx, s.db, scope(), budget.Currency); err != nil {
+		return 0, err
+	}
+	costSQL, costArgs := convertedCostSQL("activity_feed", budget.Currency)
+	var spend float64
+	if err := scope().Select("COALESCE(SUM("+costSQL+"), 0)", costArgs...).Scan(&spend).Error; err != nil {
+		return 0, err
+	}
+	return spend, nil
+}
+
+// budgetPeriodWindow returns the UTC period of the given kind containing at.
+func budgetPeriodWindow(period string, at time.Time) (time.Time, time.Time) {
+	at = at.UTC()
+	day := time.Date(at.Year(), at.Month(), at.Day(), 0, 0, 0, 0, time.UTC)
+	switch period {
+	case BudgetPeriodWeekly:
+		start := day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+		return start, start.AddDate(0, 0, 7)
+	case BudgetPeriodMonthly:
+		start := time.Date(at.Year(), at.Month(), 1, 0, 0, 0, 0, time.UTC)
+		return start, start.AddDate(0, 1, 0)
+	default:
+		return day, day.AddDate(0, 0, 1)
+	}
+}
+
+func normalizeBudget(budget *Budget) error {
+	budget.Period = strings.ToLower(strings.TrimSpace(budget.Period))
+	switch budget.Period {
+	case BudgetPeriodDaily, BudgetPeriodWeekly, BudgetPeriodMonthly:
+	default:
+		return fmt.Errorf("%w: period must be daily, weekly or monthly", ErrInvalidBudget)
+	}
+	if budget.Limit <= 0 {
+		return fmt.Errorf("%w: limit must be positive", ErrInvalidBudget)
+	}
+	if strings.TrimSpace(budget.Currency) == "" {
+		budget.Currency = baseCurrency
+	}
+	currency, err := normalizeCurrencyCode(budget.Currency)
+	if err != nil {
+		return fmt.Errorf("%w: currency must be a 3-letter ISO 4217 code", ErrInvalidBudget)
+	}
+	budget.Currency = currency
+	return nil
+}
+
+func ensureBudgetFree(tx *gorm.DB, projectID *string, period, exceptID string) error {
+	query := tx.Model(&Budget{}).Where("period = ?", period)
+	if projectID == nil {
+		query = query.Where("project_id IS NULL")
+	} else {
+		query = query.Where("project_id = ?", *projectID)
+	}
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrBudgetConflict
+	}
+	return nil
+}
+
+func populateBudgetProjectTags(ctx context.Context, db *gorm.DB, budgets []Budget) error {
+	ids := make([]string, 0, len(budgets))
+	for _, budget := range budgets {
+		if budget.ProjectID != nil {
+			ids = append(ids, *budget.ProjectID)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	var projects []Project
+	if err := db.WithContext(ctx).Where("id IN ?", ids).Find(&projects).Error; err != nil {
+		return err
+	}
+	slugs := make(map[string]string, len(projects))
+	for _, project := range projects {
+		slugs[project.ID] = project.Slug
+	}
+	for i := range budgets {
+		if budgets[i].ProjectID != nil {
+			budgets[i].ProjectTag = slugs[*budgets[i].ProjectID]
+		}
+	}
+	return nil
+}
+
+// parseBudgetThresholds parses comma-separated percentages into a sorted,
+// de-duplicated list.
+func parseBudgetThresholds(value string) ([]int, error) {
+	seen := map[int]struct{}{}
+	var thresholds []int
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSuffix(strings.TrimSpace(part), "%")
+		if part == "" {
+			continue
+		}
+		threshold, err := strconv.Atoi(part)
+		if err != nil || threshold <= 0 {
+			return nil, fmt.Errorf("threshold %q must be a positive integer percentage", part)
+		}
+		if _, ok := seen[threshold]; ok {
+			continue
+		}
+		seen[threshold] = struct{}{}
+		thresholds = append(thresholds, threshold)
+	}
+	sort.Ints(thresholds)
+	return thresholds, nil
+}
+
+func resolveBudgetThresholds() []int {
+	value, ok := os.LookupEnv("CLAWTIVITY_BUDGET_THRESHOLDS")
+	if !ok {
+		value = defaultBudgetThresholds
+	}
+	thresholds, err := parseBudgetThresholds(value)
+	if err != nil {
+		log.Printf("invalid CLAWTIVITY_BUDGET_THRESHOLDS, using %s: %v", defaultBudgetThresholds, err)
+		thresholds, _ = parseBudgetThresholds(defaultBudgetThresholds)
+	}
+	return thresholds
+}
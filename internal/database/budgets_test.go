@@ -0,0 +1,237 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestBudgetPeriodWindowUsesUTCCalendarPeriods(t *testing.T) {
+	at := time.Date(2026, 3, 12, 15, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	cases := map[string][2]time.Time{
+		BudgetPeriodDaily:   {time.Date(2026, 3, 12, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 13, 0, 0, 0, 0, time.UTC)},
+		BudgetPeriodWeekly:  {time.Date(2026, 3, 9, 0, 0, 0, 0, time.UTC), time.Date(2026, 3, 16, 0, 0, 0, 0, time.UTC)},
+		BudgetPeriodMonthly: {time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC), time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)},
+	}
+	for period, want := range cases {
+		start, end := budgetPeriodWindow(period, at)
+		if !start.Equal(want[0]) || !end.Equal(want[1]) {
+			t.Fatalf("%s: expected %s - %s, got %s - %s", period, want[0], want[1], start, end)
+		}
+	}
+}
+
+func TestParseBudgetThresholdsSortsAndRejectsInvalid(t *testing.T) {
+	thresholds, err := parseBudgetThresholds("100, 50%,80,50")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(thresholds) != 3 || thresholds[0] != 50 || thresholds[1] != 80 || thresholds[2] != 100 {
+		t.Fatalf("expected [50 80 100], got %v", thresholds)
+	}
+	for _, invalid := range []string{"fifty", "0", "-10"} {
+		if _, err := parseBudgetThresholds(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestCreateBudgetValidatesAndRejectsDuplicates(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	mustProjectID(t, svc, "clawtivity")
+
+	budget := Budget{ProjectTag: "Clawtivity", Period: "Monthly", Limit: 10, Currency: "eur"}
+	if err := svc.CreateBudget(t.Context(), &budget); err != nil {
+		t.Fatalf("expected budget to be created: %v", err)
+	}
+	if budget.ProjectID == nil || budget.Period != BudgetPeriodMonthly || budget.Currency != "EUR" {
+		t.Fatalf("expected normalized project budget, got %#v", budget)
+	}
+
+	if err := svc.CreateBudget(t.Context(), &Budget{ProjectTag: "clawtivity", Period: "monthly", Limit: 5}); !errors.Is(err, ErrBudgetConflict) {
+		t.Fatalf("expected ErrBudgetConflict, got %v", err)
+	}
+	if err := svc.CreateBudget(t.Context(), &Budget{Period: "monthly", Limit: 5}); err != nil {
+		t.Fatalf("expected a global budget alongside the project one: %v", err)
+	}
+
+	for _, invalid := range []Budget{
+		{Period: "yearly", Limit: 5},
+		{Period: "daily", Limit: 0},
+		{Period: "daily", Limit: 5, Currency: "euro"},
+		{ProjectTag: "missing", Period: "daily", Limit: 5},
+	} {
+		if err := svc.CreateBudget(t.Context(), &invalid); !errors.Is(err, ErrInvalidBudget) {
+			t.Fatalf("expected ErrInvalidBudget for %#v, got %v", invalid, err)
+		}
+	}
+
+	limit := 20.0
+	updated, err := svc.UpdateBudget(t.Context(), budget.ID, BudgetUpdate{Limit: &limit})
+	if err != nil || updated.Limit != 20 || updated.ProjectTag != "clawtivity" {
+		t.Fatalf("expected limit update, got %#v (%v)", updated, err)
+	}
+	if err := svc.DeleteBudget(t.Context(), budget.ID); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteBudget(t.Context(), budget.ID); !errors.Is(err, ErrBudgetNotFound) {
+		t.Fatalf("expected ErrBudgetNotFound, got %v", err)
+	}
+}
+
+func TestEvaluateBudgetsReportsEachThresholdOncePerPeriod(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+	otherID := mustProjectID(t, svc, "other")
+
+	if err := svc.CreateModelPricing(t.Context(), &ModelPricing{
+		Provider:        "local",
+		Model:           "budget-model",
+		EffectiveFrom:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		InputCostPer1M:  10,
+		OutputCostPer1M: 10,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	budget := Budget{ProjectTag: "clawtivity", Period: BudgetPeriodDaily, Limit: 2}
+	if err := svc.CreateBudget(t.Context(), &budget); err != nil {
+		t.Fatal(err)
+	}
+
+	day := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	// Each turn costs 1.0, half the daily limit.
+	record := func(projectID string, at time.Time) []BudgetAlert {
+		t.Helper()
+		activity := &ActivityFeed{SessionKey: "budget", Model: "budget-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: at}
+		mustCreateActivity(t, svc, activity)
+		alerts, err := svc.EvaluateBudgets(t.Context(), *activity)
+		if err != nil {
+			t.Fatalf("expected evaluation to succeed: %v", err)
+		}
+		return alerts
+	}
+
+	if alerts := record(otherID, day); len(alerts) != 0 {
+		t.Fatalf("expected another project's spend to be ignored, got %#v", alerts)
+	}
+	alerts := record(projectID, day)
+	if len(alerts) != 1 || alerts[0].Threshold != 50 || alerts[0].ProjectTag != "clawtivity" || alerts[0].Period != BudgetPeriodDaily {
+		t.Fatalf("expected the 50%% crossing, got %#v", alerts)
+	}
+	alerts = record(projectID, day.Add(time.Hour))
+	if len(alerts) != 2 || alerts[0].Threshold != 80 || alerts[1].Threshold != 100 || !nearlyEqual(alerts[1].Spend, 2) {
+		t.Fatalf("expected the 80%% and 100%% crossings, got %#v", alerts)
+	}
+	if alerts := record(projectID, day.Add(2*time.Hour)); len(alerts) != 0 {
+		t.Fatalf("expected crossings to be reported once per period, got %#v", alerts)
+	}
+	if alerts := record(projectID, day.AddDate(0, 0, 1)); len(alerts) != 1 || alerts[0].Threshold != 50 {
+		t.Fatalf("expected a new period to start over, got %#v", alerts)
+	}
+}
+
+func TestEvaluateBudgetsEvaluatesABatchOncePerPeriod(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+	otherID := mustProjectID(t, svc, "other")
+
+	if err := svc.CreateModelPricing(t.Context(), &ModelPricing{
+		Provider:        "local",
+		Model:           "budget-model",
+		EffectiveFrom:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		InputCostPer1M:  10,
+		OutputCostPer1M: 10,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	budget := Budget{ProjectTag: "clawtivity", Period: BudgetPeriodDaily, Limit: 2}
+	if err := svc.CreateBudget(t.Context(), &budget); err != nil {
+		t.Fatal(err)
+	}
+
+	// Three turns costing 1.0 each on one day, one on the next and one for
+	// another project, stored together like a batch.
+	day := time.Date(2026, 3, 12, 9, 0, 0, 0, time.UTC)
+	var batch []*ActivityFeed
+	for _, row := range []struct {
+		projectID string
+		at        time.Time
+	}{
+		{projectID, day},
+		{projectID, day.Add(time.Hour)},
+		{otherID, day},
+		{projectID, day.Add(2 * time.Hour)},
+		{projectID, day.AddDate(0, 0, 1)},
+	} {
+		batch = append(batch, &ActivityFeed{SessionKey: "budget", Model: "budget-model", TokensIn: 100_000, ProjectID: row.projectID, CreatedAt: row.at})
+	}
+	if err := svc.CreateActivities(t.Context(), batch); err != nil {
+		t.Fatal(err)
+	}
+	stored := make([]ActivityFeed, 0, len(batch))
+	for _, activity := range batch {
+		stored = append(stored, *activity)
+	}
+
+	alerts, err := svc.EvaluateBudgets(t.Context(), stored...)
+	if err != nil {
+		t.Fatalf("expected evaluation to succeed: %v", err)
+	}
+	if len(alerts) != 4 {
+		t.Fatalf("expected 50/80/100%% on the first day and 50%% on the next, got %#v", alerts)
+	}
+	first, next := day.Truncate(24*time.Hour), day.AddDate(0, 0, 1).Truncate(24*time.Hour)
+	for i, want := range []struct {
+		start     time.Time
+		threshold int
+	}{{first, 50}, {first, 80}, {first, 100}, {next, 50}} {
+		if !alerts[i].PeriodStart.Equal(want.start) || alerts[i].Threshold != want.threshold {
+			t.Fatalf("alert %d: expected %d%% for %s, got %#v", i, want.threshold, want.start, alerts[i])
+		}
+	}
+	if !nearlyEqual(alerts[2].Spend, 3) {
+		t.Fatalf("expected the first day's spend to cover the whole batch, got %v", alerts[2].Spend)
+	}
+}
+
+func TestListBudgetStatusesProjectsSpend(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+
+	if err := svc.CreateModelPricing(t.Context(), &ModelPricing{
+		Provider:        "local",
+		Model:           "budget-model",
+		EffectiveFrom:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		InputCostPer1M:  10,
+		OutputCostPer1M: 10,
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateBudget(t.Context(), &Budget{Period: BudgetPeriodWeekly, Limit: 10}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Monday noon is 1/14 of the way through the week.
+	now := time.Date(2026, 3, 9, 12, 0, 0, 0, time.UTC)
+	mustCreateActivity(t, svc, &ActivityFeed{SessionKey: "s", Model: "budget-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: now.Add(-time.Hour)})
+	mustCreateActivity(t, svc, &ActivityFeed{SessionKey: "s", Model: "budget-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: now.AddDate(0, 0, -1)})
+
+	statuses, err := svc.ListBudgetStatuses(t.Context(), now)
+	if err != nil {
+		t.Fatalf("expected statuses: %v", err)
+	}
+	if len(statuses) != 1 {
+		t.Fatalf("expected one status, got %#v", statuses)
+	}
+	status := statuses[0]
+	if !nearlyEqual(status.Spend, 1) || !nearlyEqual(status.Remaining, 9) || !nearlyEqual(status.PercentUsed, 10) {
+		t.Fatalf("expected only this week's spend, got %#v", status)
+	}
+	if !nearlyEqual(status.ProjectedSpend, 14) {
+		t.Fatalf("expected projected spend 14, got %f", status.ProjectedSpend)
+	}
+	if len(status.ThresholdsCrossed) != 0 || status.ProjectTag != "" {
+		t.Fatalf("expected an uncrossed global budget, got %#v", status)
+	}
+}
@@ -0,0 +1,216 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"sort"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultCostRecomputeBatchSize = 500
+	maxCostRecomputeBatchSize     = 5000
+)
+
+// CostRecomputeRequest selects the activities whose cost_estimate is
+// re-resolved against the current pricing catalog. Filters.Currency picks the
+// currency the report totals are converted to (USD by default).
+type CostRecomputeRequest struct {
+	Filters ActivityFilters
+	// OnlyZeroCost restricts the run to rows currently costed at 0, which is
+	// where unmatched models end up.
+	OnlyZeroCost bool
+	// DryRun computes the report without writing any rows.
+	DryRun    bool
+	BatchSize int
+}
+
+// CostRecomputeModelChange is the per-model share of a recompute report, in
+// the report currency.
+type CostRecomputeModelChange struct {
+	Model     string  `json:"model"`
+	Changed   int64   `json:"changed"`
+	CostDelta float64 `json:"cost_delta"`
+}
+
+// CostRecomputeReport totals costs in Currency, converting each activity at
+// the exchange rate in effect at its created_at.
+type CostRecomputeReport struct {
+	DryRun     bool                       `json:"dry_run"`
+	Currency   string                     `json:"currency"`
+	Scanned    int64                      `json:"scanned"`
+	Changed    int64                      `json:"changed"`
+	Unmatched  int64                      `json:"unmatched"`
+	Batches    int                        `json:"batches"`
+	CostBefore float64                    `json:"cost_before"`
+	CostAfter  float64                    `json:"cost_after"`
+	CostDelta  float64                    `json:"cost_delta"`
+	Models     []CostRecomputeModelChange `json:"models"`
+}
+
+type costRecomputeRow struct {
+	ID               string
+	Model            string
+	TokensIn         int
+	TokensOut        int
+	CacheReadTokens  int
+	CacheWriteTokens int
+	ReasoningTokens  int
+	CostEstimate     float64
+	PricingID        *string
+	CostMatch        string
+	CostCurrency     string
+	CreatedAt        sqlTime
+}
+
+// RecomputeActivityCosts re-resolves cost_estimate, pricing_id and
+// cost_match for the matching activities in id order, one transaction per
+// batch, so a large backfill never holds the write lock for long.
+func (s *service) RecomputeActivityCosts(ctx context.Context, request CostRecomputeRequest) (CostRecomputeReport, error) {
+	batchSize := request.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultCostRecomputeBatchSize
+	}
+	if batchSize < 0 || batchSize > maxCostRecomputeBatchSize {
+		return CostRecomputeReport{}, fmt.Errorf("%w: batch_size must be between 1 and %d", ErrInvalidFilter, maxCostRecomputeBatchSize)
+	}
+
+	currency, err := reportingCurrency(request.Filters)
+	if err != nil {
+		return CostRecomputeReport{}, err
+	}
+
+	report := CostRecomputeReport{DryRun: request.DryRun, Currency: currency, Models: []CostRecomputeModelChange{}}
+	byModel := map[string]*CostRecomputeModelChange{}
+	lastID := ""
+	for {
+		query, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), request.Filters)
+		if err != nil {
+			return CostRecomputeReport{}, err
+		}
+		if request.OnlyZeroCost {
+			query = query.Where("activity_feed.cost_estimate = 0")
+		}
+
+		var rows []costRecomputeRow
+		if err := query.
+			Select("activity_feed.id, activity_feed.model, activity_feed.tokens_in, activity_feed.tokens_out, activity_feed.cache_read_tokens, activity_feed.cache_write_tokens, activity_feed.reasoning_tokens, activity_feed.cost_estimate, activity_feed.pricing_id, activity_feed.cost_match, activity_feed.cost_currency, activity_feed.created_at").
+			Where("activity_feed.id > ?", lastID).
+			Order("activity_feed.id asc").
+			Limit(batchSize).
+			Scan(&rows).Error; err != nil {
+			return CostRecomputeReport{}, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		lastID = rows[len(rows)-1].ID
+		report.Batches++
+
+		// Resolve before opening the write transaction, as CreateActivities does.
+		type costUpdate struct {
+			id        string
+			cost      float64
+			pricingID *string
+			match     string
+			currency  string
+		}
+		resolutions := make([]CostResolution, 0, len(rows))
+		currencies := make([]string, 0, 2*len(rows))
+		for _, row := range rows {
+			resolution, err := s.ResolveReferenceCost(ctx, row.Model, row.CreatedAt.Time, TokenUsage{
+				Input:      row.TokensIn,
+				Output:     row.TokensOut,
+				CacheRead:  row.CacheReadTokens,
+				CacheWrite: row.CacheWriteTokens,
+				Reasoning:  row.ReasoningTokens,
+			})
+			if err != nil {
+				return CostRecomputeReport{}, err
+			}
+			resolutions = append(resolutions, resolution)
+			currencies = append(currencies, row.CostCurrency, resolution.Currency)
+		}
+		book, err := loadExchangeRateBook(ctx, s.db, uniqueStrings(currencies), currency)
+		if err != nil {
+			return CostRecomputeReport{}, err
+		}
+
+		updates := make([]costUpdate, 0, len(rows))
+		for i, row := range rows {
+			report.Scanned++
+			resolution := resolutions[i]
+			if !resolution.Matched {
+				report.Unmatched++
+			}
+
+			var pricingID *string
+			if resolution.Matched {
+				pricingID = &resolution.PricingID
+			}
+			before := book.convert(row.CostEstimate, row.CostCurrency, currency, row.CreatedAt.Time)
+			after := book.convert(resolution.Cost, resolution.Currency, currency, row.CreatedAt.Time)
+			report.CostBefore += before
+			report.CostAfter += after
+			if !costChanged(row.CostEstimate, resolution.Cost) && equalOptionalStrings(row.PricingID, pricingID) &&
+				row.CostMatch == resolution.Match && row.CostCurrency == resolution.Currency {
+				continue
+			}
+
+			report.Changed++
+			change, ok := byModel[row.Model]
+			if !ok {
+				change = &CostRecomputeModelChange{Model: row.Model}
+				byModel[row.Model] = change
+			}
+			change.Changed++
+			change.CostDelta += after - before
+			updates = append(updates, costUpdate{id: row.ID, cost: resolution.Cost, pricingID: pricingID, match: resolution.Match, currency: resolution.Currency})
+		}
+
+		if !request.DryRun && len(updates) > 0 {
+			err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, update := range updates {
+					if err := tx.Model(&ActivityFeed{}).Where("id = ?", update.id).Updates(map[string]any{
+						"cost_estimate": update.cost,
+						"pricing_id":    update.pricingID,
+						"cost_match":    update.match,
+						"cost_currency": update.currency,
+					}).Error; err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return CostRecomputeReport{}, err
+			}
+		}
+
+		if len(rows) < batchSize {
+			break
+		}
+	}
+
+	report.CostDelta = report.CostAfter - report.CostBefore
+	for _, change := range byModel {
+		report.Models = append(report.Models, *change)
+	}
+	sort.Slice(report.Models, func(i, j int) bool {
+		return report.Models[i].Model < report.Models[j].Model
+	})
+	return report, nil
+}
+
+func costChanged(before, after float64) bool {
+	return math.Abs(before-after) > 1e-12
+}
+
+func equalOptionalStrings(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
@@ -0,0 +1,142 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRecomputeActivityCostsBackfillsNewPricing(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	base := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for i, model := range []string{"in-house-a", "in-house-a", "in-house-b", "gpt-5"} {
+		mustCreateActivity(t, svc, &ActivityFeed{
+			SessionKey: "recompute",
+			ProjectID:  projectID,
+			Model:      model,
+			TokensIn:   1_000_000,
+			CreatedAt:  base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+	for _, model := range []string{"in-house-a", "in-house-b"} {
+		if err := svc.CreateModelPricing(t.Context(), &ModelPricing{Provider: "local", Model: model, EffectiveFrom: base.AddDate(0, -1, 0), InputCostPer1M: 2}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	request := CostRecomputeRequest{Filters: ActivityFilters{Models: []string{"in-house-a", "in-house-b"}}, OnlyZeroCost: true, DryRun: true, BatchSize: 1}
+	dryRun, err := svc.RecomputeActivityCosts(t.Context(), request)
+	if err != nil {
+		t.Fatalf("expected dry run to succeed: %v", err)
+	}
+	if dryRun.Scanned != 3 || dryRun.Changed != 3 || dryRun.Batches != 3 || !nearlyEqual(dryRun.CostDelta, 6) {
+		t.Fatalf("expected 3 rows to change by 6.00 over 3 batches, got %#v", dryRun)
+	}
+	if len(dryRun.Models) != 2 || dryRun.Models[0].Model != "in-house-a" || dryRun.Models[0].Changed != 2 || !nearlyEqual(dryRun.Models[0].CostDelta, 4) {
+		t.Fatalf("expected per-model breakdown, got %#v", dryRun.Models)
+	}
+	if rows, _ := svc.ListActivities(t.Context(), ActivityFilters{Models: []string{"in-house-a"}}); rows[0].CostEstimate != 0 {
+		t.Fatalf("expected dry run not to write, got cost %.2f", rows[0].CostEstimate)
+	}
+
+	request.DryRun = false
+	applied, err := svc.RecomputeActivityCosts(t.Context(), request)
+	if err != nil {
+		t.Fatalf("expected recompute to succeed: %v", err)
+	}
+	if applied.Changed != 3 {
+		t.Fatalf("expected 3 rows to change, got %#v", applied)
+	}
+	rows, err := svc.ListActivities(t.Context(), ActivityFilters{Models: []string{"in-house-a", "in-house-b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if !nearlyEqual(row.CostEstimate, 2) || row.PricingID == nil {
+			t.Fatalf("expected recomputed cost and pricing_id, got %#v", row)
+		}
+	}
+
+	again, err := svc.RecomputeActivityCosts(t.Context(), request)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.Scanned != 0 || again.Changed != 0 {
+		t.Fatalf("expected only_zero_cost to skip costed rows, got %#v", again)
+	}
+}
+
+func TestRecomputeActivityCostsRejectsInvalidScope(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	for _, request := range []CostRecomputeRequest{
+		{BatchSize: maxCostRecomputeBatchSize + 1},
+		{Filters: ActivityFilters{From: "last week"}},
+		{Filters: ActivityFilters{Currency: "euro"}},
+	} {
+		if _, err := svc.RecomputeActivityCosts(t.Context(), request); !errors.Is(err, ErrInvalidFilter) {
+			t.Fatalf("expected ErrInvalidFilter for %#v, got %v", request, err)
+		}
+	}
+}
+
+func TestRecomputeActivityCostsReportsTotalsInOneCurrency(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	january := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+	euPricing := &ModelPricing{Provider: "eu-host", Model: "eu-model", EffectiveFrom: january, InputCostPer1M: 10, Currency: "EUR"}
+	usPricing := &ModelPricing{Provider: "us-host", Model: "us-model", EffectiveFrom: january, InputCostPer1M: 10}
+	for _, pricing := range []*ModelPricing{euPricing, usPricing} {
+		if err := svc.CreateModelPricing(t.Context(), pricing); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Each turn costs 1.0 in its pricing currency until the EUR rate doubles.
+	february := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	for _, activity := range []*ActivityFeed{
+		{SessionKey: "fx-1", Model: "eu-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: february},
+		{SessionKey: "fx-2", Model: "eu-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: march},
+		{SessionKey: "fx-3", Model: "us-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: march},
+	} {
+		mustCreateActivity(t, svc, activity)
+	}
+	doubled := 20.0
+	if _, err := svc.UpdateModelPricing(t.Context(), euPricing.ID, ModelPricingUpdate{InputCostPer1M: &doubled}); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := svc.RecomputeActivityCosts(t.Context(), CostRecomputeRequest{DryRun: true}); !errors.Is(err, ErrMissingExchangeRate) {
+		t.Fatalf("expected EUR costs without rates to be rejected rather than summed, got %v", err)
+	}
+	if _, err := svc.ImportExchangeRatesCSV(t.Context(), strings.NewReader("currency,effective_from,rate\nEUR,2026-01-01,0.8\nEUR,2026-03-01,0.5\nGBP,2026-01-01,0.75\n"), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	// February EUR converts at 0.8, March EUR at 0.5.
+	wantBefore := 1/0.8 + 1/0.5 + 1
+	wantAfter := 2/0.8 + 2/0.5 + 1
+	report, err := svc.RecomputeActivityCosts(t.Context(), CostRecomputeRequest{DryRun: true})
+	if err != nil {
+		t.Fatalf("expected recompute to succeed: %v", err)
+	}
+	if report.Currency != "USD" || !nearlyEqual(report.CostBefore, wantBefore) || !nearlyEqual(report.CostAfter, wantAfter) || !nearlyEqual(report.CostDelta, wantAfter-wantBefore) {
+		t.Fatalf("expected USD totals %.4f -> %.4f, got %#v", wantBefore, wantAfter, report)
+	}
+	if len(report.Models) != 1 || report.Models[0].Model != "eu-model" || !nearlyEqual(report.Models[0].CostDelta, wantAfter-wantBefore) {
+		t.Fatalf("expected the eu-model delta in USD, got %#v", report.Models)
+	}
+
+	report, err = svc.RecomputeActivityCosts(t.Context(), CostRecomputeRequest{Filters: ActivityFilters{Currency: "gbp"}, DryRun: true})
+	if err != nil {
+		t.Fatalf("expected GBP recompute to succeed: %v", err)
+	}
+	if report.Currency != "GBP" || !nearlyEqual(report.CostBefore, wantBefore*0.75) || !nearlyEqual(report.CostAfter, wantAfter*0.75) {
+		t.Fatalf("expected GBP totals %.4f -> %.4f, got %#v", wantBefore*0.75, wantAfter*0.75, report)
+	}
+}
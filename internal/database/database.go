@@ -5,22 +5,36 @@ import (
 	"crypto/rand"
 	"database/sql"
 	_ "embed"
+	"encoding/base64"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
 	"os"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
 
 	_ "github.com/joho/godotenv/autoload"
+	"gorm.io/driver/postgres"
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 var ErrInvalidDateFilter = errors.New("invalid date filter: expected YYYY-MM-DD")
+var ErrInvalidCursor = errors.New("invalid cursor")
+var ErrInvalidSort = errors.New("invalid sort: expected created_at or -created_at")
+var ErrInvalidLimit = errors.New("invalid limit: expected a positive integer up to 1000")
+var ErrInvalidFilter = errors.New("invalid filter")
+var ErrInvalidGroupBy = errors.New("invalid group_by: expected project, model, category, channel, user_id, status and at most one of hour, day, week, month")
+
+// ErrDuplicateActivity is returned by CreateActivity when a row with the same
+// idempotency key already exists; the activity is replaced with the stored row.
+var ErrDuplicateActivity = errors.New("activity already exists for idempotency key")
 
 // ActivityFeed is the local-first event ledger entry.
 type ActivityFeed struct {
@@ -29,6 +43,9 @@ type ActivityFeed struct {
 	Model            string    `json:"model"`
 	TokensIn         int       `json:"tokens_in"`
 	TokensOut        int       `json:"tokens_out"`
+	CacheReadTokens  int       `json:"cache_read_tokens"`
+	CacheWriteTokens int       `json:"cache_write_tokens"`
+	ReasoningTokens  int       `json:"reasoning_tokens"`
 	CostEstimate     float64   `json:"cost_estimate"`
 	DurationMS       int64     `json:"duration_ms"`
 	ProjectID        string    `gorm:"type:char(36);index:idx_activity_feed_project_id" json:"project_id"`
@@ -37,6 +54,10 @@ type ActivityFeed struct {
 	ProjectTag       string    `gorm:"-" json:"project_tag"`
 	ProjectReason    string    `json:"project_reason"`
 	ExternalRef      string    `json:"external_ref"`
+	IdempotencyKey   *string   `gorm:"column:idempotency_key;uniqueIndex:idx_activity_feed_idempotency_key" json:"idempotency_key,omitempty"`
+	PricingID        *string   `gorm:"column:pricing_id;type:char(36);index:idx_activity_feed_pricing_id" json:"pricing_id,omitempty"`
+	CostCurrency     string    `gorm:"column:cost_currency;default:USD" json:"cost_currency"`
+	CostMatch        string    `gorm:"column:cost_match;index:idx_activity_feed_cost_match" json:"cost_match"`
 	Category         string    `gorm:"index:idx_activity_feed_category" json:"category"`
 	CategoryReason   string    `json:"category_reason"`
 	Thinking         string    `json:"thinking"`
@@ -45,6 +66,14 @@ type ActivityFeed struct {
 	Status           string    `gorm:"index:idx_activity_feed_status" json:"status"`
 	UserID           string    `gorm:"index:idx_activity_feed_user_id" json:"user_id"`
 	CreatedAt        time.Time `gorm:"autoCreateTime" json:"created_at"`
+
+	// Categories are the ranked category labels, primary first. They are
+	// stored in activity_categories together with a new activity.
+	Categories []ActivityCategory `gorm:"foreignKey:ActivityID;references:ID" json:"categories"`
+
+	// Features are stored alongside a new activity in activity_features; they
+	// are not read back with the activity.
+	Features *ActivityFeature `gorm:"-" json:"-"`
 }
 
 func (ActivityFeed) TableName() string {
@@ -60,15 +89,15 @@ func (a *ActivityFeed) BeforeCreate(_ *gorm.DB) error {
 
 // TurnMemory stores compact summaries of a session turn.
 type TurnMemory struct {
-	ID             string    `gorm:"type:char(36);primaryKey" json:"id"`
-	SessionKey     string    `gorm:"index:idx_turn_memories_session_key" json:"session_key"`
-	Summary        string    `json:"summary"`
-	ToolsUsed      string    `gorm:"type:json" json:"tools_used"`
-	FilesTouched   string    `gorm:"type:json" json:"files_touched"`
-	KeyDecisions   string    `gorm:"type:json" json:"key_decisions"`
-	ContextSnippet string    `json:"context_snippet"`
-	Tags           string    `gorm:"type:json" json:"tags"`
-	CreatedAt      time.Time `gorm:"autoCreateTime" json:"created_at"`
+	ID             string     `gorm:"type:char(36);primaryKey" json:"id"`
+	SessionKey     string     `gorm:"index:idx_turn_memories_session_key" json:"session_key"`
+	Summary        string     `json:"summary"`
+	ToolsUsed      StringList `gorm:"type:json" json:"tools_used"`
+	FilesTouched   StringList `gorm:"type:json" json:"files_touched"`
+	KeyDecisions   StringList `gorm:"type:json" json:"key_decisions"`
+	ContextSnippet string     `json:"context_snippet"`
+	Tags           StringList `gorm:"type:json" json:"tags"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
 }
 
 func (TurnMemory) TableName() string {
@@ -105,13 +134,17 @@ func (p *Project) BeforeCreate(_ *gorm.DB) error {
 
 // ModelPricing stores local reference pricing for provider/model pairs.
 type ModelPricing struct {
-	ID                  string     `gorm:"type:char(36);primaryKey" json:"id"`
-	Provider            string     `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:1;index:idx_model_pricing_source" json:"provider"`
-	Model               string     `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:2" json:"model"`
-	EffectiveFrom       time.Time  `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:3" json:"effective_from"`
-	InputCostPer1M      float64    `gorm:"column:input_cost_per_1m" json:"input_cost_per_1m"`
-	OutputCostPer1M     float64    `gorm:"column:output_cost_per_1m" json:"output_cost_per_1m"`
-	ReasoningCostPer1M  *float64   `gorm:"column:reasoning_cost_per_1m" json:"reasoning_cost_per_1m,omitempty"`
+	ID                 string    `gorm:"type:char(36);primaryKey" json:"id"`
+	Provider           string    `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:1;index:idx_model_pricing_source" json:"provider"`
+	Model              string    `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:2" json:"model"`
+	EffectiveFrom      time.Time `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:3" json:"effective_from"`
+	InputCostPer1M     float64   `gorm:"column:input_cost_per_1m" json:"input_cost_per_1m"`
+	OutputCostPer1M    float64   `gorm:"column:output_cost_per_1m" json:"output_cost_per_1m"`
+	ReasoningCostPer1M *float64  `gorm:"column:reasoning_cost_per_1m" json:"reasoning_cost_per_1m,omitempty"`
+	// Cache rates are optional; cached tokens are priced at the input rate
+	// when they are unset.
+	CacheReadCostPer1M  *float64   `gorm:"column:cache_read_cost_per_1m" json:"cache_read_cost_per_1m,omitempty"`
+	CacheWriteCostPer1M *float64   `gorm:"column:cache_write_cost_per_1m" json:"cache_write_cost_per_1m,omitempty"`
 	Currency            string     `json:"currency"`
 	Source              string     `gorm:"index:idx_model_pricing_source" json:"source"`
 	IsEstimated         bool       `json:"is_estimated"`
@@ -122,6 +155,17 @@ type ModelPricing struct {
 	UpdatedAt           time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
 }
 
+// CostResolution is a reference cost and the model_pricing row it came from.
+type CostResolution struct {
+	Cost      float64 `json:"cost"`
+	PricingID string  `json:"pricing_id,omitempty"`
+	Matched   bool    `json:"matched"`
+	// Match is one of CostMatchMatched, CostMatchAlias or CostMatchMissing.
+	Match string `json:"match"`
+	// Currency is the pricing row's currency; unmatched costs are USD.
+	Currency string `json:"currency"`
+}
+
 func (ModelPricing) TableName() string {
 	return "model_pricing"
 }
@@ -139,13 +183,51 @@ type Service interface {
 	Health() map[string]string
 
 	CreateActivity(ctx context.Context, activity *ActivityFeed) error
+	CreateActivities(ctx context.Context, activities []*ActivityFeed) error
+	FindActivityByIdempotencyKey(ctx context.Context, key string) (ActivityFeed, bool, error)
 	ListActivities(ctx context.Context, filters ActivityFilters) ([]ActivityFeed, error)
+	ListActivityPage(ctx context.Context, filters ActivityFilters) (ActivityPage, error)
 	SummarizeActivities(ctx context.Context, filters ActivityFilters) (ActivitySummary, error)
+	SummarizeActivityGroups(ctx context.Context, filters ActivityFilters, groupBy []string) (ActivityGroupSummary, error)
+	CorrectActivityCategory(ctx context.Context, id, category string) (ActivityFeed, error)
+	ListCategoryCorrections(ctx context.Context) ([]CategoryCorrection, error)
 	UpsertProject(ctx context.Context, slug, displayName string) (Project, error)
 	ListProjects(ctx context.Context, status string) ([]Project, error)
-	ListProjectsWithStats(ctx context.Context, status string) ([]ProjectSummary, error)
+	ListProjectsWithStats(ctx context.Context, status string, filters ActivityFilters) ([]ProjectSummary, error)
 	ListModelPricing(ctx context.Context, provider string) ([]ModelPricing, error)
-	ResolveReferenceCost(ctx context.Context, model string, tokensIn, tokensOut int) (float64, bool, error)
+	CreateModelPricing(ctx context.Context, pricing *ModelPricing) error
+	UpdateModelPricing(ctx context.Context, id string, update ModelPricingUpdate) (ModelPricing, error)
+	DeleteModelPricing(ctx context.Context, id string) error
+	ListModelAliases(ctx context.Context) ([]ModelAlias, error)
+	CreateModelAlias(ctx context.Context, alias *ModelAlias) error
+	UpdateModelAlias(ctx context.Context, id string, update ModelAliasUpdate) (ModelAlias, error)
+	DeleteModelAlias(ctx context.Context, id string) error
+	ListUnmatchedModels(ctx context.Context, filters ActivityFilters) ([]UnmatchedModel, error)
+	ListExchangeRates(ctx context.Context, currency string) ([]ExchangeRate, error)
+	ImportExchangeRatesCSV(ctx context.Context, r io.Reader, source string) (ExchangeRateImport, error)
+	ListPricingSources(ctx context.Context) ([]PricingSourceStatus, error)
+	ListBudgetStatuses(ctx context.Context, now time.Time) ([]BudgetStatus, error)
+	CreateBudget(ctx context.Context, budget *Budget) error
+	UpdateBudget(ctx context.Context, id string, update BudgetUpdate) (Budget, error)
+	DeleteBudget(ctx context.Context, id string) error
+	EvaluateBudgets(ctx context.Context, activities ...ActivityFeed) ([]BudgetAlert, error)
+	ListWebhooks(ctx context.Context) ([]Webhook, error)
+	CreateWebhook(ctx context.Context, webhook *Webhook) error
+	UpdateWebhook(ctx context.Context, id string, update WebhookUpdate) (Webhook, error)
+	DeleteWebhook(ctx context.Context, id string) error
+	ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]WebhookDelivery, error)
+	EnqueueWebhookEvent(ctx context.Context, event string, data any) error
+	TestWebhook(ctx context.Context, id string) (WebhookDelivery, error)
+	RecomputeActivityCosts(ctx context.Context, request CostRecomputeRequest) (CostRecomputeReport, error)
+	ReclassifyActivities(ctx context.Context, request ReclassifyRequest) (ReclassifyReport, error)
+	ResolveReferenceCost(ctx context.Context, model string, at time.Time, usage TokenUsage) (CostResolution, error)
+	CreateTurnMemory(ctx context.Context, memory *TurnMemory) error
+	ListTurnMemories(ctx context.Context, filters MemoryFilters) ([]TurnMemory, error)
+	GetTurnMemory(ctx context.Context, id string) (TurnMemory, error)
+	DeleteTurnMemory(ctx context.Context, id string) error
+	SearchTurnMemories(ctx context.Context, query string, filters MemoryFilters) ([]TurnMemorySearchResult, error)
+	ListSessions(ctx context.Context, filters ActivityFilters) ([]SessionSummary, error)
+	GetSession(ctx context.Context, sessionKey string) (SessionDetail, error)
 
 	// Close terminates the database connection.
 	Close() error
@@ -155,15 +237,80 @@ type ActivityFilters struct {
 	ProjectTag string
 	Model      string
 	Date       string
+
+	// Multi-value filters match any of the listed values; ProjectTag and Model
+	// above are folded into ProjectTags and Models.
+	ProjectTags []string
+	Models      []string
+	UserIDs     []string
+	Channels    []string
+	Statuses    []string
+	Categories  []string
+	SessionKeys []string
+	Thinking    []string
+	Reasoning   *bool
+
+	// From is inclusive and To is exclusive; both are RFC3339 timestamps.
+	From string
+	To   string
+
+	// MinCost and MaxCost compare the stored cost_estimate, before any
+	// currency conversion.
+	MinCost   *float64
+	MaxCost   *float64
+	MinTokens *int64
+	MaxTokens *int64
+
+	// Currency reports costs in this ISO 4217 code, converted at the rate in
+	// effect at each activity's created_at. Summaries default to USD; lists
+	// keep each row's own cost_currency unless it is set.
+	Currency string
+
+	// Limit, Cursor and Sort only apply to listing; summaries ignore them.
+	Limit  int
+	Cursor string
+	Sort   string
 }
 
+// ActivityPage is one keyset-paginated slice of activities. NextCursor is
+// empty when there are no further rows.
+type ActivityPage struct {
+	Data       []ActivityFeed `json:"data"`
+	NextCursor string         `json:"next_cursor"`
+}
+
+const (
+	defaultActivityPageSize = 100
+	maxActivityPageSize     = 1000
+)
+
 type ActivitySummary struct {
 	Count           int64          `gorm:"column:count" json:"count"`
 	TokensInTotal   int64          `gorm:"column:tokens_in_total" json:"tokens_in_total"`
 	TokensOutTotal  int64          `gorm:"column:tokens_out_total" json:"tokens_out_total"`
 	CostTotal       float64        `gorm:"column:cost_total" json:"cost_total"`
+	Currency        string         `gorm:"-" json:"currency"`
 	DurationMSTotal int64          `gorm:"column:duration_ms_total" json:"duration_ms_total"`
 	ByStatus        map[string]int `json:"by_status"`
+	// ByCategory counts primary and secondary category labels.
+	ByCategory map[string]CategoryLabelCount `json:"by_category"`
+}
+
+// ActivityGroup is one row of a grouped summary. Keys holds the value of each
+// requested dimension, keyed by dimension name.
+type ActivityGroup struct {
+	Keys            map[string]string `json:"keys"`
+	Count           int64             `json:"count"`
+	TokensInTotal   int64             `json:"tokens_in_total"`
+	TokensOutTotal  int64             `json:"tokens_out_total"`
+	CostTotal       float64           `json:"cost_total"`
+	DurationMSTotal int64             `json:"duration_ms_total"`
+}
+
+type ActivityGroupSummary struct {
+	GroupBy  []string        `json:"group_by"`
+	Currency string          `json:"currency"`
+	Groups   []ActivityGroup `json:"groups"`
 }
 
 type ProjectSummary struct {
@@ -177,27 +324,35 @@ type ProjectSummary struct {
 	TokensInTotal  int64     `json:"tokens_in_total"`
 	TokensOutTotal int64     `json:"tokens_out_total"`
 	CostTotal      float64   `json:"cost_total"`
+	Currency       string    `gorm:"-" json:"currency"`
 }
 
 type service struct {
 	db                   *gorm.DB
 	sqlDB                *sql.DB
 	dsn                  string
+	memorySearchModule   string
+	pricingRefresh       pricingRefreshConfig
 	pricingRefreshCancel context.CancelFunc
+	budgetThresholds     []int
+	webhooks             webhookConfig
+	webhookCancel        context.CancelFunc
 }
 
 type seededModelPricing struct {
-	Provider           string   `json:"provider"`
-	Model              string   `json:"model"`
-	EffectiveFrom      string   `json:"effective_from"`
-	InputCostPer1M     float64  `json:"input_cost_per_1m"`
-	OutputCostPer1M    float64  `json:"output_cost_per_1m"`
-	ReasoningCostPer1M *float64 `json:"reasoning_cost_per_1m"`
-	Currency           string   `json:"currency"`
-	Source             string   `json:"source"`
-	IsEstimated        bool     `json:"is_estimated"`
-	LastVerifiedAt     string   `json:"last_verified_at"`
-	VerificationNotes  string   `json:"verification_notes"`
+	Provider            string   `json:"provider"`
+	Model               string   `json:"model"`
+	EffectiveFrom       string   `json:"effective_from"`
+	InputCostPer1M      float64  `json:"input_cost_per_1m"`
+	OutputCostPer1M     float64  `json:"output_cost_per_1m"`
+	ReasoningCostPer1M  *float64 `json:"reasoning_cost_per_1m"`
+	CacheReadCostPer1M  *float64 `json:"cache_read_cost_per_1m"`
+	CacheWriteCostPer1M *float64 `json:"cache_write_cost_per_1m"`
+	Currency            string   `json:"currency"`
+	Source              string   `json:"source"`
+	IsEstimated         bool     `json:"is_estimated"`
+	LastVerifiedAt      string   `json:"last_verified_at"`
+	VerificationNotes   string   `json:"verification_notes"`
 }
 
 //go:embed model_pricing_seed.json
@@ -212,16 +367,27 @@ type pricingRefreshConfig struct {
 	Enabled    bool
 	Interval   time.Duration
 	StaleAfter time.Duration
+	Sources    []PricingSource
 }
 
+// New opens the database named by BLUEPRINT_DB_URL: postgres:// and
+// postgresql:// URLs select PostgreSQL, anything else is a SQLite path.
 func New() (Service, error) {
-	return newSQLiteService(os.Getenv("BLUEPRINT_DB_URL"))
+	dsn := os.Getenv("BLUEPRINT_DB_URL")
+	if isPostgresDSN(dsn) {
+		return newPostgresService(dsn)
+	}
+	return newSQLiteService(dsn)
 }
 
 func NewSQLiteAdapter(dsn string) (Service, error) {
 	return newSQLiteService(dsn)
 }
 
+func NewPostgresAdapter(dsn string) (Service, error) {
+	return newPostgresService(dsn)
+}
+
 func newSQLiteService(dsn string) (*service, error) {
 	if dsn == "" {
 		dsn = "./test.db"
@@ -231,38 +397,65 @@ func newSQLiteService(dsn string) (*service, error) {
 	if err != nil {
 		return nil, err
 	}
+	return initializeService(gormDB, dsn)
+}
 
-	legacyProjectTags, err := loadLegacyProjectTags(context.Background(), gormDB)
+func newPostgresService(dsn string) (*service, error) {
+	gormDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
 	if err != nil {
 		return nil, err
 	}
+	return initializeService(gormDB, dsn)
+}
 
-	if err := gormDB.AutoMigrate(&Project{}, &ActivityFeed{}, &TurnMemory{}, &ModelPricing{}); err != nil {
-		return nil, err
+// openGormDB opens dsn with the driver its scheme selects, without running
+// migrations. It returns the DSN actually used.
+func openGormDB(dsn string) (*gorm.DB, string, error) {
+	if isPostgresDSN(dsn) {
+		gormDB, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+		return gormDB, dsn, err
+	}
+	if dsn == "" {
+		dsn = "./test.db"
 	}
+	gormDB, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+	return gormDB, dsn, err
+}
 
-	if err := seedModelPricingCatalog(context.Background(), gormDB); err != nil {
+// initializeService applies pending schema migrations and seeds pricing;
+// both are shared by every backend.
+func initializeService(gormDB *gorm.DB, dsn string) (*service, error) {
+	if err := registerQueryMetrics(gormDB); err != nil {
 		return nil, err
 	}
-	if err := bootstrapOpenRouterModelPricing(context.Background(), gormDB); err != nil {
-		log.Printf("openrouter pricing bootstrap skipped: %v", err)
+	if _, err := applyMigrations(context.Background(), gormDB, false); err != nil {
+		return nil, err
 	}
-	refreshConfig := resolvePricingRefreshConfig()
-	if err := refreshOpenRouterModelPricingIfDue(context.Background(), gormDB, time.Now().UTC(), refreshConfig); err != nil {
-		log.Printf("openrouter pricing refresh skipped: %v", err)
+
+	memorySearchModule, err := detectMemorySearchModule(context.Background(), gormDB)
+	if err != nil {
+		return nil, err
 	}
 
-	if err := synchronizeActivityProjectIDs(context.Background(), gormDB, legacyProjectTags); err != nil {
+	if err := seedModelPricingCatalog(context.Background(), gormDB); err != nil {
 		return nil, err
 	}
+	refreshConfig := resolvePricingRefreshConfig()
+	if hasOpenRouterPricingSource(refreshConfig.Sources) {
+		if err := bootstrapOpenRouterModelPricing(context.Background(), gormDB); err != nil {
+			log.Printf("openrouter pricing bootstrap skipped: %v", err)
+		}
+	}
+	refreshPricingSourcesIfDue(context.Background(), gormDB, time.Now().UTC(), refreshConfig)
 
 	sqlDB, err := gormDB.DB()
 	if err != nil {
 		return nil, err
 	}
 
-	svc := &service{db: gormDB, sqlDB: sqlDB, dsn: dsn}
+	svc := &service{db: gormDB, sqlDB: sqlDB, dsn: dsn, memorySearchModule: memorySearchModule, pricingRefresh: refreshConfig, budgetThresholds: resolveBudgetThresholds(), webhooks: resolveWebhookConfig()}
 	svc.startPricingRefreshWorker(refreshConfig)
+	svc.startWebhookWorker(svc.webhooks)
 
 	return svc, nil
 }
@@ -310,20 +503,126 @@ func (s *service) Health() map[string]string {
 }
 
 func (s *service) CreateActivity(ctx context.Context, activity *ActivityFeed) error {
+	if activity != nil && activity.IdempotencyKey != nil {
+		existing, found, err := s.FindActivityByIdempotencyKey(ctx, *activity.IdempotencyKey)
+		if err != nil {
+			return err
+		}
+		if found {
+			*activity = existing
+			return ErrDuplicateActivity
+		}
+	}
+
+	if err := s.prepareActivityInsert(ctx, activity); err != nil {
+		return err
+	}
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(activity).Error; err != nil {
+			return err
+		}
+		return createActivityFeatures(tx, activity)
+	})
+	if err != nil {
+		// A concurrent insert may have won the unique index race.
+		if activity.IdempotencyKey != nil {
+			existing, found, lookupErr := s.FindActivityByIdempotencyKey(ctx, *activity.IdempotencyKey)
+			if lookupErr == nil && found {
+				*activity = existing
+				return ErrDuplicateActivity
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *service) FindActivityByIdempotencyKey(ctx context.Context, key string) (ActivityFeed, bool, error) {
+	trimmed := strings.TrimSpace(key)
+	if trimmed == "" {
+		return ActivityFeed{}, false, nil
+	}
+
+	// Find with a limit avoids GORM logging a "record not found" error for
+	// every first-time ingest.
+	var rows []ActivityFeed
+	if err := preloadActivityRelations(s.db.WithContext(ctx)).Where("idempotency_key = ?", trimmed).Limit(1).Find(&rows).Error; err != nil {
+		return ActivityFeed{}, false, err
+	}
+	if len(rows) == 0 {
+		return ActivityFeed{}, false, nil
+	}
+	populateProjectTags(rows)
+	return rows[0], true, nil
+}
+
+// CreateActivities inserts all activities in a single transaction; either every
+// row is written or none are. When the insert fails because another writer
+// already stored one of the idempotency keys it returns ErrDuplicateActivity so
+// the caller can drop the stored rows and retry the rest.
+func (s *service) CreateActivities(ctx context.Context, activities []*ActivityFeed) error {
+	if len(activities) == 0 {
+		return nil
+	}
+
+	// Resolve costs before opening the transaction so pricing lookups do not
+	// contend with the write lock.
+	for _, activity := range activities {
+		if err := s.prepareActivityInsert(ctx, activity); err != nil {
+			return err
+		}
+	}
+
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, activity := range activities {
+			if err := tx.Create(activity).Error; err != nil {
+				return err
+			}
+			if err := createActivityFeatures(tx, activity); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		// A concurrent insert may have won the unique index race for a row.
+		for _, activity := range activities {
+			if activity.IdempotencyKey == nil {
+				continue
+			}
+			if _, found, lookupErr := s.FindActivityByIdempotencyKey(ctx, *activity.IdempotencyKey); lookupErr == nil && found {
+				return ErrDuplicateActivity
+			}
+		}
+		return err
+	}
+	return nil
+}
+
+func (s *service) prepareActivityInsert(ctx context.Context, activity *ActivityFeed) error {
+	if activity == nil {
+		return errors.New("activity is required")
+	}
 	if strings.TrimSpace(activity.ProjectID) == "" {
 		return errors.New("project_id is required")
 	}
-	costEstimate, matched, err := s.ResolveReferenceCost(ctx, activity.Model, activity.TokensIn, activity.TokensOut)
+	pricedAt := activity.CreatedAt
+	if pricedAt.IsZero() {
+		pricedAt = time.Now().UTC()
+	}
+	resolution, err := s.ResolveReferenceCost(ctx, activity.Model, pricedAt, activityTokenUsage(activity))
 	if err != nil {
 		return err
 	}
-	if matched {
-		activity.CostEstimate = costEstimate
-	} else {
-		activity.CostEstimate = 0
+	activity.CostEstimate = resolution.Cost
+	activity.PricingID = nil
+	if resolution.Matched {
+		activity.PricingID = &resolution.PricingID
 	}
+	activity.CostMatch = resolution.Match
+	activity.CostCurrency = resolution.Currency
 	activity.LegacyProjectTag = strings.TrimSpace(strings.ToLower(activity.ProjectTag))
-	return s.db.WithContext(ctx).Create(activity).Error
+	return nil
 }
 
 func (s *service) ListActivities(ctx context.Context, filters ActivityFilters) ([]ActivityFeed, error) {
@@ -331,20 +630,98 @@ func (s *service) ListActivities(ctx context.Context, filters ActivityFilters) (
 	if err != nil {
 		return nil, err
 	}
+	tx, err = applyActivityPagination(tx, filters)
+	if err != nil {
+		return nil, err
+	}
 
 	var activities []ActivityFeed
-	if err := tx.Preload("Project").Order("activity_feed.created_at desc").Find(&activities).Error; err != nil {
+	if err := preloadActivityRelations(tx).Find(&activities).Error; err != nil {
 		return nil, err
 	}
 	populateProjectTags(activities)
+	if strings.TrimSpace(filters.Currency) != "" {
+		if err := s.convertActivityCosts(ctx, activities, filters); err != nil {
+			return nil, err
+		}
+	}
 	return activities, nil
 }
 
+// convertActivityCosts rewrites each row's cost_estimate into the requested
+// currency at the rate in effect at its created_at.
+func (s *service) convertActivityCosts(ctx context.Context, activities []ActivityFeed, filters ActivityFilters) error {
+	currency, err := reportingCurrency(filters)
+	if err != nil {
+		return err
+	}
+
+	currencies := make([]string, 0, len(activities))
+	for _, activity := range activities {
+		currencies = append(currencies, activity.CostCurrency)
+	}
+	book, err := loadExchangeRateBook(ctx, s.db, uniqueStrings(currencies), currency)
+	if err != nil {
+		return err
+	}
+	for i := range activities {
+		activities[i].CostEstimate = book.convert(activities[i].CostEstimate, activities[i].CostCurrency, currency, activities[i].CreatedAt)
+		activities[i].CostCurrency = currency
+	}
+	return nil
+}
+
+// costConversion resolves the currency aggregate costs are reported in and
+// checks that the activities selected by filters can be converted to it.
+func (s *service) costConversion(ctx context.Context, filters ActivityFilters) (string, error) {
+	currency, err := reportingCurrency(filters)
+	if err != nil {
+		return "", err
+	}
+	tx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
+	if err != nil {
+		return "", err
+	}
+	return currency, ensureExchangeRates(ctx, s.db, tx, currency)
+}
+
+func (s *service) ListActivityPage(ctx context.Context, filters ActivityFilters) (ActivityPage, error) {
+	limit := filters.Limit
+	if limit == 0 {
+		limit = defaultActivityPageSize
+	}
+	if limit < 0 || limit > maxActivityPageSize {
+		return ActivityPage{}, ErrInvalidLimit
+	}
+
+	// Fetch one extra row to learn whether another page exists.
+	filters.Limit = limit + 1
+	activities, err := s.ListActivities(ctx, filters)
+	if err != nil {
+		return ActivityPage{}, err
+	}
+
+	page := ActivityPage{Data: activities}
+	if len(activities) > limit {
+		page.Data = activities[:limit]
+		page.NextCursor = encodeActivityCursor(page.Data[limit-1])
+	}
+	if page.Data == nil {
+		page.Data = []ActivityFeed{}
+	}
+	return page, nil
+}
+
 func (s *service) SummarizeActivities(ctx context.Context, filters ActivityFilters) (ActivitySummary, error) {
+	currency, err := s.costConversion(ctx, filters)
+	if err != nil {
+		return ActivitySummary{}, err
+	}
 	tx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
 	if err != nil {
 		return ActivitySummary{}, err
 	}
+	costSQL, costArgs := convertedCostSQL("activity_feed", currency)
 
 	// Use a temp struct to avoid GORM trying to map to ByStatus map field
 	var result struct {
@@ -355,11 +732,12 @@ func (s *service) SummarizeActivities(ctx context.Context, filters ActivityFilte
 		DurationMSTotal int64   `gorm:"column:duration_ms_total" json:"duration_ms_total"`
 	}
 	if err := tx.Select(
-		"COUNT(*) AS count, " +
-			"COALESCE(SUM(activity_feed.tokens_in), 0) AS tokens_in_total, " +
-			"COALESCE(SUM(activity_feed.tokens_out), 0) AS tokens_out_total, " +
-			"COALESCE(SUM(activity_feed.cost_estimate), 0) AS cost_total, " +
+		"COUNT(*) AS count, "+
+			"COALESCE(SUM(activity_feed.tokens_in), 0) AS tokens_in_total, "+
+			"COALESCE(SUM(activity_feed.tokens_out), 0) AS tokens_out_total, "+
+			"COALESCE(SUM("+costSQL+"), 0) AS cost_total, "+
 			"COALESCE(SUM(activity_feed.duration_ms), 0) AS duration_ms_total",
+		costArgs...,
 	).Scan(&result).Error; err != nil {
 		return ActivitySummary{}, err
 	}
@@ -369,6 +747,7 @@ func (s *service) SummarizeActivities(ctx context.Context, filters ActivityFilte
 		TokensInTotal:   result.TokensInTotal,
 		TokensOutTotal:  result.TokensOutTotal,
 		CostTotal:       result.CostTotal,
+		Currency:        currency,
 		DurationMSTotal: result.DurationMSTotal,
 	}
 
@@ -393,6 +772,132 @@ func (s *service) SummarizeActivities(ctx context.Context, filters ActivityFilte
 		summary.ByStatus[row.Status] = int(row.Count)
 	}
 
+	summary.ByCategory, err = s.summarizeCategoryLabels(ctx, filters)
+	if err != nil {
+		return ActivitySummary{}, err
+	}
+
+	return summary, nil
+}
+
+// activityGroupDimensions maps group_by names to SQL expressions. Time
+// buckets come from the dialect instead.
+var activityGroupDimensions = map[string]string{
+	"project":  "COALESCE(group_projects.slug, '')",
+	"model":    "activity_feed.model",
+	"category": "activity_feed.category",
+	"channel":  "activity_feed.channel",
+	"user_id":  "activity_feed.user_id",
+	"status":   "activity_feed.status",
+	"hour":     "",
+	"day":      "",
+	"week":     "",
+	"month":    "",
+}
+
+func isTimeBucketDimension(name string) bool {
+	switch name {
+	case "hour", "day", "week", "month":
+		return true
+	}
+	return false
+}
+
+func normalizeGroupBy(groupBy []string) ([]string, error) {
+	seen := map[string]bool{}
+	normalized := make([]string, 0, len(groupBy))
+	buckets := 0
+	for _, raw := range groupBy {
+		name := strings.ToLower(strings.TrimSpace(raw))
+		if name == "" {
+			continue
+		}
+		if _, ok := activityGroupDimensions[name]; !ok || seen[name] {
+			return nil, ErrInvalidGroupBy
+		}
+		if isTimeBucketDimension(name) {
+			buckets++
+		}
+		seen[name] = true
+		normalized = append(normalized, name)
+	}
+	if len(normalized) == 0 || buckets > 1 {
+		return nil, ErrInvalidGroupBy
+	}
+	return normalized, nil
+}
+
+func (s *service) SummarizeActivityGroups(ctx context.Context, filters ActivityFilters, groupBy []string) (ActivityGroupSummary, error) {
+	dimensions, err := normalizeGroupBy(groupBy)
+	if err != nil {
+		return ActivityGroupSummary{}, err
+	}
+
+	currency, err := s.costConversion(ctx, filters)
+	if err != nil {
+		return ActivityGroupSummary{}, err
+	}
+	tx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
+	if err != nil {
+		return ActivityGroupSummary{}, err
+	}
+	if slices.Contains(dimensions, "project") {
+		tx = tx.Joins("LEFT JOIN projects AS group_projects ON group_projects.id = activity_feed.project_id")
+	}
+
+	costSQL, costArgs := convertedCostSQL("activity_feed", currency)
+	columns := make([]string, 0, len(dimensions)+5)
+	positions := make([]string, 0, len(dimensions))
+	for i, name := range dimensions {
+		expression := activityGroupDimensions[name]
+		if isTimeBucketDimension(name) {
+			expression = dialectFor(s.db).timeBucket(name, "activity_feed.created_at")
+		}
+		columns = append(columns, fmt.Sprintf("%s AS group_%d", expression, i))
+		positions = append(positions, strconv.Itoa(i+1))
+	}
+	columns = append(columns,
+		"COUNT(*) AS count",
+		"COALESCE(SUM(activity_feed.tokens_in), 0) AS tokens_in_total",
+		"COALESCE(SUM(activity_feed.tokens_out), 0) AS tokens_out_total",
+		"COALESCE(SUM("+costSQL+"), 0) AS cost_total",
+		"COALESCE(SUM(activity_feed.duration_ms), 0) AS duration_ms_total",
+	)
+	grouping := strings.Join(positions, ", ")
+
+	// Group through a raw clause: Group quotes a single bare position as a
+	// column name.
+	rows, err := tx.Select(strings.Join(columns, ", "), costArgs...).
+		Clauses(clause.GroupBy{Columns: []clause.Column{{Name: grouping, Raw: true}}}).
+		Order(grouping).
+		Rows()
+	if err != nil {
+		return ActivityGroupSummary{}, err
+	}
+	defer rows.Close()
+
+	summary := ActivityGroupSummary{GroupBy: dimensions, Currency: currency, Groups: []ActivityGroup{}}
+	for rows.Next() {
+		keys := make([]sql.NullString, len(dimensions))
+		var group ActivityGroup
+		dest := make([]any, 0, len(dimensions)+5)
+		for i := range keys {
+			dest = append(dest, &keys[i])
+		}
+		dest = append(dest, &group.Count, &group.TokensInTotal, &group.TokensOutTotal, &group.CostTotal, &group.DurationMSTotal)
+		if err := rows.Scan(dest...); err != nil {
+			return ActivityGroupSummary{}, err
+		}
+
+		group.Keys = make(map[string]string, len(dimensions))
+		for i, name := range dimensions {
+			group.Keys[name] = keys[i].String
+		}
+		summary.Groups = append(summary.Groups, group)
+	}
+	if err := rows.Err(); err != nil {
+		return ActivityGroupSummary{}, err
+	}
 	return summary, nil
 }
 
@@ -438,28 +943,46 @@ func (s *service) ListProjects(ctx context.Context, status string) ([]Project, e
 	return projects, nil
 }
 
-func (s *service) ListProjectsWithStats(ctx context.Context, status string) ([]ProjectSummary, error) {
+func (s *service) ListProjectsWithStats(ctx context.Context, status string, filters ActivityFilters) ([]ProjectSummary, error) {
+	currency, err := s.costConversion(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	costSQL, costArgs := convertedCostSQL("a", currency)
+
+	activities, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}).Select("activity_feed.*"), filters)
+	if err != nil {
+		return nil, err
+	}
+
 	tx := s.db.WithContext(ctx).
 		Table("projects AS p").
 		Select(
-			"p.id, p.slug, p.display_name, p.status, p.created_at, p.updated_at, " +
-				"COUNT(a.id) AS activity_count, " +
-				"COALESCE(SUM(a.tokens_in), 0) AS tokens_in_total, " +
-				"COALESCE(SUM(a.tokens_out), 0) AS tokens_out_total, " +
-				"COALESCE(SUM(a.cost_estimate), 0) AS cost_total",
+			"p.id, p.slug, p.display_name, p.status, p.created_at, p.updated_at, "+
+				"COUNT(a.id) AS activity_count, "+
+				"COALESCE(SUM(a.tokens_in), 0) AS tokens_in_total, "+
+				"COALESCE(SUM(a.tokens_out), 0) AS tokens_out_total, "+
+				"COALESCE(SUM("+costSQL+"), 0) AS cost_total",
+			costArgs...,
 		).
-		Joins("LEFT JOIN activity_feed AS a ON a.project_id = p.id").
+		Joins("LEFT JOIN (?) AS a ON a.project_id = p.id", activities).
 		Group("p.id, p.slug, p.display_name, p.status, p.created_at, p.updated_at").
 		Order("p.slug asc")
 
 	if trimmed := strings.TrimSpace(status); trimmed != "" {
 		tx = tx.Where("p.status = ?", trimmed)
 	}
+	if slugs := projectSlugFilter(filters); len(slugs) > 0 {
+		tx = tx.Where("p.slug IN ?", slugs)
+	}
 
 	var rows []ProjectSummary
 	if err := tx.Scan(&rows).Error; err != nil {
 		return nil, err
 	}
+	for i := range rows {
+		rows[i].Currency = currency
+	}
 	return rows, nil
 }
 
@@ -476,16 +999,63 @@ func (s *service) ListModelPricing(ctx context.Context, provider string) ([]Mode
 	return rows, nil
 }
 
-func (s *service) ResolveReferenceCost(ctx context.Context, model string, tokensIn, tokensOut int) (float64, bool, error) {
-	pricing, matched, err := s.lookupReferencePricing(ctx, model)
-	if err != nil || !matched {
-		return 0, false, err
+// TokenUsage is a turn's tokens split by billing bucket. Input excludes cached
+// tokens; Reasoning is the part of Output billed at the reasoning rate.
+type TokenUsage struct {
+	Input      int
+	Output     int
+	CacheRead  int
+	CacheWrite int
+	Reasoning  int
+}
+
+func activityTokenUsage(activity *ActivityFeed) TokenUsage {
+	return TokenUsage{
+		Input:      activity.TokensIn,
+		Output:     activity.TokensOut,
+		CacheRead:  activity.CacheReadTokens,
+		CacheWrite: activity.CacheWriteTokens,
+		Reasoning:  activity.ReasoningTokens,
+	}
+}
+
+// ResolveReferenceCost prices a turn with the pricing row that was in effect at
+// the given time, charging each token bucket at its own rate.
+func (s *service) ResolveReferenceCost(ctx context.Context, model string, at time.Time, usage TokenUsage) (CostResolution, error) {
+	pricing, match, err := s.lookupReferencePricing(ctx, model, at)
+	if err != nil {
+		return CostResolution{}, err
+	}
+	if match == CostMatchMissing {
+		return CostResolution{Match: CostMatchMissing, Currency: baseCurrency}, nil
+	}
+
+	currency := strings.ToUpper(strings.TrimSpace(pricing.Currency))
+	if currency == "" {
+		currency = baseCurrency
 	}
+	return CostResolution{Cost: priceTokenUsage(pricing, usage), PricingID: pricing.ID, Matched: true, Match: match, Currency: currency}, nil
+}
 
-	inputCost := (float64(tokensIn) / 1_000_000) * pricing.InputCostPer1M
-	outputCost := (float64(tokensOut) / 1_000_000) * pricing.OutputCostPer1M
+// priceTokenUsage applies pricing to usage. Buckets without a dedicated rate
+// fall back to the input rate (cache) or output rate (reasoning).
+func priceTokenUsage(pricing ModelPricing, usage TokenUsage) float64 {
+	perToken := func(tokens int, rate float64) float64 {
+		return (float64(tokens) / 1_000_000) * rate
+	}
+	rateOr := func(rate *float64, fallback float64) float64 {
+		if rate == nil {
+			return fallback
+		}
+		return *rate
+	}
 
-	return inputCost + outputCost, true, nil
+	reasoning := min(max(usage.Reasoning, 0), usage.Output)
+	return perToken(usage.Input, pricing.InputCostPer1M) +
+		perToken(usage.CacheRead, rateOr(pricing.CacheReadCostPer1M, pricing.InputCostPer1M)) +
+		perToken(usage.CacheWrite, rateOr(pricing.CacheWriteCostPer1M, pricing.InputCostPer1M)) +
+		perToken(usage.Output-reasoning, pricing.OutputCostPer1M) +
+		perToken(reasoning, rateOr(pricing.ReasoningCostPer1M, pricing.OutputCostPer1M))
 }
 
 // Close closes the database connection.
@@ -493,7 +1063,10 @@ func (s *service) Close() error {
 	if s.pricingRefreshCancel != nil {
 		s.pricingRefreshCancel()
 	}
-	log.Printf("Disconnected from database: %s", s.dsn)
+	if s.webhookCancel != nil {
+		s.webhookCancel()
+	}
+	log.Printf("Disconnected from database: %s", redactDSN(s.dsn))
 	return s.sqlDB.Close()
 }
 
@@ -513,9 +1086,7 @@ func (s *service) startPricingRefreshWorker(config pricingRefreshConfig) {
 			case <-ctx.Done():
 				return
 			case <-ticker.C:
-				if err := refreshOpenRouterModelPricingIfDue(ctx, s.db, time.Now().UTC(), config); err != nil {
-					log.Printf("openrouter pricing refresh failed: %v", err)
-				}
+				refreshPricingSourcesIfDue(ctx, s.db, time.Now().UTC(), config)
 			}
 		}
 	}()
@@ -538,6 +1109,7 @@ func resolvePricingRefreshConfig() pricingRefreshConfig {
 		Enabled:    enabled,
 		Interval:   interval,
 		StaleAfter: staleAfter,
+		Sources:    resolvePricingSources(),
 	}
 }
 
@@ -555,13 +1127,35 @@ func resolveDurationEnv(key string, fallback time.Duration) time.Duration {
 }
 
 func applyActivityFilters(tx *gorm.DB, filters ActivityFilters) (*gorm.DB, error) {
-	if filters.ProjectTag != "" {
+	if slugs := projectSlugFilter(filters); len(slugs) > 0 {
 		tx = tx.Joins("JOIN projects ON projects.id = activity_feed.project_id")
-		tx = tx.Where("projects.slug = ?", normalizeProjectSlug(filters.ProjectTag))
+		tx = tx.Where("projects.slug IN ?", slugs)
+	}
+
+	listFilters := []struct {
+		column string
+		values []string
+	}{
+		{"activity_feed.model", append(filterValues(filters.Model), filters.Models...)},
+		{"activity_feed.user_id", filters.UserIDs},
+		{"activity_feed.channel", filters.Channels},
+		{"activity_feed.status", filters.Statuses},
+		{"activity_feed.category", filters.Categories},
+		{"activity_feed.session_key", filters.SessionKeys},
+		{"activity_feed.thinking", filters.Thinking},
+	}
+	for _, filter := range listFilters {
+		values := uniqueStrings(filter.values)
+		if len(values) == 0 {
+			continue
+		}
+		tx = tx.Where(filter.column+" IN ?", values)
 	}
-	if filters.Model != "" {
-		tx = tx.Where("activity_feed.model = ?", filters.Model)
+
+	if filters.Reasoning != nil {
+		tx = tx.Where("activity_feed.reasoning = ?", *filters.Reasoning)
 	}
+
 	if filters.Date != "" {
 		start, err := time.Parse("2006-01-02", filters.Date)
 		if err != nil {
@@ -570,6 +1164,133 @@ func applyActivityFilters(tx *gorm.DB, filters ActivityFilters) (*gorm.DB, error
 		end := start.Add(24 * time.Hour)
 		tx = tx.Where("activity_feed.created_at >= ? AND activity_feed.created_at < ?", start, end)
 	}
+
+	from, err := parseTimeFilter("from", filters.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTimeFilter("to", filters.To)
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidFilter)
+	}
+	if !from.IsZero() {
+		tx = tx.Where("activity_feed.created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		tx = tx.Where("activity_feed.created_at < ?", to)
+	}
+
+	if filters.MinCost != nil {
+		tx = tx.Where("activity_feed.cost_estimate >= ?", *filters.MinCost)
+	}
+	if filters.MaxCost != nil {
+		tx = tx.Where("activity_feed.cost_estimate <= ?", *filters.MaxCost)
+	}
+	if filters.MinTokens != nil {
+		tx = tx.Where("(activity_feed.tokens_in + activity_feed.tokens_out) >= ?", *filters.MinTokens)
+	}
+	if filters.MaxTokens != nil {
+		tx = tx.Where("(activity_feed.tokens_in + activity_feed.tokens_out) <= ?", *filters.MaxTokens)
+	}
+	return tx, nil
+}
+
+func projectSlugFilter(filters ActivityFilters) []string {
+	values := append(filterValues(filters.ProjectTag), filters.ProjectTags...)
+	slugs := make([]string, 0, len(values))
+	for _, value := range values {
+		slugs = append(slugs, normalizeProjectSlug(value))
+	}
+	return uniqueStrings(slugs)
+}
+
+func filterValues(value string) []string {
+	if strings.TrimSpace(value) == "" {
+		return nil
+	}
+	return []string{value}
+}
+
+func parseTimeFilter(name, value string) (time.Time, error) {
+	trimmed := strings.TrimSpace(value)
+	if trimmed == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, trimmed)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%w: %s must be an RFC3339 timestamp", ErrInvalidFilter, name)
+	}
+	return parsed.UTC(), nil
+}
+
+type activityCursor struct {
+	CreatedAt time.Time `json:"created_at"`
+	ID        string    `json:"id"`
+}
+
+func encodeActivityCursor(activity ActivityFeed) string {
+	payload, err := json.Marshal(activityCursor{CreatedAt: activity.CreatedAt.UTC(), ID: activity.ID})
+	if err != nil {
+		return ""
+	}
+	return base64.RawURLEncoding.EncodeToString(payload)
+}
+
+func decodeActivityCursor(value string) (activityCursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(strings.TrimSpace(value))
+	if err != nil {
+		return activityCursor{}, ErrInvalidCursor
+	}
+
+	var cursor activityCursor
+	if err := json.Unmarshal(raw, &cursor); err != nil || cursor.CreatedAt.IsZero() || strings.TrimSpace(cursor.ID) == "" {
+		return activityCursor{}, ErrInvalidCursor
+	}
+	cursor.CreatedAt = cursor.CreatedAt.UTC()
+	return cursor, nil
+}
+
+// applyActivityPagination orders by (created_at, id) and, when a cursor is
+// given, continues strictly after the row it points at.
+func applyActivityPagination(tx *gorm.DB, filters ActivityFilters) (*gorm.DB, error) {
+	descending := true
+	switch strings.ToLower(strings.TrimSpace(filters.Sort)) {
+	case "", "-created_at", "desc":
+	case "created_at", "asc":
+		descending = false
+	default:
+		return nil, ErrInvalidSort
+	}
+	if filters.Limit < 0 {
+		return nil, ErrInvalidLimit
+	}
+
+	if strings.TrimSpace(filters.Cursor) != "" {
+		cursor, err := decodeActivityCursor(filters.Cursor)
+		if err != nil {
+			return nil, err
+		}
+		comparator := ">"
+		if descending {
+			comparator = "<"
+		}
+		tx = tx.Where(
+			fmt.Sprintf("(activity_feed.created_at %[1]s ?) OR (activity_feed.created_at = ? AND activity_feed.id %[1]s ?)", comparator),
+			cursor.CreatedAt, cursor.CreatedAt, cursor.ID,
+		)
+	}
+
+	if descending {
+		tx = tx.Order("activity_feed.created_at desc").Order("activity_feed.id desc")
+	} else {
+		tx = tx.Order("activity_feed.created_at asc").Order("activity_feed.id asc")
+	}
+	if filters.Limit > 0 {
+		tx = tx.Limit(filters.Limit)
+	}
 	return tx, nil
 }
 
@@ -703,30 +1424,14 @@ func tableHasColumn(ctx context.Context, db *gorm.DB, tableName, columnName stri
 	if strings.TrimSpace(tableName) == "" || strings.TrimSpace(columnName) == "" {
 		return false
 	}
-
-	var count int64
-	if err := db.WithContext(ctx).Raw(
-		fmt.Sprintf("SELECT COUNT(*) FROM pragma_table_info(%q) WHERE name = ?", tableName),
-		columnName,
-	).Scan(&count).Error; err != nil {
-		return false
-	}
-	return count > 0
+	return dialectFor(db).tableHasColumn(ctx, db, tableName, columnName)
 }
 
 func tableExists(ctx context.Context, db *gorm.DB, tableName string) bool {
 	if strings.TrimSpace(tableName) == "" {
 		return false
 	}
-
-	var count int64
-	if err := db.WithContext(ctx).Raw(
-		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
-		tableName,
-	).Scan(&count).Error; err != nil {
-		return false
-	}
-	return count > 0
+	return dialectFor(db).tableExists(ctx, db, tableName)
 }
 
 func loadLegacyProjectTags(ctx context.Context, db *gorm.DB) (map[string]string, error) {
@@ -811,92 +1516,112 @@ func loadSeededModelPricing() ([]ModelPricing, error) {
 		}
 
 		rows = append(rows, ModelPricing{
-			Provider:           strings.ToLower(strings.TrimSpace(seed.Provider)),
-			Model:              strings.TrimSpace(seed.Model),
-			EffectiveFrom:      effectiveFrom,
-			InputCostPer1M:     seed.InputCostPer1M,
-			OutputCostPer1M:    seed.OutputCostPer1M,
-			ReasoningCostPer1M: seed.ReasoningCostPer1M,
-			Currency:           strings.ToUpper(strings.TrimSpace(seed.Currency)),
-			Source:             strings.TrimSpace(seed.Source),
-			IsEstimated:        seed.IsEstimated,
-			LastVerifiedAt:     lastVerifiedAt,
-			VerificationNotes:  strings.TrimSpace(seed.VerificationNotes),
+			Provider:            strings.ToLower(strings.TrimSpace(seed.Provider)),
+			Model:               strings.TrimSpace(seed.Model),
+			EffectiveFrom:       effectiveFrom,
+			InputCostPer1M:      seed.InputCostPer1M,
+			OutputCostPer1M:     seed.OutputCostPer1M,
+			ReasoningCostPer1M:  seed.ReasoningCostPer1M,
+			CacheReadCostPer1M:  seed.CacheReadCostPer1M,
+			CacheWriteCostPer1M: seed.CacheWriteCostPer1M,
+			Currency:            strings.ToUpper(strings.TrimSpace(seed.Currency)),
+			Source:              strings.TrimSpace(seed.Source),
+			IsEstimated:         seed.IsEstimated,
+			LastVerifiedAt:      lastVerifiedAt,
+			VerificationNotes:   strings.TrimSpace(seed.VerificationNotes),
 		})
 	}
 
 	return rows, nil
 }
 
-func (s *service) lookupReferencePricing(ctx context.Context, model string) (ModelPricing, bool, error) {
+// lookupReferencePricing finds the pricing row in force for model at the given
+// time, trying the exact model name, then its model_aliases target, then
+// provider-qualified names. It reports which of those matched.
+func (s *service) lookupReferencePricing(ctx context.Context, model string, at time.Time) (ModelPricing, string, error) {
 	normalizedModel := strings.TrimSpace(model)
 	if normalizedModel == "" || normalizedModel == "unknown-model" {
-		return ModelPricing{}, false, nil
+		return ModelPricing{}, CostMatchMissing, nil
 	}
 
-	var pricing ModelPricing
-	err := s.db.WithContext(ctx).
-		Where("model = ?", normalizedModel).
-		Order("CASE WHEN provider = 'openrouter' THEN 0 ELSE 1 END").
-		Order("effective_from desc").
-		First(&pricing).Error
-	if err == nil {
-		return pricing, true, nil
+	pricing, found, err := findModelPricingEffectiveAt(ctx, s.db, []string{normalizedModel}, at)
+	if err != nil {
+		return ModelPricing{}, "", err
+	}
+	if found {
+		return pricing, CostMatchMatched, nil
 	}
-	if errors.Is(err, gorm.ErrRecordNotFound) {
-		aliasCandidates := providerQualifiedModelCandidates(normalizedModel)
-		if len(aliasCandidates) == 0 {
-			return ModelPricing{}, false, nil
-		}
 
-		err = s.db.WithContext(ctx).
-			Where("model IN ?", aliasCandidates).
-			Order("CASE WHEN provider = 'openrouter' THEN 0 ELSE 1 END").
-			Order("effective_from desc").
-			First(&pricing).Error
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return ModelPricing{}, false, nil
+	// Aliases are only looked up once the exact name has no price, which
+	// spares most ingests the query.
+	aliasTargets, err := modelAliasTargets(ctx, s.db, normalizedModel)
+	if err != nil {
+		return ModelPricing{}, "", err
+	}
+
+	for _, attempt := range []struct {
+		models []string
+		match  string
+	}{
+		{aliasTargets, CostMatchAlias},
+		{providerQualifiedModelCandidates(normalizedModel), CostMatchMatched},
+	} {
+		if len(attempt.models) == 0 {
+			continue
 		}
+		pricing, found, err := findModelPricingEffectiveAt(ctx, s.db, attempt.models, at)
 		if err != nil {
-			return ModelPricing{}, false, err
+			return ModelPricing{}, "", err
+		}
+		if found {
+			return pricing, attempt.match, nil
 		}
+	}
+	return ModelPricing{}, CostMatchMissing, nil
+}
 
-		return pricing, true, nil
+// findModelPricingEffectiveAt returns the row with the latest effective_from
+// not after at; OpenRouter rows only win a tie on the same date. Activities
+// older than every known price fall back to the earliest row rather than
+// going uncosted, since the seeded catalog is dated after much existing
+// activity.
+func findModelPricingEffectiveAt(ctx context.Context, db *gorm.DB, models []string, at time.Time) (ModelPricing, bool, error) {
+	candidates := func() *gorm.DB {
+		return db.WithContext(ctx).Where("model IN ?", models)
 	}
-	if err != nil {
+	const preferOpenRouter = "CASE WHEN provider = 'openrouter' THEN 0 ELSE 1 END"
+
+	// Find with a limit avoids GORM logging a "record not found" error for
+	// every miss on the way to an alias or provider-qualified match.
+	var rows []ModelPricing
+	if err := candidates().Where("effective_from <= ?", at.UTC()).
+		Order("effective_from desc").Order(preferOpenRouter).
+		Limit(1).Find(&rows).Error; err != nil {
 		return ModelPricing{}, false, err
 	}
-
-	return pricing, true, nil
+	if len(rows) == 0 {
+		if err := candidates().Order("effective_from asc").Order(preferOpenRouter).Limit(1).Find(&rows).Error; err != nil {
+			return ModelPricing{}, false, err
+		}
+	}
+	if len(rows) == 0 {
+		return ModelPricing{}, false, nil
+	}
+	return rows[0], true, nil
 }
 
 func providerQualifiedModelCandidates(model string) []string {
-	candidates := append([]string{}, explicitModelPricingAliases(model)...)
 	if strings.Contains(model, "/") {
-		return candidates
+		return nil
 	}
 
-	candidates = append(candidates,
-		"openai/"+model,
-		"anthropic/"+model,
-		"google/"+model,
-		"moonshotai/"+model,
-		"meta-llama/"+model,
-		"x-ai/"+model,
-	)
-
-	return uniqueStrings(candidates)
-}
-
-func explicitModelPricingAliases(model string) []string {
-	switch strings.TrimSpace(model) {
-	case "gpt-5-codex-mini", "openai/gpt-5-codex-mini":
-		return []string{
-			"gpt-5-mini",
-			"openai/gpt-5-mini",
-		}
-	default:
-		return nil
+	return []string{
+		"openai/" + model,
+		"anthropic/" + model,
+		"google/" + model,
+		"moonshotai/" + model,
+		"meta-llama/" + model,
+		"x-ai/" + model,
 	}
 }
 
@@ -935,32 +1660,11 @@ func bootstrapOpenRouterModelPricing(ctx context.Context, db *gorm.DB) error {
 	if err != nil {
 		return err
 	}
-	return upsertOpenRouterModelPricing(ctx, db, rows)
+	return upsertModelPricing(ctx, db, rows)
 }
 
 func refreshOpenRouterModelPricingIfDue(ctx context.Context, db *gorm.DB, now time.Time, config pricingRefreshConfig) error {
-	if !config.Enabled {
-		return nil
-	}
-
-	if err := markOpenRouterPricingStale(ctx, db, now, config.StaleAfter); err != nil {
-		return err
-	}
-
-	due, err := openRouterPricingRefreshDue(ctx, db, now, config.Interval)
-	if err != nil || !due {
-		return err
-	}
-
-	rows, err := openRouterModelsFetcher(ctx)
-	if err != nil {
-		if staleErr := markOpenRouterPricingStale(ctx, db, now, config.StaleAfter); staleErr != nil {
-			return staleErr
-		}
-		return err
-	}
-
-	return upsertOpenRouterModelPricing(ctx, db, rows)
+	return refreshPricingSourceIfDue(ctx, db, now, config, openRouterPricingSource{})
 }
 
 func openRouterPricingRefreshDue(ctx context.Context, db *gorm.DB, now time.Time, interval time.Duration) (bool, error) {
@@ -1006,40 +1710,76 @@ func markOpenRouterPricingStale(ctx context.Context, db *gorm.DB, now time.Time,
 	return nil
 }
 
-func upsertOpenRouterModelPricing(ctx context.Context, db *gorm.DB, rows []ModelPricing) error {
+// upsertModelPricing stores fetched rows keyed by provider, model and
+// effective_from. Rows are applied oldest first so a source may list dated
+// rows in any order; a row only ever updates the stored row with its exact
+// date.
+func upsertModelPricing(ctx context.Context, db *gorm.DB, rows []ModelPricing) error {
 	if len(rows) == 0 {
 		return nil
 	}
 
-	for _, row := range rows {
+	ordered := make([]ModelPricing, len(rows))
+	for i, row := range rows {
 		row.IsStale = false
-
-		var existing ModelPricing
-		err := db.WithContext(ctx).
-			Where("provider = ? AND model = ?", row.Provider, row.Model).
-			Order("effective_from desc").
-			First(&existing).Error
-		if err == nil {
-			if err := db.WithContext(ctx).Model(&existing).Updates(map[string]any{
-				"effective_from":        row.EffectiveFrom,
-				"input_cost_per_1m":     row.InputCostPer1M,
-				"output_cost_per_1m":    row.OutputCostPer1M,
-				"reasoning_cost_per_1m": row.ReasoningCostPer1M,
-				"currency":              row.Currency,
-				"source":                row.Source,
-				"is_estimated":          row.IsEstimated,
-				"is_stale":              false,
-				"last_verified_at":      row.LastVerifiedAt,
-				"verification_notes":    row.VerificationNotes,
+		row.EffectiveFrom = row.EffectiveFrom.UTC()
+		ordered[i] = row
+	}
+	slices.SortStableFunc(ordered, func(a, b ModelPricing) int {
+		return a.EffectiveFrom.Compare(b.EffectiveFrom)
+	})
+
+	for _, row := range ordered {
+		var matches []ModelPricing
+		if err := db.WithContext(ctx).
+			Where("provider = ? AND model = ? AND effective_from = ?", row.Provider, row.Model, row.EffectiveFrom).
+			Limit(1).
+			Find(&matches).Error; err != nil {
+			return err
+		}
+		if len(matches) > 0 {
+			if err := db.WithContext(ctx).Model(&matches[0]).Updates(map[string]any{
+				"input_cost_per_1m":       row.InputCostPer1M,
+				"output_cost_per_1m":      row.OutputCostPer1M,
+				"reasoning_cost_per_1m":   row.ReasoningCostPer1M,
+				"cache_read_cost_per_1m":  row.CacheReadCostPer1M,
+				"cache_write_cost_per_1m": row.CacheWriteCostPer1M,
+				"currency":                row.Currency,
+				"source":                  row.Source,
+				"is_estimated":            row.IsEstimated,
+				"is_stale":                false,
+				"last_verified_at":        row.LastVerifiedAt,
+				"verification_notes":      row.VerificationNotes,
 			}).Error; err != nil {
 				return err
 			}
 			continue
 		}
-		if !errors.Is(err, gorm.ErrRecordNotFound) {
+
+		// Live sources date every fetch with the fetch time. When the price in
+		// effect at that date is unchanged, only re-verify it instead of
+		// storing a duplicate row.
+		var current []ModelPricing
+		if err := db.WithContext(ctx).
+			Where("provider = ? AND model = ? AND effective_from <= ?", row.Provider, row.Model, row.EffectiveFrom).
+			Order("effective_from desc").
+			Limit(1).
+			Find(&current).Error; err != nil {
 			return err
 		}
+		if len(current) > 0 && !modelPricingRatesChanged(current[0], row) {
+			if err := db.WithContext(ctx).Model(&current[0]).Updates(map[string]any{
+				"is_stale":           false,
+				"last_verified_at":   row.LastVerifiedAt,
+				"verification_notes": row.VerificationNotes,
+			}).Error; err != nil {
+				return err
+			}
+			continue
+		}
 
+		// A new date keeps every other row, so earlier activities stay costed
+		// at the price that applied when they happened.
 		if err := db.WithContext(ctx).Create(&row).Error; err != nil {
 			return err
 		}
@@ -1048,6 +1788,24 @@ func upsertOpenRouterModelPricing(ctx context.Context, db *gorm.DB, rows []Model
 	return nil
 }
 
+func modelPricingRatesChanged(existing, candidate ModelPricing) bool {
+	if existing.InputCostPer1M != candidate.InputCostPer1M ||
+		existing.OutputCostPer1M != candidate.OutputCostPer1M ||
+		!strings.EqualFold(existing.Currency, candidate.Currency) {
+		return true
+	}
+	return !equalOptionalRates(existing.ReasoningCostPer1M, candidate.ReasoningCostPer1M) ||
+		!equalOptionalRates(existing.CacheReadCostPer1M, candidate.CacheReadCostPer1M) ||
+		!equalOptionalRates(existing.CacheWriteCostPer1M, candidate.CacheWriteCostPer1M)
+}
+
+func equalOptionalRates(a, b *float64) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
+
 func fetchOpenRouterModelPricing(ctx context.Context) ([]ModelPricing, error) {
 	req, err := http.NewRequestWithContext(ctx, http.MethodGet, openRouterModelsAPIURL, nil)
 	if err != nil {
@@ -1071,6 +1829,8 @@ func fetchOpenRouterModelPricing(ctx context.Context) ([]ModelPricing, error) {
 				Prompt            string `json:"prompt"`
 				Completion        string `json:"completion"`
 				InternalReasoning string `json:"internal_reasoning"`
+				InputCacheRead    string `json:"input_cache_read"`
+				InputCacheWrite   string `json:"input_cache_write"`
 			} `json:"pricing"`
 		} `json:"data"`
 	}
@@ -1095,34 +1855,53 @@ func fetchOpenRouterModelPricing(ctx context.Context) ([]ModelPricing, error) {
 			return nil, fmt.Errorf("parse openrouter completion price for %s: %w", modelID, err)
 		}
 
-		var reasoningCost *float64
-		if strings.TrimSpace(item.Pricing.InternalReasoning) != "" {
-			value, err := parsePerTokenPriceToPerMillion(item.Pricing.InternalReasoning)
-			if err != nil {
-				return nil, fmt.Errorf("parse openrouter reasoning price for %s: %w", modelID, err)
-			}
-			reasoningCost = &value
+		reasoningCost, err := parseOptionalPerTokenPriceToPerMillion(item.Pricing.InternalReasoning)
+		if err != nil {
+			return nil, fmt.Errorf("parse openrouter reasoning price for %s: %w", modelID, err)
+		}
+		cacheReadCost, err := parseOptionalPerTokenPriceToPerMillion(item.Pricing.InputCacheRead)
+		if err != nil {
+			return nil, fmt.Errorf("parse openrouter cache read price for %s: %w", modelID, err)
+		}
+		cacheWriteCost, err := parseOptionalPerTokenPriceToPerMillion(item.Pricing.InputCacheWrite)
+		if err != nil {
+			return nil, fmt.Errorf("parse openrouter cache write price for %s: %w", modelID, err)
 		}
 
 		verifiedAt := now
 		rows = append(rows, ModelPricing{
-			Provider:           "openrouter",
-			Model:              modelID,
-			EffectiveFrom:      now,
-			InputCostPer1M:     inputCost,
-			OutputCostPer1M:    outputCost,
-			ReasoningCostPer1M: reasoningCost,
-			Currency:           "USD",
-			Source:             openRouterModelsAPIURL,
-			IsEstimated:        false,
-			LastVerifiedAt:     &verifiedAt,
-			VerificationNotes:  "Imported from OpenRouter models endpoint.",
+			Provider:            "openrouter",
+			Model:               modelID,
+			EffectiveFrom:       now,
+			InputCostPer1M:      inputCost,
+			OutputCostPer1M:     outputCost,
+			ReasoningCostPer1M:  reasoningCost,
+			CacheReadCostPer1M:  cacheReadCost,
+			CacheWriteCostPer1M: cacheWriteCost,
+			Currency:            "USD",
+			Source:              openRouterModelsAPIURL,
+			IsEstimated:         false,
+			LastVerifiedAt:      &verifiedAt,
+			VerificationNotes:   "Imported from OpenRouter models endpoint.",
 		})
 	}
 
 	return rows, nil
 }
 
+// parseOptionalPerTokenPriceToPerMillion returns nil for a price OpenRouter
+// does not list, so the cost falls back to the base rate.
+func parseOptionalPerTokenPriceToPerMillion(value string) (*float64, error) {
+	if strings.TrimSpace(value) == "" {
+		return nil, nil
+	}
+	parsed, err := parsePerTokenPriceToPerMillion(value)
+	if err != nil {
+		return nil, err
+	}
+	return &parsed, nil
+}
+
 func parsePerTokenPriceToPerMillion(value string) (float64, error) {
 	trimmed := strings.TrimSpace(value)
 	if trimmed == "" {
@@ -0,0 +1,131 @@
+package database
+
+import (
+	"context"
+	"fmt"
+	"net/url"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+// sqlDialect isolates the SQL that differs between backends. Everything
+// else goes through GORM or portable SQL.
+type sqlDialect interface {
+	tableExists(ctx context.Context, db *gorm.DB, tableName string) bool
+	tableHasColumn(ctx context.Context, db *gorm.DB, tableName, columnName string) bool
+	// timeBucket renders column truncated to unit (hour, day, week, month)
+	// as a UTC string; weeks start on Monday.
+	timeBucket(unit, column string) string
+	// jsonArrayContains is a condition with one placeholder that matches
+	// when the JSON array in column contains that string.
+	jsonArrayContains(column string) string
+}
+
+func dialectFor(db *gorm.DB) sqlDialect {
+	if db != nil && db.Dialector != nil && db.Dialector.Name() == "postgres" {
+		return postgresDialect{}
+	}
+	return sqliteDialect{}
+}
+
+func isPostgresDSN(dsn string) bool {
+	lower := strings.ToLower(strings.TrimSpace(dsn))
+	return strings.HasPrefix(lower, "postgres://") || strings.HasPrefix(lower, "postgresql://")
+}
+
+// redactDSN hides credentials before a DSN is logged.
+func redactDSN(dsn string) string {
+	if !isPostgresDSN(dsn) {
+		return dsn
+	}
+	parsed, err := url.Parse(dsn)
+	if err != nil {
+		return "postgres://"
+	}
+	return parsed.Redacted()
+}
+
+type sqliteDialect struct{}
+
+func (sqliteDialect) tableExists(ctx context.Context, db *gorm.DB, tableName string) bool {
+	var count int64
+	if err := db.WithContext(ctx).Raw(
+		"SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?",
+		tableName,
+	).Scan(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func (sqliteDialect) tableHasColumn(ctx context.Context, db *gorm.DB, tableName, columnName string) bool {
+	var count int64
+	if err := db.WithContext(ctx).Raw(
+		fmt.Sprintf("SELECT COUNT(*) FROM pragma_table_info(%q) WHERE name = ?", tableName),
+		columnName,
+	).Scan(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func (sqliteDialect) timeBucket(unit, column string) string {
+	switch unit {
+	case "hour":
+		return fmt.Sprintf("strftime('%%Y-%%m-%%dT%%H:00:00Z', %s)", column)
+	case "week":
+		return fmt.Sprintf("date(%s, 'weekday 0', '-6 days')", column)
+	case "month":
+		return fmt.Sprintf("strftime('%%Y-%%m', %s)", column)
+	default:
+		return fmt.Sprintf("strftime('%%Y-%%m-%%d', %s)", column)
+	}
+}
+
+func (sqliteDialect) jsonArrayContains(column string) string {
+	return fmt.Sprintf("EXISTS (SELECT 1 FROM json_each(%s) WHERE json_each.value = ?)", column)
+}
+
+type postgresDialect struct{}
+
+func (postgresDialect) tableExists(ctx context.Context, db *gorm.DB, tableName string) bool {
+	var count int64
+	if err := db.WithContext(ctx).Raw(
+		"SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?",
+		tableName,
+	).Scan(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func (postgresDialect) tableHasColumn(ctx context.Context, db *gorm.DB, tableName, columnName string) bool {
+	var count int64
+	if err := db.WithContext(ctx).Raw(
+		"SELECT COUNT(*) FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = ? AND column_name = ?",
+		tableName,
+		columnName,
+	).Scan(&count).Error; err != nil {
+		return false
+	}
+	return count > 0
+}
+
+func (postgresDialect) timeBucket(unit, column string) string {
+	utc := fmt.Sprintf("(%s AT TIME ZONE 'UTC')", column)
+	switch unit {
+	case "hour":
+		return fmt.Sprintf(`to_char(date_trunc('hour', %s), 'YYYY-MM-DD"T"HH24:00:00"Z"')`, utc)
+	case "week":
+		return fmt.Sprintf("to_char(date_trunc('week', %s), 'YYYY-MM-DD')", utc)
+	case "month":
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM')", utc)
+	default:
+		return fmt.Sprintf("to_char(%s, 'YYYY-MM-DD')", utc)
+	}
+}
+
+func (postgresDialect) jsonArrayContains(column string) string {
+	return fmt.Sprintf("%s::jsonb @> jsonb_build_array(?::text)", column)
+}
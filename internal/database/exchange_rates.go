@@ -0,0 +1,310 @@
+package database
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// baseCurrency is the currency exchange rates are quoted against and the
+// one costs are reported in when no currency is requested.
+const baseCurrency = "USD"
+
+var ErrInvalidExchangeRate = errors.New("invalid exchange rate")
+
+// ErrMissingExchangeRate is returned when costs must be converted from or to
+// a currency that has no exchange_rates rows.
+var ErrMissingExchangeRate = errors.New("no exchange rate")
+
+// ExchangeRate is the number of Currency units one USD buys from
+// EffectiveFrom until the next row for the same currency.
+type ExchangeRate struct {
+	ID            string    `gorm:"type:char(36);primaryKey" json:"id"`
+	Currency      string    `gorm:"uniqueIndex:idx_exchange_rates_lookup,priority:1" json:"currency"`
+	EffectiveFrom time.Time `gorm:"uniqueIndex:idx_exchange_rates_lookup,priority:2" json:"effective_from"`
+	Rate          float64   `json:"rate"`
+	Source        string    `json:"source"`
+	CreatedAt     time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ExchangeRate) TableName() string {
+	return "exchange_rates"
+}
+
+func (r *ExchangeRate) BeforeCreate(_ *gorm.DB) error {
+	if r.ID == "" {
+		r.ID = generateUUIDv4()
+	}
+	return nil
+}
+
+type ExchangeRateImport struct {
+	Imported int `json:"imported"`
+	Updated  int `json:"updated"`
+}
+
+func (s *service) ListExchangeRates(ctx context.Context, currency string) ([]ExchangeRate, error) {
+	tx := s.db.WithContext(ctx).Model(&ExchangeRate{})
+	if trimmed := strings.TrimSpace(currency); trimmed != "" {
+		tx = tx.Where("currency = ?", strings.ToUpper(trimmed))
+	}
+
+	var rates []ExchangeRate
+	if err := tx.Order("currency asc, effective_from desc").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	return rates, nil
+}
+
+// ImportExchangeRatesCSV loads rows with a currency,effective_from,rate
+// header (an optional source column is kept as provenance). Rates are units
+// of currency per USD. Rows matching an existing currency and effective_from
+// replace its rate. Nothing is written if any row is invalid.
+func (s *service) ImportExchangeRatesCSV(ctx context.Context, r io.Reader, source string) (ExchangeRateImport, error) {
+	rates, err := parseExchangeRatesCSV(r, strings.TrimSpace(source))
+	if err != nil {
+		return ExchangeRateImport{}, err
+	}
+
+	var result ExchangeRateImport
+	err = s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for _, rate := range rates {
+			var existing ExchangeRate
+			err := tx.Where("currency = ? AND effective_from = ?", rate.Currency, rate.EffectiveFrom).First(&existing).Error
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				if err := tx.Create(&rate).Error; err != nil {
+					return err
+				}
+				result.Imported++
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			if err := tx.Model(&existing).Updates(map[string]any{"rate": rate.Rate, "source": rate.Source}).Error; err != nil {
+				return err
+			}
+			result.Updated++
+		}
+		return nil
+	})
+	if err != nil {
+		return ExchangeRateImport{}, err
+	}
+	return result, nil
+}
+
+func parseExchangeRatesCSV(r io.Reader, source string) ([]ExchangeRate, error) {
+	reader := csv.NewReader(r)
+	reader.TrimLeadingSpace = true
+
+	header, err := reader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: csv is empty", ErrInvalidExchangeRate)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidExchangeRate, err)
+	}
+	columns := map[string]int{}
+	for i, name := range header {
+		columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"currency", "effective_from", "rate"} {
+		if _, ok := columns[required]; !ok {
+			return nil, fmt.Errorf("%w: csv header must include %s", ErrInvalidExchangeRate, required)
+		}
+	}
+
+	var rates []ExchangeRate
+	for line := 2; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidExchangeRate, err)
+		}
+
+		currency, err := normalizeCurrencyCode(record[columns["currency"]])
+		if err != nil || currency == baseCurrency {
+			return nil, fmt.Errorf("%w: line %d: currency must be a 3-letter code other than %s", ErrInvalidExchangeRate, line, baseCurrency)
+		}
+		effectiveFrom, err := parsePricingFileTime(strings.TrimSpace(record[columns["effective_from"]]))
+		if err != nil {
+			return nil, fmt.Errorf("%w: line %d: effective_from must be RFC3339 or YYYY-MM-DD", ErrInvalidExchangeRate, line)
+		}
+		rate, err := strconv.ParseFloat(strings.TrimSpace(record[columns["rate"]]), 64)
+		if err != nil || rate <= 0 {
+			return nil, fmt.Errorf("%w: line %d: rate must be a positive number", ErrInvalidExchangeRate, line)
+		}
+
+		rowSource := source
+		if index, ok := columns["source"]; ok && strings.TrimSpace(record[index]) != "" {
+			rowSource = strings.TrimSpace(record[index])
+		}
+		rates = append(rates, ExchangeRate{
+			Currency:      currency,
+			EffectiveFrom: effectiveFrom.UTC(),
+			Rate:          rate,
+			Source:        rowSource,
+		})
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("%w: csv has no rows", ErrInvalidExchangeRate)
+	}
+	return rates, nil
+}
+
+func normalizeCurrencyCode(value string) (string, error) {
+	code := strings.ToUpper(strings.TrimSpace(value))
+	if len(code) != 3 {
+		return "", fmt.Errorf("%w: currency must be a 3-letter ISO 4217 code", ErrInvalidFilter)
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return "", fmt.Errorf("%w: currency must be a 3-letter ISO 4217 code", ErrInvalidFilter)
+		}
+	}
+	return code, nil
+}
+
+// reportingCurrency validates the requested currency, defaulting to USD.
+func reportingCurrency(filters ActivityFilters) (string, error) {
+	if strings.TrimSpace(filters.Currency) == "" {
+		return baseCurrency, nil
+	}
+	return normalizeCurrencyCode(filters.Currency)
+}
+
+// ensureExchangeRates checks that every cost currency among the activities
+// selected by query can be converted to target, so a missing rate fails the
+// request instead of dropping rows from a sum.
+func ensureExchangeRates(ctx context.Context, db *gorm.DB, query *gorm.DB, target string) error {
+	var currencies []string
+	if err := query.Distinct("activity_feed.cost_currency").Pluck("activity_feed.cost_currency", &currencies).Error; err != nil {
+		return err
+	}
+	return ensureExchangeRatesFor(ctx, db, currencies, target)
+}
+
+func ensureExchangeRatesFor(ctx context.Context, db *gorm.DB, currencies []string, target string) error {
+	needed := map[string]struct{}{}
+	for _, currency := range currencies {
+		if currency == "" || currency == target {
+			continue
+		}
+		for _, code := range []string{currency, target} {
+			if code != baseCurrency {
+				needed[code] = struct{}{}
+			}
+		}
+	}
+	if len(needed) == 0 {
+		return nil
+	}
+
+	codes := make([]string, 0, len(needed))
+	for code := range needed {
+		codes = append(codes, code)
+	}
+	var available []string
+	if err := db.WithContext(ctx).Model(&ExchangeRate{}).Where("currency IN ?", codes).Distinct("currency").Pluck("currency", &available).Error; err != nil {
+		return err
+	}
+	for _, code := range available {
+		delete(needed, code)
+	}
+	if len(needed) == 0 {
+		return nil
+	}
+
+	missing := make([]string, 0, len(needed))
+	for code := range needed {
+		missing = append(missing, code)
+	}
+	sort.Strings(missing)
+	return fmt.Errorf("%w for %s; import rates via POST /api/exchange-rates", ErrMissingExchangeRate, strings.Join(missing, ", "))
+}
+
+// convertedCostSQL is an expression for table's cost_estimate in target,
+// converted through USD at the rates in effect at its created_at. Like
+// pricing, timestamps older than every rate use the earliest one.
+func convertedCostSQL(table, target string) (string, []any) {
+	cost := table + ".cost_estimate"
+	currency := table + ".cost_currency"
+	at := table + ".created_at"
+
+	targetRate, args := "1.0", []any{}
+	if target != baseCurrency {
+		targetRate = exchangeRateSQL("?", at)
+		for range strings.Count(targetRate, "?") {
+			args = append(args, target)
+		}
+	}
+	expression := fmt.Sprintf(
+		"CASE WHEN %[1]s = ? THEN %[2]s ELSE %[2]s * %[3]s / %[4]s END",
+		currency, cost, targetRate, exchangeRateSQL(currency, at),
+	)
+	return expression, append([]any{target}, args...)
+}
+
+func exchangeRateSQL(currency, at string) string {
+	return fmt.Sprintf(
+		"(CASE WHEN %[1]s = 'USD' THEN 1.0 ELSE COALESCE("+
+			"(SELECT er.rate FROM exchange_rates er WHERE er.currency = %[1]s AND er.effective_from <= %[2]s ORDER BY er.effective_from DESC LIMIT 1), "+
+			"(SELECT er.rate FROM exchange_rates er WHERE er.currency = %[1]s ORDER BY er.effective_from ASC LIMIT 1)) END)",
+		currency, at,
+	)
+}
+
+// exchangeRateBook converts individual amounts with the same rules as
+// convertedCostSQL, for rows that are not aggregated in SQL.
+type exchangeRateBook map[string][]ExchangeRate
+
+func loadExchangeRateBook(ctx context.Context, db *gorm.DB, currencies []string, target string) (exchangeRateBook, error) {
+	if err := ensureExchangeRatesFor(ctx, db, currencies, target); err != nil {
+		return nil, err
+	}
+
+	codes := append([]string{target}, currencies...)
+	var rates []ExchangeRate
+	if err := db.WithContext(ctx).Where("currency IN ?", codes).Order("effective_from asc").Find(&rates).Error; err != nil {
+		return nil, err
+	}
+	book := exchangeRateBook{}
+	for _, rate := range rates {
+		book[rate.Currency] = append(book[rate.Currency], rate)
+	}
+	return book, nil
+}
+
+func (b exchangeRateBook) rate(currency string, at time.Time) float64 {
+	rates := b[currency]
+	if currency == baseCurrency || len(rates) == 0 {
+		return 1
+	}
+	rate := rates[0].Rate
+	for _, candidate := range rates {
+		if candidate.EffectiveFrom.After(at) {
+			break
+		}
+		rate = candidate.Rate
+	}
+	return rate
+}
+
+func (b exchangeRateBook) convert(amount float64, from, to string, at time.Time) float64 {
+	if from == to {
+		return amount
+	}
+	return amount * b.rate(to, at) / b.rate(from, at)
+}
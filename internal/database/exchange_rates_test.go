@@ -0,0 +1,142 @@
+package database
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestImportExchangeRatesCSVUpsertsAndValidates(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	result, err := svc.ImportExchangeRatesCSV(t.Context(), strings.NewReader("currency,effective_from,rate\neur,2026-01-01,0.9\nGBP,2026-01-01T00:00:00Z,0.78\n"), "ecb")
+	if err != nil {
+		t.Fatalf("expected import to succeed: %v", err)
+	}
+	if result.Imported != 2 || result.Updated != 0 {
+		t.Fatalf("expected 2 imported rows, got %#v", result)
+	}
+
+	result, err = svc.ImportExchangeRatesCSV(t.Context(), strings.NewReader("Currency,Rate,Effective_From,Source\nEUR,0.91,2026-01-01,manual\n"), "ecb")
+	if err != nil || result.Imported != 0 || result.Updated != 1 {
+		t.Fatalf("expected the EUR row to be replaced, got %#v (%v)", result, err)
+	}
+	rates, err := svc.ListExchangeRates(t.Context(), "eur")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 1 || rates[0].Rate != 0.91 || rates[0].Source != "manual" {
+		t.Fatalf("expected updated EUR rate, got %#v", rates)
+	}
+
+	for _, body := range []string{
+		"",
+		"currency,rate\nEUR,0.9\n",
+		"currency,effective_from,rate\nUSD,2026-01-01,1\n",
+		"currency,effective_from,rate\nEUR,yesterday,0.9\n",
+		"currency,effective_from,rate\nEUR,2026-02-01,0.9\nGBP,2026-02-01,-1\n",
+	} {
+		if _, err := svc.ImportExchangeRatesCSV(t.Context(), strings.NewReader(body), "ecb"); !errors.Is(err, ErrInvalidExchangeRate) {
+			t.Fatalf("expected ErrInvalidExchangeRate for %q, got %v", body, err)
+		}
+	}
+	if rates, _ := svc.ListExchangeRates(t.Context(), "EUR"); len(rates) != 1 {
+		t.Fatalf("expected a failed import to write nothing, got %#v", rates)
+	}
+}
+
+func TestCostReportsConvertMixedCurrencies(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+
+	if err := svc.CreateModelPricing(t.Context(), &ModelPricing{
+		Provider:        "eu-host",
+		Model:           "eu-model",
+		EffectiveFrom:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		InputCostPer1M:  10,
+		OutputCostPer1M: 10,
+		Currency:        "EUR",
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.CreateModelPricing(t.Context(), &ModelPricing{
+		Provider:        "us-host",
+		Model:           "us-model",
+		EffectiveFrom:   time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC),
+		InputCostPer1M:  10,
+		OutputCostPer1M: 10,
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// Each turn costs 1.0 in its pricing currency.
+	february := time.Date(2026, 2, 10, 12, 0, 0, 0, time.UTC)
+	march := time.Date(2026, 3, 10, 12, 0, 0, 0, time.UTC)
+	rows := []*ActivityFeed{
+		{SessionKey: "fx-1", Model: "eu-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: february},
+		{SessionKey: "fx-2", Model: "eu-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: march},
+		{SessionKey: "fx-3", Model: "us-model", TokensIn: 100_000, ProjectID: projectID, CreatedAt: march},
+	}
+	for _, row := range rows {
+		mustCreateActivity(t, svc, row)
+	}
+	if rows[0].CostCurrency != "EUR" || rows[2].CostCurrency != "USD" {
+		t.Fatalf("expected cost currency from the pricing row, got %q/%q", rows[0].CostCurrency, rows[2].CostCurrency)
+	}
+
+	if _, err := svc.SummarizeActivities(t.Context(), ActivityFilters{}); !errors.Is(err, ErrMissingExchangeRate) {
+		t.Fatalf("expected EUR costs without rates to be rejected rather than summed, got %v", err)
+	}
+	if _, err := svc.SummarizeActivities(t.Context(), ActivityFilters{Currency: "euro"}); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("expected ErrInvalidFilter for a malformed currency, got %v", err)
+	}
+
+	if _, err := svc.ImportExchangeRatesCSV(t.Context(), strings.NewReader("currency,effective_from,rate\nEUR,2026-01-01,0.8\nEUR,2026-03-01,0.5\nGBP,2026-01-01,0.75\n"), "test"); err != nil {
+		t.Fatal(err)
+	}
+
+	// February EUR converts at 0.8, March EUR at 0.5.
+	wantUSD := 1/0.8 + 1/0.5 + 1
+	summary, err := svc.SummarizeActivities(t.Context(), ActivityFilters{})
+	if err != nil {
+		t.Fatalf("expected summary to succeed: %v", err)
+	}
+	if summary.Currency != "USD" || !nearlyEqual(summary.CostTotal, wantUSD) {
+		t.Fatalf("expected %f USD, got %f %s", wantUSD, summary.CostTotal, summary.Currency)
+	}
+
+	summary, err = svc.SummarizeActivities(t.Context(), ActivityFilters{Currency: "gbp"})
+	if err != nil {
+		t.Fatalf("expected GBP summary to succeed: %v", err)
+	}
+	if summary.Currency != "GBP" || !nearlyEqual(summary.CostTotal, wantUSD*0.75) {
+		t.Fatalf("expected %f GBP, got %f %s", wantUSD*0.75, summary.CostTotal, summary.Currency)
+	}
+
+	groups, err := svc.SummarizeActivityGroups(t.Context(), ActivityFilters{Currency: "EUR"}, []string{"model"})
+	if err != nil {
+		t.Fatalf("expected grouped summary to succeed: %v", err)
+	}
+	if len(groups.Groups) != 2 || !nearlyEqual(groups.Groups[0].CostTotal, 2) || !nearlyEqual(groups.Groups[1].CostTotal, 0.5) {
+		t.Fatalf("expected eu-model 2 EUR and us-model 0.5 EUR, got %#v", groups.Groups)
+	}
+
+	projects, err := svc.ListProjectsWithStats(t.Context(), "", ActivityFilters{Currency: "EUR"})
+	if err != nil {
+		t.Fatalf("expected project stats to succeed: %v", err)
+	}
+	for _, project := range projects {
+		if project.Slug == "clawtivity" && (project.Currency != "EUR" || !nearlyEqual(project.CostTotal, 2.5)) {
+			t.Fatalf("expected 2.5 EUR for the project, got %#v", project)
+		}
+	}
+
+	activities, err := svc.ListActivities(t.Context(), ActivityFilters{Currency: "USD", Sort: "created_at"})
+	if err != nil {
+		t.Fatalf("expected converted list to succeed: %v", err)
+	}
+	if activities[0].CostCurrency != "USD" || !nearlyEqual(activities[0].CostEstimate, 1.25) {
+		t.Fatalf("expected the February EUR turn as 1.25 USD, got %f %s", activities[0].CostEstimate, activities[0].CostCurrency)
+	}
+}
@@ -0,0 +1,206 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrTurnMemoryNotFound = errors.New("turn memory not found")
+var ErrInvalidTurnMemory = errors.New("invalid turn memory")
+
+// StringList is a []string stored as a JSON array column. A nil list is
+// written and rendered as [] so clients always receive an array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return "[]", nil
+	}
+	payload, err := json.Marshal([]string(l))
+	if err != nil {
+		return nil, err
+	}
+	return string(payload), nil
+}
+
+func (l *StringList) Scan(value any) error {
+	var raw []byte
+	switch v := value.(type) {
+	case nil:
+		*l = StringList{}
+		return nil
+	case string:
+		raw = []byte(v)
+	case []byte:
+		raw = v
+	default:
+		return fmt.Errorf("unsupported StringList column type %T", value)
+	}
+
+	if len(strings.TrimSpace(string(raw))) == 0 {
+		*l = StringList{}
+		return nil
+	}
+	var values []string
+	if err := json.Unmarshal(raw, &values); err != nil {
+		return err
+	}
+	if values == nil {
+		values = []string{}
+	}
+	*l = values
+	return nil
+}
+
+func (l StringList) MarshalJSON() ([]byte, error) {
+	if l == nil {
+		return []byte("[]"), nil
+	}
+	return json.Marshal([]string(l))
+}
+
+type MemoryFilters struct {
+	SessionKey string
+	// Project matches memories whose session has activity in that project.
+	Project string
+	Tag     string
+	File    string
+	Date    string
+
+	// From is inclusive and To is exclusive; both are RFC3339 timestamps.
+	From string
+	To   string
+
+	Limit int
+}
+
+func (s *service) CreateTurnMemory(ctx context.Context, memory *TurnMemory) error {
+	if memory == nil {
+		return fmt.Errorf("%w: memory is required", ErrInvalidTurnMemory)
+	}
+	memory.SessionKey = strings.TrimSpace(memory.SessionKey)
+	memory.Summary = strings.TrimSpace(memory.Summary)
+	if memory.SessionKey == "" {
+		return fmt.Errorf("%w: session_key is required", ErrInvalidTurnMemory)
+	}
+	if memory.Summary == "" {
+		return fmt.Errorf("%w: summary is required", ErrInvalidTurnMemory)
+	}
+
+	memory.ToolsUsed = compactStringList(memory.ToolsUsed)
+	memory.FilesTouched = compactStringList(memory.FilesTouched)
+	memory.KeyDecisions = compactStringList(memory.KeyDecisions)
+	memory.Tags = compactStringList(memory.Tags)
+	if !memory.CreatedAt.IsZero() {
+		memory.CreatedAt = memory.CreatedAt.UTC()
+	}
+
+	return s.db.WithContext(ctx).Create(memory).Error
+}
+
+func (s *service) ListTurnMemories(ctx context.Context, filters MemoryFilters) ([]TurnMemory, error) {
+	if filters.Limit < 0 || filters.Limit > maxActivityPageSize {
+		return nil, ErrInvalidLimit
+	}
+
+	tx, err := applyMemoryFilters(s.db.WithContext(ctx).Model(&TurnMemory{}), filters)
+	if err != nil {
+		return nil, err
+	}
+
+	tx = tx.Order("turn_memories.created_at desc").Order("turn_memories.id desc")
+	if filters.Limit > 0 {
+		tx = tx.Limit(filters.Limit)
+	}
+
+	memories := []TurnMemory{}
+	if err := tx.Find(&memories).Error; err != nil {
+		return nil, err
+	}
+	return memories, nil
+}
+
+func applyMemoryFilters(tx *gorm.DB, filters MemoryFilters) (*gorm.DB, error) {
+	if sessionKey := strings.TrimSpace(filters.SessionKey); sessionKey != "" {
+		tx = tx.Where("turn_memories.session_key = ?", sessionKey)
+	}
+	if project := normalizeProjectSlug(filters.Project); project != "" {
+		tx = tx.Where(
+			"EXISTS (SELECT 1 FROM activity_feed JOIN projects ON projects.id = activity_feed.project_id "+
+				"WHERE activity_feed.session_key = turn_memories.session_key AND projects.slug = ?)",
+			project,
+		)
+	}
+	if tag := strings.TrimSpace(filters.Tag); tag != "" {
+		tx = tx.Where(dialectFor(tx).jsonArrayContains("turn_memories.tags"), tag)
+	}
+	if file := strings.TrimSpace(filters.File); file != "" {
+		tx = tx.Where(dialectFor(tx).jsonArrayContains("turn_memories.files_touched"), file)
+	}
+	if filters.Date != "" {
+		start, err := time.Parse("2006-01-02", filters.Date)
+		if err != nil {
+			return nil, ErrInvalidDateFilter
+		}
+		tx = tx.Where("turn_memories.created_at >= ? AND turn_memories.created_at < ?", start, start.Add(24*time.Hour))
+	}
+
+	from, err := parseTimeFilter("from", filters.From)
+	if err != nil {
+		return nil, err
+	}
+	to, err := parseTimeFilter("to", filters.To)
+	if err != nil {
+		return nil, err
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return nil, fmt.Errorf("%w: from must be before to", ErrInvalidFilter)
+	}
+	if !from.IsZero() {
+		tx = tx.Where("turn_memories.created_at >= ?", from)
+	}
+	if !to.IsZero() {
+		tx = tx.Where("turn_memories.created_at < ?", to)
+	}
+
+	return tx, nil
+}
+
+func (s *service) GetTurnMemory(ctx context.Context, id string) (TurnMemory, error) {
+	var rows []TurnMemory
+	if err := s.db.WithContext(ctx).Where("id = ?", strings.TrimSpace(id)).Limit(1).Find(&rows).Error; err != nil {
+		return TurnMemory{}, err
+	}
+	if len(rows) == 0 {
+		return TurnMemory{}, ErrTurnMemoryNotFound
+	}
+	return rows[0], nil
+}
+
+func (s *service) DeleteTurnMemory(ctx context.Context, id string) error {
+	result := s.db.WithContext(ctx).Where("id = ?", strings.TrimSpace(id)).Delete(&TurnMemory{})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrTurnMemoryNotFound
+	}
+	return nil
+}
+
+func compactStringList(values StringList) StringList {
+	compacted := make(StringList, 0, len(values))
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			compacted = append(compacted, trimmed)
+		}
+	}
+	return compacted
+}
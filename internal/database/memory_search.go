@@ -0,0 +1,249 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"html"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+var ErrInvalidSearchQuery = errors.New("invalid search query: q is required")
+
+const (
+	memorySearchTable        = "turn_memory_search"
+	defaultMemorySearchLimit = 20
+
+	// fts5 needs the sqlite_fts5 build tag on mattn/go-sqlite3; fts4 ships
+	// with every build and is the fallback. PostgreSQL uses a GIN-indexed
+	// tsvector expression instead of a side table.
+	memorySearchFTS5     = "fts5"
+	memorySearchFTS4     = "fts4"
+	memorySearchPostgres = "postgres"
+)
+
+// postgresMemoryDocument is the indexed text of a memory. It must match the
+// expression in idx_turn_memories_search for the index to be used.
+const postgresMemoryDocument = "coalesce(summary, '') || ' ' || coalesce(key_decisions::text, '') || ' ' || " +
+	"coalesce(context_snippet, '') || ' ' || coalesce(tags::text, '')"
+
+// TurnMemorySearchResult is a memory matched by full-text search. Snippet is
+// HTML-escaped text with matched terms wrapped in <mark> tags; higher Score
+// ranks better.
+type TurnMemorySearchResult struct {
+	TurnMemory
+	Snippet string  `json:"snippet"`
+	Score   float64 `json:"score"`
+}
+
+// The search backends delimit matches with private-use sentinels rather than
+// <mark> so the memory text can be HTML-escaped before the tags go in.
+const (
+	snippetMatchStart = "\uE000"
+	snippetMatchEnd   = "\uE001"
+)
+
+var snippetMarkReplacer = strings.NewReplacer(snippetMatchStart, "<mark>", snippetMatchEnd, "</mark>")
+
+func highlightSnippets(results []TurnMemorySearchResult) {
+	for i := range results {
+		results[i].Snippet = snippetMarkReplacer.Replace(html.EscapeString(results[i].Snippet))
+	}
+}
+
+// flattenJSONListSQL renders a JSON array column as space-separated text so
+// its entries are tokenized individually.
+func flattenJSONListSQL(column string) string {
+	return fmt.Sprintf("COALESCE((SELECT group_concat(value, ' ') FROM json_each(%s)), '')", column)
+}
+
+// detectMemorySearchModule reports which FTS module backs the memory search
+// index, or an error when the index has not been migrated.
+func detectMemorySearchModule(ctx context.Context, db *gorm.DB) (string, error) {
+	if _, ok := dialectFor(db).(postgresDialect); ok {
+		return memorySearchPostgres, nil
+	}
+
+	module, err := existingMemorySearchModule(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	if module == "" {
+		return "", errors.New("memory search index is missing; run migrations")
+	}
+	return module, nil
+}
+
+// existingMemorySearchModule reports the FTS module of the SQLite memory
+// search index, or "" when it does not exist yet. An fts5 index opened by a
+// build without fts5 is an error, since its triggers would fail every memory
+// write with "no such module".
+func existingMemorySearchModule(ctx context.Context, db *gorm.DB) (string, error) {
+	var existing []string
+	if err := db.WithContext(ctx).Raw("SELECT sql FROM sqlite_master WHERE type = 'table' AND name = ?", memorySearchTable).Scan(&existing).Error; err != nil {
+		return "", err
+	}
+	if len(existing) == 0 {
+		return "", nil
+	}
+	if !strings.Contains(strings.ToLower(existing[0]), memorySearchFTS5) {
+		return memorySearchFTS4, nil
+	}
+
+	var probe []string
+	if err := db.WithContext(ctx).Raw(fmt.Sprintf("SELECT memory_id FROM %s LIMIT 0", memorySearchTable)).Scan(&probe).Error; err != nil {
+		return "", fmt.Errorf("memory search index was created with fts5, which this build lacks; rebuild with -tags sqlite_fts5: %w", err)
+	}
+	return memorySearchFTS5, nil
+}
+
+// ensureMemorySearchIndex creates the full-text index over turn memories,
+// its sync triggers and, when the index is new, backfills existing rows. It
+// returns the FTS module backing the index.
+func ensureMemorySearchIndex(ctx context.Context, db *gorm.DB) (string, error) {
+	if _, ok := dialectFor(db).(postgresDialect); ok {
+		statement := fmt.Sprintf(
+			"CREATE INDEX IF NOT EXISTS idx_turn_memories_search ON turn_memories USING GIN (to_tsvector('simple', %s))",
+			postgresMemoryDocument,
+		)
+		if err := db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return "", fmt.Errorf("create memory search index: %w", err)
+		}
+		return memorySearchPostgres, nil
+	}
+
+	module, err := existingMemorySearchModule(ctx, db)
+	if err != nil {
+		return "", err
+	}
+	created := module == ""
+	if created {
+		fts5 := fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts5(memory_id UNINDEXED, summary, key_decisions, context_snippet, tags)", memorySearchTable)
+		fts4 := fmt.Sprintf("CREATE VIRTUAL TABLE %s USING fts4(memory_id, summary, key_decisions, context_snippet, tags, notindexed=memory_id)", memorySearchTable)
+		if err := db.WithContext(ctx).Exec(fts5).Error; err == nil {
+			module = memorySearchFTS5
+		} else if err := db.WithContext(ctx).Exec(fts4).Error; err == nil {
+			module = memorySearchFTS4
+		} else {
+			return "", fmt.Errorf("create memory search index: %w", err)
+		}
+	}
+
+	insertColumns := fmt.Sprintf("INSERT INTO %s (memory_id, summary, key_decisions, context_snippet, tags)", memorySearchTable)
+	insertValues := func(row string) string {
+		return fmt.Sprintf("%s.id, %s.summary, %s, %s.context_snippet, %s",
+			row, row, flattenJSONListSQL(row+".key_decisions"), row, flattenJSONListSQL(row+".tags"))
+	}
+	statements := []string{
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS turn_memories_search_insert AFTER INSERT ON turn_memories BEGIN %s VALUES (%s); END",
+			insertColumns, insertValues("NEW")),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS turn_memories_search_delete AFTER DELETE ON turn_memories BEGIN DELETE FROM %s WHERE memory_id = OLD.id; END",
+			memorySearchTable),
+		fmt.Sprintf("CREATE TRIGGER IF NOT EXISTS turn_memories_search_update AFTER UPDATE ON turn_memories BEGIN DELETE FROM %s WHERE memory_id = OLD.id; %s VALUES (%s); END",
+			memorySearchTable, insertColumns, insertValues("NEW")),
+	}
+	if created {
+		statements = append(statements, fmt.Sprintf("%s SELECT %s FROM turn_memories", insertColumns, insertValues("turn_memories")))
+	}
+	for _, statement := range statements {
+		if err := db.WithContext(ctx).Exec(statement).Error; err != nil {
+			return "", err
+		}
+	}
+	return module, nil
+}
+
+// memorySearchExpression quotes every whitespace-separated term so user input
+// is matched literally instead of parsed as FTS query syntax. Terms are ANDed.
+func memorySearchExpression(query string) string {
+	terms := strings.Fields(query)
+	quoted := make([]string, 0, len(terms))
+	for _, term := range terms {
+		quoted = append(quoted, `"`+strings.ReplaceAll(term, `"`, `""`)+`"`)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func (s *service) SearchTurnMemories(ctx context.Context, query string, filters MemoryFilters) ([]TurnMemorySearchResult, error) {
+	expression := memorySearchExpression(query)
+	if expression == "" {
+		return nil, ErrInvalidSearchQuery
+	}
+	if filters.Limit < 0 || filters.Limit > maxActivityPageSize {
+		return nil, ErrInvalidLimit
+	}
+	limit := filters.Limit
+	if limit == 0 {
+		limit = defaultMemorySearchLimit
+	}
+
+	if s.memorySearchModule == memorySearchPostgres {
+		return s.searchTurnMemoriesPostgres(ctx, query, filters, limit)
+	}
+
+	var snippet, score string
+	switch s.memorySearchModule {
+	case memorySearchFTS5:
+		snippet = fmt.Sprintf("snippet(%s, -1, '%s', '%s', '…', 16)", memorySearchTable, snippetMatchStart, snippetMatchEnd)
+		score = fmt.Sprintf("-bm25(%s)", memorySearchTable)
+	case memorySearchFTS4:
+		// fts4 has no built-in ranking; score by the number of matched term
+		// occurrences (offsets() emits four integers per match).
+		snippet = fmt.Sprintf("snippet(%s, '%s', '%s', '…', -1, 16)", memorySearchTable, snippetMatchStart, snippetMatchEnd)
+		score = fmt.Sprintf("(length(offsets(%[1]s)) - length(replace(offsets(%[1]s), ' ', '')) + 1) / 4.0", memorySearchTable)
+	default:
+		return nil, errors.New("memory search index is not available")
+	}
+
+	tx := s.db.WithContext(ctx).
+		Table(memorySearchTable).
+		Select("turn_memories.*, "+snippet+" AS snippet, "+score+" AS score").
+		Joins("JOIN turn_memories ON turn_memories.id = "+memorySearchTable+".memory_id").
+		Where(memorySearchTable+" MATCH ?", expression)
+	tx, err := applyMemoryFilters(tx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []TurnMemorySearchResult{}
+	if err := tx.Order("score desc").Order("turn_memories.created_at desc").Limit(limit).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	highlightSnippets(results)
+	return results, nil
+}
+
+// searchTurnMemoriesPostgres uses plainto_tsquery, which ANDs the terms and
+// ignores query operators, matching the literal behaviour of the SQLite path.
+func (s *service) searchTurnMemoriesPostgres(ctx context.Context, query string, filters MemoryFilters, limit int) ([]TurnMemorySearchResult, error) {
+	terms := strings.Join(strings.Fields(query), " ")
+	vector := fmt.Sprintf("to_tsvector('simple', %s)", postgresMemoryDocument)
+	// The headline flattens the JSON arrays so snippets don't show brackets.
+	headlineDocument := "coalesce(turn_memories.summary, '') || ' ' || " +
+		"coalesce((SELECT string_agg(value, ' ') FROM json_array_elements_text(turn_memories.key_decisions)), '') || ' ' || " +
+		"coalesce(turn_memories.context_snippet, '') || ' ' || " +
+		"coalesce((SELECT string_agg(value, ' ') FROM json_array_elements_text(turn_memories.tags)), '')"
+
+	tx := s.db.WithContext(ctx).
+		Model(&TurnMemory{}).
+		Select(
+			"turn_memories.*, "+
+				"ts_headline('simple', "+headlineDocument+", plainto_tsquery('simple', ?), 'StartSel="+snippetMatchStart+", StopSel="+snippetMatchEnd+", MaxWords=16, MinWords=4') AS snippet, "+
+				"ts_rank("+vector+", plainto_tsquery('simple', ?)) AS score",
+			terms, terms,
+		).
+		Where(vector+" @@ plainto_tsquery('simple', ?)", terms)
+	tx, err := applyMemoryFilters(tx, filters)
+	if err != nil {
+		return nil, err
+	}
+
+	results := []TurnMemorySearchResult{}
+	if err := tx.Order("score desc").Order("turn_memories.created_at desc").Limit(limit).Scan(&results).Error; err != nil {
+		return nil, err
+	}
+	highlightSnippets(results)
+	return results, nil
+}
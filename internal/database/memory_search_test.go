@@ -0,0 +1,167 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSearchTurnMemoriesRanksAndHighlightsMatches(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	for _, memory := range []*TurnMemory{
+		{SessionKey: "s-1", Summary: "reworked the fallback queue", KeyDecisions: StringList{"queue format stays markdown", "queue files rotate daily"}, CreatedAt: base},
+		{SessionKey: "s-2", Summary: "pricing refresh", KeyDecisions: StringList{"keep the queue untouched"}, CreatedAt: base.Add(time.Hour)},
+		{SessionKey: "s-3", Summary: "dashboard polish", Tags: StringList{"ui"}, CreatedAt: base.Add(2 * time.Hour)},
+	} {
+		if err := svc.CreateTurnMemory(t.Context(), memory); err != nil {
+			t.Fatalf("expected memory insert to succeed: %v", err)
+		}
+	}
+
+	results, err := svc.SearchTurnMemories(t.Context(), "queue format", MemoryFilters{})
+	if err != nil {
+		t.Fatalf("expected search to succeed: %v", err)
+	}
+	if len(results) != 1 || results[0].SessionKey != "s-1" {
+		t.Fatalf("expected only s-1 to match every term, got %#v", results)
+	}
+	if !strings.Contains(results[0].Snippet, "<mark>") {
+		t.Fatalf("expected highlighted snippet, got %q", results[0].Snippet)
+	}
+
+	results, err = svc.SearchTurnMemories(t.Context(), "queue", MemoryFilters{})
+	if err != nil {
+		t.Fatalf("expected search to succeed: %v", err)
+	}
+	if len(results) != 2 || results[0].SessionKey != "s-1" || results[0].Score <= results[1].Score {
+		t.Fatalf("expected s-1 to outrank s-2, got %#v", results)
+	}
+
+	results, err = svc.SearchTurnMemories(t.Context(), "ui", MemoryFilters{})
+	if err != nil || len(results) != 1 || results[0].SessionKey != "s-3" {
+		t.Fatalf("expected tag match for s-3, got %#v err=%v", results, err)
+	}
+
+	results, err = svc.SearchTurnMemories(t.Context(), "queue", MemoryFilters{SessionKey: "s-2"})
+	if err != nil || len(results) != 1 || results[0].SessionKey != "s-2" {
+		t.Fatalf("expected session filter to apply, got %#v err=%v", results, err)
+	}
+
+	if err := svc.DeleteTurnMemory(t.Context(), results[0].ID); err != nil {
+		t.Fatal(err)
+	}
+	results, err = svc.SearchTurnMemories(t.Context(), "untouched", MemoryFilters{})
+	if err != nil || len(results) != 0 {
+		t.Fatalf("expected deleted memory to leave the index, got %#v err=%v", results, err)
+	}
+}
+
+func TestSearchTurnMemoriesTreatsInputAsLiteralTerms(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	if err := svc.CreateTurnMemory(t.Context(), &TurnMemory{SessionKey: "s-1", Summary: "NOT a keyword here"}); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, query := range []string{`NOT`, `"unbalanced`, `summary:keyword`} {
+		if _, err := svc.SearchTurnMemories(t.Context(), query, MemoryFilters{}); err != nil {
+			t.Fatalf("expected %q to be searched literally, got %v", query, err)
+		}
+	}
+	if _, err := svc.SearchTurnMemories(t.Context(), "   ", MemoryFilters{}); !errors.Is(err, ErrInvalidSearchQuery) {
+		t.Fatalf("expected ErrInvalidSearchQuery for blank query, got %v", err)
+	}
+}
+
+func TestSearchTurnMemoriesEscapesSnippetText(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	if err := svc.CreateTurnMemory(t.Context(), &TurnMemory{SessionKey: "s-1", Summary: `<img src=x onerror="alert(1)"> queue & co`}); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := svc.SearchTurnMemories(t.Context(), "queue", MemoryFilters{})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected one match, got %#v err=%v", results, err)
+	}
+	snippet := results[0].Snippet
+	if strings.Contains(snippet, "<img") || !strings.Contains(snippet, "&lt;img") || !strings.Contains(snippet, "&amp; co") {
+		t.Fatalf("expected memory text to be HTML-escaped, got %q", snippet)
+	}
+	if !strings.Contains(snippet, "<mark>queue</mark>") {
+		t.Fatalf("expected the match to stay highlighted, got %q", snippet)
+	}
+}
+
+func TestMemorySearchIndexBackfillsExistingMemories(t *testing.T) {
+	disableOpenRouterBootstrap(t)
+	dbPath := filepath.Join(t.TempDir(), "clawtivity.db")
+
+	svc, err := newSQLiteService(dbPath)
+	if err != nil {
+		t.Fatalf("expected adapter to initialize: %v", err)
+	}
+	if err := svc.CreateTurnMemory(t.Context(), &TurnMemory{SessionKey: "s-1", Summary: "backfilled decision"}); err != nil {
+		t.Fatal(err)
+	}
+	// Simulate a database migrated before the search index existed.
+	if err := svc.db.Exec("DROP TABLE " + memorySearchTable).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.db.Exec("DELETE FROM schema_migrations WHERE name = ?", "create_turn_memory_search_index").Error; err != nil {
+		t.Fatal(err)
+	}
+	_ = svc.Close()
+
+	reopened, err := newSQLiteService(dbPath)
+	if err != nil {
+		t.Fatalf("expected adapter to reopen: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = reopened.Close()
+	})
+
+	results, err := reopened.SearchTurnMemories(t.Context(), "backfilled", MemoryFilters{})
+	if err != nil || len(results) != 1 {
+		t.Fatalf("expected backfilled memory to be searchable, got %#v err=%v", results, err)
+	}
+}
+
+func TestMemorySearchIndexFromFTS5BuildFailsWithoutFTS5(t *testing.T) {
+	disableOpenRouterBootstrap(t)
+	dbPath := filepath.Join(t.TempDir(), "clawtivity.db")
+
+	svc, err := newSQLiteService(dbPath)
+	if err != nil {
+		t.Fatalf("expected adapter to initialize: %v", err)
+	}
+	if svc.memorySearchModule == memorySearchFTS5 {
+		_ = svc.Close()
+		t.Skip("needs a build without the sqlite_fts5 tag")
+	}
+	// Simulate a database first opened by a build with the sqlite_fts5 tag.
+	statements := []string{
+		"PRAGMA writable_schema = ON",
+		"UPDATE sqlite_master SET sql = replace(sql, 'fts4', 'fts5') WHERE name = '" + memorySearchTable + "'",
+		"PRAGMA writable_schema = OFF",
+	}
+	for _, statement := range statements {
+		if err := svc.db.Exec(statement).Error; err != nil {
+			t.Fatal(err)
+		}
+	}
+	_ = svc.Close()
+
+	reopened, err := newSQLiteService(dbPath)
+	if err == nil {
+		_ = reopened.Close()
+		t.Fatal("expected opening an fts5 index without fts5 to fail")
+	}
+	if !strings.Contains(err.Error(), "sqlite_fts5") {
+		t.Fatalf("expected the error to name the sqlite_fts5 build tag, got %v", err)
+	}
+}
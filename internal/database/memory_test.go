@@ -0,0 +1,96 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestTurnMemoryRoundTripsJSONArrays(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	memory := TurnMemory{
+		SessionKey:   "mem-1",
+		Summary:      "wired memory API",
+		ToolsUsed:    StringList{"go", " gorm ", ""},
+		FilesTouched: StringList{"internal/database/memory.go"},
+		Tags:         StringList{"api"},
+	}
+	if err := svc.CreateTurnMemory(t.Context(), &memory); err != nil {
+		t.Fatalf("expected memory insert to succeed: %v", err)
+	}
+
+	got, err := svc.GetTurnMemory(t.Context(), memory.ID)
+	if err != nil {
+		t.Fatalf("expected memory lookup to succeed: %v", err)
+	}
+	if len(got.ToolsUsed) != 2 || got.ToolsUsed[1] != "gorm" {
+		t.Fatalf("expected compacted tools_used, got %#v", got.ToolsUsed)
+	}
+	if got.KeyDecisions == nil || len(got.KeyDecisions) != 0 {
+		t.Fatalf("expected empty key_decisions list, got %#v", got.KeyDecisions)
+	}
+
+	if err := svc.DeleteTurnMemory(t.Context(), memory.ID); err != nil {
+		t.Fatalf("expected delete to succeed: %v", err)
+	}
+	if _, err := svc.GetTurnMemory(t.Context(), memory.ID); !errors.Is(err, ErrTurnMemoryNotFound) {
+		t.Fatalf("expected ErrTurnMemoryNotFound after delete, got %v", err)
+	}
+	if err := svc.DeleteTurnMemory(t.Context(), memory.ID); !errors.Is(err, ErrTurnMemoryNotFound) {
+		t.Fatalf("expected ErrTurnMemoryNotFound on repeated delete, got %v", err)
+	}
+}
+
+func TestListTurnMemoriesFiltersByTagFileAndRange(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	for _, memory := range []*TurnMemory{
+		{SessionKey: "s-1", Summary: "one", Tags: StringList{"api", "db"}, FilesTouched: StringList{"a.go"}, CreatedAt: base},
+		{SessionKey: "s-1", Summary: "two", Tags: StringList{"db"}, FilesTouched: StringList{"b.go"}, CreatedAt: base.Add(time.Hour)},
+		{SessionKey: "s-2", Summary: "three", Tags: StringList{"api"}, FilesTouched: StringList{"b.go"}, CreatedAt: base.Add(48 * time.Hour)},
+	} {
+		if err := svc.CreateTurnMemory(t.Context(), memory); err != nil {
+			t.Fatalf("expected memory insert to succeed: %v", err)
+		}
+	}
+
+	cases := []struct {
+		filters MemoryFilters
+		want    []string
+	}{
+		{MemoryFilters{Tag: "api"}, []string{"three", "one"}},
+		{MemoryFilters{File: "b.go", SessionKey: "s-1"}, []string{"two"}},
+		{MemoryFilters{Date: "2026-02-18"}, []string{"two", "one"}},
+		{MemoryFilters{From: "2026-02-18T10:30:00Z"}, []string{"three", "two"}},
+	}
+	for _, tc := range cases {
+		got, err := svc.ListTurnMemories(t.Context(), tc.filters)
+		if err != nil {
+			t.Fatalf("expected list to succeed for %#v: %v", tc.filters, err)
+		}
+		summaries := make([]string, 0, len(got))
+		for _, memory := range got {
+			summaries = append(summaries, memory.Summary)
+		}
+		if len(summaries) != len(tc.want) {
+			t.Fatalf("expected %v for %#v, got %v", tc.want, tc.filters, summaries)
+		}
+		for i := range summaries {
+			if summaries[i] != tc.want[i] {
+				t.Fatalf("expected %v for %#v, got %v", tc.want, tc.filters, summaries)
+			}
+		}
+	}
+}
+
+func TestCreateTurnMemoryRequiresSessionAndSummary(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	for _, memory := range []TurnMemory{{Summary: "no session"}, {SessionKey: "s-1", Summary: "  "}} {
+		if err := svc.CreateTurnMemory(t.Context(), &memory); !errors.Is(err, ErrInvalidTurnMemory) {
+			t.Fatalf("expected ErrInvalidTurnMemory for %#v, got %v", memory, err)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package database
+
+import (
+	"time"
+
+	"clawtivity/internal/metrics"
+	"gorm.io/gorm"
+)
+
+var (
+	queryDurationSeconds = metrics.Default.NewHistogramVec(
+		"clawtivity_db_query_duration_seconds",
+		"Database statement latency by operation and table.",
+		metrics.DefaultBuckets,
+		"operation", "table",
+	)
+	pricingRefreshTotal = metrics.Default.NewCounterVec(
+		"clawtivity_pricing_refresh_total",
+		"Pricing refresh attempts by source and outcome (success, failure, skipped).",
+		"source", "outcome",
+	)
+)
+
+const queryStartedAtKey = "clawtivity:query_started_at"
+
+// registerQueryMetrics times every GORM statement on db through callbacks so
+// raw queries are covered as well as model operations.
+func registerQueryMetrics(db *gorm.DB) error {
+	callbacks := db.Callback()
+	registrations := []struct {
+		operation string
+		before    func(string, func(*gorm.DB)) error
+		after     func(string, func(*gorm.DB)) error
+	}{
+		{"create", callbacks.Create().Before("gorm:create").Register, callbacks.Create().After("gorm:create").Register},
+		{"query", callbacks.Query().Before("gorm:query").Register, callbacks.Query().After("gorm:query").Register},
+		{"update", callbacks.Update().Before("gorm:update").Register, callbacks.Update().After("gorm:update").Register},
+		{"delete", callbacks.Delete().Before("gorm:delete").Register, callbacks.Delete().After("gorm:delete").Register},
+		{"row", callbacks.Row().Before("gorm:row").Register, callbacks.Row().After("gorm:row").Register},
+		{"raw", callbacks.Raw().Before("gorm:raw").Register, callbacks.Raw().After("gorm:raw").Register},
+	}
+	for _, r := range registrations {
+		if err := r.before("clawtivity:metrics_before_"+r.operation, startQueryTimer); err != nil {
+			return err
+		}
+		if err := r.after("clawtivity:metrics_after_"+r.operation, observeQueryDuration(r.operation)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func startQueryTimer(tx *gorm.DB) {
+	tx.InstanceSet(queryStartedAtKey, time.Now())
+}
+
+func observeQueryDuration(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(queryStartedAtKey)
+		if !ok {
+			return
+		}
+		startedAt, ok := value.(time.Time)
+		if !ok {
+			return
+		}
+		table := tx.Statement.Table
+		if table == "" {
+			table = "unknown"
+		}
+		queryDurationSeconds.Observe(time.Since(startedAt).Seconds(), operation, table)
+	}
+}
@@ -0,0 +1,566 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+)
+
+// SchemaMigration records an applied migration in schema_migrations.
+type SchemaMigration struct {
+	Version   string    `gorm:"primaryKey;size:64" json:"version"`
+	Name      string    `json:"name"`
+	AppliedAt time.Time `json:"applied_at"`
+}
+
+func (SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// MigrationStatus reports whether a known migration has been applied.
+type MigrationStatus struct {
+	Version   string     `json:"version"`
+	Name      string     `json:"name"`
+	AppliedAt *time.Time `json:"applied_at,omitempty"`
+}
+
+// MigrationPlan is a pending migration and, for dry runs, the statements it
+// would execute.
+type MigrationPlan struct {
+	Version    string   `json:"version"`
+	Name       string   `json:"name"`
+	Statements []string `json:"statements,omitempty"`
+}
+
+type migration struct {
+	version string
+	name    string
+	up      func(ctx context.Context, tx *gorm.DB) error
+}
+
+// migrations is the ordered schema history. Append new entries; never edit or
+// reorder applied ones. Migrations use frozen snapshot structs rather than
+// the live models so replaying them always produces the same schema.
+var migrations = []migration{
+	{version: "0001", name: "create_core_tables", up: migrateCreateCoreTables},
+	{version: "0002", name: "add_activity_idempotency_key", up: migrateAddActivityIdempotencyKey},
+	{version: "0003", name: "create_turn_memory_search_index", up: migrateCreateTurnMemorySearchIndex},
+	{version: "0004", name: "add_activity_pricing_id", up: migrateAddActivityPricingID},
+	{version: "0005", name: "add_cache_and_reasoning_token_accounting", up: migrateAddCacheAndReasoningTokenAccounting},
+	{version: "0006", name: "create_pricing_source_status", up: migrateCreatePricingSourceStatus},
+	{version: "0007", name: "create_model_aliases_and_cost_match", up: migrateCreateModelAliasesAndCostMatch},
+	{version: "0008", name: "create_exchange_rates_and_cost_currency", up: migrateCreateExchangeRatesAndCostCurrency},
+	{version: "0009", name: "create_budgets", up: migrateCreateBudgets},
+	{version: "0010", name: "create_webhooks", up: migrateCreateWebhooks},
+	{version: "0011", name: "create_activity_features", up: migrateCreateActivityFeatures},
+	{version: "0012", name: "create_activity_categories", up: migrateCreateActivityCategories},
+	{version: "0013", name: "add_activity_signals", up: migrateAddActivitySignals},
+	{version: "0014", name: "add_activity_signals_truncated", up: migrateAddActivitySignalsTruncated},
+}
+
+type projectV1 struct {
+	ID          string `gorm:"type:char(36);primaryKey"`
+	Slug        string `gorm:"uniqueIndex:idx_projects_slug"`
+	DisplayName string
+	Status      string    `gorm:"index:idx_projects_status"`
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+	UpdatedAt   time.Time `gorm:"autoUpdateTime"`
+}
+
+func (projectV1) TableName() string { return "projects" }
+
+type activityFeedV1 struct {
+	ID               string `gorm:"type:char(36);primaryKey"`
+	SessionKey       string `gorm:"index:idx_activity_feed_session_key"`
+	Model            string
+	TokensIn         int
+	TokensOut        int
+	CostEstimate     float64
+	DurationMS       int64
+	ProjectID        string    `gorm:"type:char(36);index:idx_activity_feed_project_id"`
+	Project          projectV1 `gorm:"foreignKey:ProjectID;references:ID;constraint:OnUpdate:CASCADE,OnDelete:RESTRICT;"`
+	LegacyProjectTag string    `gorm:"column:project_tag"`
+	ProjectReason    string
+	ExternalRef      string
+	Category         string `gorm:"index:idx_activity_feed_category"`
+	CategoryReason   string
+	Thinking         string
+	Reasoning        bool
+	Channel          string
+	Status           string    `gorm:"index:idx_activity_feed_status"`
+	UserID           string    `gorm:"index:idx_activity_feed_user_id"`
+	CreatedAt        time.Time `gorm:"autoCreateTime"`
+}
+
+func (activityFeedV1) TableName() string { return "activity_feed" }
+
+type turnMemoryV1 struct {
+	ID             string `gorm:"type:char(36);primaryKey"`
+	SessionKey     string `gorm:"index:idx_turn_memories_session_key"`
+	Summary        string
+	ToolsUsed      string `gorm:"type:json"`
+	FilesTouched   string `gorm:"type:json"`
+	KeyDecisions   string `gorm:"type:json"`
+	ContextSnippet string
+	Tags           string    `gorm:"type:json"`
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+}
+
+func (turnMemoryV1) TableName() string { return "turn_memories" }
+
+type modelPricingV1 struct {
+	ID                 string    `gorm:"type:char(36);primaryKey"`
+	Provider           string    `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:1;index:idx_model_pricing_source"`
+	Model              string    `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:2"`
+	EffectiveFrom      time.Time `gorm:"uniqueIndex:idx_model_pricing_lookup,priority:3"`
+	InputCostPer1M     float64   `gorm:"column:input_cost_per_1m"`
+	OutputCostPer1M    float64   `gorm:"column:output_cost_per_1m"`
+	ReasoningCostPer1M *float64  `gorm:"column:reasoning_cost_per_1m"`
+	Currency           string
+	Source             string `gorm:"index:idx_model_pricing_source"`
+	IsEstimated        bool
+	IsStale            bool       `gorm:"index:idx_model_pricing_stale"`
+	LastVerifiedAt     *time.Time `gorm:"column:last_verified_at"`
+	VerificationNotes  string     `gorm:"column:verification_notes"`
+	CreatedAt          time.Time  `gorm:"autoCreateTime"`
+	UpdatedAt          time.Time  `gorm:"autoUpdateTime"`
+}
+
+func (modelPricingV1) TableName() string { return "model_pricing" }
+
+type activityFeedIdempotencyKeyV2 struct {
+	IdempotencyKey *string `gorm:"column:idempotency_key;uniqueIndex:idx_activity_feed_idempotency_key"`
+}
+
+func (activityFeedIdempotencyKeyV2) TableName() string { return "activity_feed" }
+
+type activityFeedPricingIDV4 struct {
+	PricingID *string `gorm:"column:pricing_id;type:char(36);index:idx_activity_feed_pricing_id"`
+}
+
+func (activityFeedPricingIDV4) TableName() string { return "activity_feed" }
+
+type activityFeedTokenBucketsV5 struct {
+	CacheReadTokens  int
+	CacheWriteTokens int
+	ReasoningTokens  int
+}
+
+func (activityFeedTokenBucketsV5) TableName() string { return "activity_feed" }
+
+type modelPricingCacheRatesV5 struct {
+	CacheReadCostPer1M  *float64 `gorm:"column:cache_read_cost_per_1m"`
+	CacheWriteCostPer1M *float64 `gorm:"column:cache_write_cost_per_1m"`
+}
+
+func (modelPricingCacheRatesV5) TableName() string { return "model_pricing" }
+
+type pricingSourceStatusV6 struct {
+	Name           string `gorm:"primaryKey"`
+	LastAttemptAt  *time.Time
+	LastSuccessAt  *time.Time
+	LastError      string
+	ModelsImported int
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (pricingSourceStatusV6) TableName() string { return "pricing_source_status" }
+
+type modelAliasV7 struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	Alias     string `gorm:"uniqueIndex:idx_model_aliases_alias"`
+	Target    string
+	Notes     string
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (modelAliasV7) TableName() string { return "model_aliases" }
+
+type activityFeedCostMatchV7 struct {
+	CostMatch string `gorm:"column:cost_match;index:idx_activity_feed_cost_match"`
+}
+
+func (activityFeedCostMatchV7) TableName() string { return "activity_feed" }
+
+type exchangeRateV8 struct {
+	ID            string    `gorm:"type:char(36);primaryKey"`
+	Currency      string    `gorm:"uniqueIndex:idx_exchange_rates_lookup,priority:1"`
+	EffectiveFrom time.Time `gorm:"uniqueIndex:idx_exchange_rates_lookup,priority:2"`
+	Rate          float64
+	Source        string
+	CreatedAt     time.Time `gorm:"autoCreateTime"`
+	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
+}
+
+func (exchangeRateV8) TableName() string { return "exchange_rates" }
+
+type activityFeedCostCurrencyV8 struct {
+	CostCurrency string `gorm:"column:cost_currency;default:USD"`
+}
+
+func (activityFeedCostCurrencyV8) TableName() string { return "activity_feed" }
+
+type budgetV9 struct {
+	ID        string  `gorm:"type:char(36);primaryKey"`
+	ProjectID *string `gorm:"type:char(36);index:idx_budgets_project_id"`
+	Period    string
+	Limit     float64   `gorm:"column:limit_amount"`
+	Currency  string    `gorm:"default:USD"`
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (budgetV9) TableName() string { return "budgets" }
+
+type budgetAlertV9 struct {
+	ID          string    `gorm:"type:char(36);primaryKey"`
+	BudgetID    string    `gorm:"type:char(36);uniqueIndex:idx_budget_alerts_crossing,priority:1"`
+	PeriodStart time.Time `gorm:"uniqueIndex:idx_budget_alerts_crossing,priority:2"`
+	Threshold   int       `gorm:"uniqueIndex:idx_budget_alerts_crossing,priority:3"`
+	Spend       float64
+	Limit       float64 `gorm:"column:limit_amount"`
+	Currency    string
+	CreatedAt   time.Time `gorm:"autoCreateTime"`
+}
+
+func (budgetAlertV9) TableName() string { return "budget_alerts" }
+
+type webhookV10 struct {
+	ID        string `gorm:"type:char(36);primaryKey"`
+	URL       string
+	Secret    string
+	Events    string `gorm:"type:json"`
+	Enabled   bool
+	CreatedAt time.Time `gorm:"autoCreateTime"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (webhookV10) TableName() string { return "webhooks" }
+
+type webhookDeliveryV10 struct {
+	ID             string `gorm:"type:char(36);primaryKey"`
+	WebhookID      string `gorm:"type:char(36);index:idx_webhook_deliveries_webhook_id"`
+	Event          string
+	Payload        string
+	Status         string `gorm:"index:idx_webhook_deliveries_due,priority:1"`
+	Attempts       int
+	NextAttemptAt  *time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2"`
+	LastAttemptAt  *time.Time
+	ResponseStatus int
+	LastError      string
+	CreatedAt      time.Time `gorm:"autoCreateTime"`
+	UpdatedAt      time.Time `gorm:"autoUpdateTime"`
+}
+
+func (webhookDeliveryV10) TableName() string { return "webhook_deliveries" }
+
+type activityFeatureV11 struct {
+	ActivityID   string     `gorm:"type:char(36);primaryKey"`
+	PromptTokens StringList `gorm:"type:json"`
+	CreatedAt    time.Time  `gorm:"autoCreateTime"`
+}
+
+func (activityFeatureV11) TableName() string { return "activity_features" }
+
+type activityCategoryV12 struct {
+	ActivityID string `gorm:"type:char(36);primaryKey"`
+	Category   string `gorm:"primaryKey;index:idx_activity_categories_category"`
+	Rank       int
+	Score      float64
+}
+
+func (activityCategoryV12) TableName() string { return "activity_categories" }
+
+type activityFeatureSignalsV13 struct {
+	ActivityID    string `gorm:"type:char(36);primaryKey"`
+	Signals       string
+	PromptDigest  string
+	PromptText    string
+	AssistantText string
+	ToolsUsed     StringList `gorm:"type:json"`
+}
+
+func (activityFeatureSignalsV13) TableName() string { return "activity_features" }
+
+type activityFeatureTruncatedV14 struct {
+	Truncated bool `gorm:"default:false"`
+}
+
+func (activityFeatureTruncatedV14) TableName() string { return "activity_features" }
+
+// migrateCreateCoreTables creates the baseline schema and moves databases
+// from the free-form project_tag column to project_id references. Legacy tags
+// are read before the table is altered because SQLite rebuilds it to add the
+// project foreign key.
+func migrateCreateCoreTables(ctx context.Context, tx *gorm.DB) error {
+	legacyProjectTags, err := loadLegacyProjectTags(ctx, tx)
+	if err != nil {
+		return err
+	}
+	if err := tx.WithContext(ctx).AutoMigrate(&projectV1{}, &activityFeedV1{}, &turnMemoryV1{}, &modelPricingV1{}); err != nil {
+		return err
+	}
+	return synchronizeActivityProjectIDs(ctx, tx, legacyProjectTags)
+}
+
+func migrateAddActivityIdempotencyKey(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&activityFeedIdempotencyKeyV2{})
+}
+
+func migrateAddActivityPricingID(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&activityFeedPricingIDV4{})
+}
+
+func migrateAddCacheAndReasoningTokenAccounting(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&activityFeedTokenBucketsV5{}, &modelPricingCacheRatesV5{})
+}
+
+func migrateCreatePricingSourceStatus(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&pricingSourceStatusV6{})
+}
+
+// migrateCreateModelAliasesAndCostMatch replaces the hardcoded codex-mini
+// alias with seeded rows. Existing activities cannot tell an alias match
+// from a direct one, so anything already priced is backfilled as matched.
+func migrateCreateModelAliasesAndCostMatch(ctx context.Context, tx *gorm.DB) error {
+	if err := tx.WithContext(ctx).AutoMigrate(&modelAliasV7{}, &activityFeedCostMatchV7{}); err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, alias := range []string{"gpt-5-codex-mini", "openai/gpt-5-codex-mini"} {
+		row := modelAliasV7{
+			ID:        generateUUIDv4(),
+			Alias:     alias,
+			Target:    "gpt-5-mini",
+			Notes:     "Priced as gpt-5-mini until a codex-mini rate is published.",
+			CreatedAt: now,
+			UpdatedAt: now,
+		}
+		if err := tx.WithContext(ctx).Where("alias = ?", alias).FirstOrCreate(&row).Error; err != nil {
+			return err
+		}
+	}
+
+	if err := tx.WithContext(ctx).Exec(
+		"UPDATE activity_feed SET cost_match = ? WHERE pricing_id IS NOT NULL OR cost_estimate > 0", CostMatchMatched,
+	).Error; err != nil {
+		return err
+	}
+	return tx.WithContext(ctx).Exec(
+		"UPDATE activity_feed SET cost_match = ? WHERE pricing_id IS NULL AND cost_estimate = 0", CostMatchMissing,
+	).Error
+}
+
+// migrateCreateExchangeRatesAndCostCurrency records the currency each
+// activity was costed in, taken from the pricing row that priced it.
+func migrateCreateExchangeRatesAndCostCurrency(ctx context.Context, tx *gorm.DB) error {
+	if err := tx.WithContext(ctx).AutoMigrate(&exchangeRateV8{}, &activityFeedCostCurrencyV8{}); err != nil {
+		return err
+	}
+	return tx.WithContext(ctx).Exec(
+		"UPDATE activity_feed SET cost_currency = COALESCE(" +
+			"(SELECT UPPER(model_pricing.currency) FROM model_pricing WHERE model_pricing.id = activity_feed.pricing_id AND model_pricing.currency <> ''), 'USD')",
+	).Error
+}
+
+func migrateCreateBudgets(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&budgetV9{}, &budgetAlertV9{})
+}
+
+func migrateCreateWebhooks(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&webhookV10{}, &webhookDeliveryV10{})
+}
+
+func migrateCreateActivityFeatures(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&activityFeatureV11{})
+}
+
+// migrateCreateActivityCategories gives every existing activity its current
+// category as the only, primary label.
+func migrateCreateActivityCategories(ctx context.Context, tx *gorm.DB) error {
+	if err := tx.WithContext(ctx).AutoMigrate(&activityCategoryV12{}); err != nil {
+		return err
+	}
+	return tx.WithContext(ctx).Exec(
+		"INSERT INTO activity_categories (activity_id, category, rank, score) " +
+			"SELECT id, category, 0, 1 FROM activity_feed WHERE category <> ''",
+	).Error
+}
+
+func migrateAddActivitySignals(ctx context.Context, tx *gorm.DB) error {
+	return tx.WithContext(ctx).AutoMigrate(&activityFeatureSignalsV13{})
+}
+
+// migrateAddActivitySignalsTruncated marks every activity stored with signals
+// before truncation was recorded as truncated, since it can no longer be told.
+func migrateAddActivitySignalsTruncated(ctx context.Context, tx *gorm.DB) error {
+	if err := tx.WithContext(ctx).AutoMigrate(&activityFeatureTruncatedV14{}); err != nil {
+		return err
+	}
+	return tx.WithContext(ctx).
+		Model(&activityFeatureTruncatedV14{}).
+		Where("signals <> ''").
+		Update("truncated", true).Error
+}
+
+func migrateCreateTurnMemorySearchIndex(ctx context.Context, tx *gorm.DB) error {
+	_, err := ensureMemorySearchIndex(ctx, tx)
+	return err
+}
+
+func appliedMigrations(ctx context.Context, db *gorm.DB) (map[string]SchemaMigration, error) {
+	applied := map[string]SchemaMigration{}
+	if !tableExists(ctx, db, SchemaMigration{}.TableName()) {
+		return applied, nil
+	}
+
+	var rows []SchemaMigration
+	if err := db.WithContext(ctx).Order("version asc").Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func migrationStatuses(ctx context.Context, db *gorm.DB) ([]MigrationStatus, error) {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	statuses := make([]MigrationStatus, 0, len(migrations))
+	for _, m := range migrations {
+		status := MigrationStatus{Version: m.version, Name: m.name}
+		if row, ok := applied[m.version]; ok {
+			appliedAt := row.AppliedAt.UTC()
+			status.AppliedAt = &appliedAt
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// applyMigrations runs pending migrations in order, each in its own
+// transaction together with its schema_migrations row. With dryRun every
+// pending migration runs inside one transaction that is rolled back, and the
+// schema statements they issued are returned instead.
+func applyMigrations(ctx context.Context, db *gorm.DB, dryRun bool) ([]MigrationPlan, error) {
+	applied, err := appliedMigrations(ctx, db)
+	if err != nil {
+		return nil, err
+	}
+
+	var pending []migration
+	for _, m := range migrations {
+		if _, ok := applied[m.version]; !ok {
+			pending = append(pending, m)
+		}
+	}
+	plans := make([]MigrationPlan, 0, len(pending))
+	if len(pending) == 0 {
+		return plans, nil
+	}
+
+	if dryRun {
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			for _, m := range pending {
+				recorder := &statementRecorder{Interface: logger.Discard}
+				if err := m.up(ctx, tx.Session(&gorm.Session{Logger: recorder})); err != nil {
+					return fmt.Errorf("migration %s_%s: %w", m.version, m.name, err)
+				}
+				plans = append(plans, MigrationPlan{Version: m.version, Name: m.name, Statements: recorder.statements})
+			}
+			return errDryRunRollback
+		})
+		if !errors.Is(err, errDryRunRollback) {
+			return nil, err
+		}
+		return plans, nil
+	}
+
+	if err := db.WithContext(ctx).AutoMigrate(&SchemaMigration{}); err != nil {
+		return nil, err
+	}
+	for _, m := range pending {
+		err := db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := m.up(ctx, tx); err != nil {
+				return err
+			}
+			return tx.Create(&SchemaMigration{Version: m.version, Name: m.name, AppliedAt: time.Now().UTC()}).Error
+		})
+		if err != nil {
+			return plans, fmt.Errorf("migration %s_%s: %w", m.version, m.name, err)
+		}
+		plans = append(plans, MigrationPlan{Version: m.version, Name: m.name})
+	}
+	return plans, nil
+}
+
+var errDryRunRollback = errors.New("dry run rollback")
+
+// statementRecorder is a GORM logger that keeps the schema-changing SQL a
+// dry run would have executed, skipping the introspection queries.
+type statementRecorder struct {
+	logger.Interface
+	statements []string
+}
+
+func (r *statementRecorder) LogMode(logger.LogLevel) logger.Interface {
+	return r
+}
+
+func (r *statementRecorder) Trace(_ context.Context, _ time.Time, fc func() (string, int64), _ error) {
+	statement, _ := fc()
+	trimmed := strings.TrimSpace(statement)
+	upper := strings.ToUpper(trimmed)
+	if trimmed == "" || strings.HasPrefix(upper, "SELECT") || strings.HasPrefix(upper, "PRAGMA") ||
+		strings.HasPrefix(upper, "SAVEPOINT") || strings.HasPrefix(upper, "RELEASE") || strings.HasPrefix(upper, "ROLLBACK") {
+		return
+	}
+	r.statements = append(r.statements, trimmed)
+}
+
+// MigrationRunner applies or inspects schema migrations for the database in
+// BLUEPRINT_DB_URL without starting the service or pricing refresh.
+type MigrationRunner struct {
+	db  *gorm.DB
+	dsn string
+}
+
+func NewMigrationRunner(dsn string) (*MigrationRunner, error) {
+	gormDB, resolvedDSN, err := openGormDB(dsn)
+	if err != nil {
+		return nil, err
+	}
+	return &MigrationRunner{db: gormDB, dsn: resolvedDSN}, nil
+}
+
+func (r *MigrationRunner) Status(ctx context.Context) ([]MigrationStatus, error) {
+	return migrationStatuses(ctx, r.db)
+}
+
+func (r *MigrationRunner) Up(ctx context.Context, dryRun bool) ([]MigrationPlan, error) {
+	return applyMigrations(ctx, r.db, dryRun)
+}
+
+// Target is the redacted DSN the runner operates on.
+func (r *MigrationRunner) Target() string {
+	return redactDSN(r.dsn)
+}
+
+func (r *MigrationRunner) Close() error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.Close()
+}
@@ -0,0 +1,189 @@
+package database
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestApplyMigrationsRecordsEveryVersionOnce(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	statuses, err := migrationStatuses(t.Context(), svc.db)
+	if err != nil {
+		t.Fatalf("expected status to load: %v", err)
+	}
+	if len(statuses) != len(migrations) {
+		t.Fatalf("expected %d statuses, got %d", len(migrations), len(statuses))
+	}
+	for _, status := range statuses {
+		if status.AppliedAt == nil {
+			t.Fatalf("expected migration %s_%s to be applied", status.Version, status.Name)
+		}
+	}
+
+	plans, err := applyMigrations(t.Context(), svc.db, false)
+	if err != nil {
+		t.Fatalf("expected re-run to succeed: %v", err)
+	}
+	if len(plans) != 0 {
+		t.Fatalf("expected no pending migrations on re-run, got %#v", plans)
+	}
+
+	var count int64
+	if err := svc.db.Model(&SchemaMigration{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != int64(len(migrations)) {
+		t.Fatalf("expected one schema_migrations row per migration, got %d", count)
+	}
+}
+
+func TestApplyMigrationsDryRunLeavesSchemaUntouched(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "clawtivity.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := applyMigrations(t.Context(), db, true)
+	if err != nil {
+		t.Fatalf("expected dry run to succeed: %v", err)
+	}
+	if len(plans) != len(migrations) {
+		t.Fatalf("expected every migration to be planned, got %#v", plans)
+	}
+	if len(plans[0].Statements) == 0 || !strings.Contains(strings.Join(plans[0].Statements, "\n"), "CREATE TABLE `activity_feed`") {
+		t.Fatalf("expected dry run to report CREATE TABLE statements, got %#v", plans[0].Statements)
+	}
+
+	for _, table := range []string{"activity_feed", "projects", SchemaMigration{}.TableName()} {
+		if tableExists(t.Context(), db, table) {
+			t.Fatalf("expected dry run not to create %s", table)
+		}
+	}
+
+	statuses, err := migrationStatuses(t.Context(), db)
+	if err != nil {
+		t.Fatalf("expected status to load without schema_migrations: %v", err)
+	}
+	if len(statuses) != len(migrations) || statuses[0].AppliedAt != nil {
+		t.Fatalf("expected every migration pending, got %#v", statuses)
+	}
+}
+
+func TestApplyMigrationsResumesFromRecordedVersion(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "clawtivity.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AutoMigrate(&SchemaMigration{}); err != nil {
+		t.Fatal(err)
+	}
+	if err := migrations[0].up(t.Context(), db); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Create(&SchemaMigration{Version: migrations[0].version, Name: migrations[0].name}).Error; err != nil {
+		t.Fatal(err)
+	}
+
+	plans, err := applyMigrations(t.Context(), db, false)
+	if err != nil {
+		t.Fatalf("expected pending migrations to apply: %v", err)
+	}
+	if len(plans) != len(migrations)-1 || plans[0].Version != migrations[1].version {
+		t.Fatalf("expected to resume after %s, got %#v", migrations[0].version, plans)
+	}
+}
+
+// The live models must never drift from what the migrations create; a new
+// field needs a new migration.
+func TestMigrationsCoverCurrentModels(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	for _, model := range []any{&Project{}, &ActivityFeed{}, &TurnMemory{}, &ModelPricing{}, &PricingSourceStatus{}, &ModelAlias{}, &ExchangeRate{}, &Budget{}, &BudgetAlert{}, &Webhook{}, &WebhookDelivery{}, &ActivityFeature{}, &ActivityCategory{}} {
+		stmt := &gorm.Statement{DB: svc.db}
+		if err := stmt.Parse(model); err != nil {
+			t.Fatal(err)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName == "" {
+				continue
+			}
+			if !svc.db.Migrator().HasColumn(model, field.DBName) {
+				t.Fatalf("expected %s.%s to be created by a migration", stmt.Schema.Table, field.DBName)
+			}
+		}
+		for _, index := range stmt.Schema.ParseIndexes() {
+			if !svc.db.Migrator().HasIndex(model, index.Name) {
+				t.Fatalf("expected index %s to be created by a migration", index.Name)
+			}
+		}
+	}
+}
+
+func TestCreateActivityCategoriesBackfillsPrimaryLabels(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "clawtivity.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backfill migration
+	for _, m := range migrations {
+		if m.version == "0012" {
+			backfill = m
+			break
+		}
+		if err := m.up(t.Context(), db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec("INSERT INTO activity_feed (id, category) VALUES ('a1', 'code'), ('a2', '')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backfill.up(t.Context(), db); err != nil {
+		t.Fatalf("expected migration to apply: %v", err)
+	}
+
+	var labels []ActivityCategory
+	if err := db.Find(&labels).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(labels) != 1 || labels[0] != (ActivityCategory{ActivityID: "a1", Category: "code", Score: 1}) {
+		t.Fatalf("expected one primary label for the categorized activity, got %#v", labels)
+	}
+}
+
+func TestAddActivitySignalsTruncatedMarksExistingSignals(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "clawtivity.db")), &gorm.Config{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var backfill migration
+	for _, m := range migrations {
+		if m.version == "0014" {
+			backfill = m
+			break
+		}
+		if err := m.up(t.Context(), db); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.Exec("INSERT INTO activity_features (activity_id, signals) VALUES ('a1', 'text'), ('a2', '')").Error; err != nil {
+		t.Fatal(err)
+	}
+
+	if err := backfill.up(t.Context(), db); err != nil {
+		t.Fatalf("expected migration to apply: %v", err)
+	}
+
+	var features []ActivityFeature
+	if err := db.Order("activity_id asc").Find(&features).Error; err != nil {
+		t.Fatal(err)
+	}
+	if len(features) != 2 || !features[0].Truncated || features[1].Truncated {
+		t.Fatalf("expected only signalled features to be marked truncated, got %#v", features)
+	}
+}
@@ -0,0 +1,233 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrModelAliasNotFound = errors.New("model alias not found")
+var ErrInvalidModelAlias = errors.New("invalid model alias")
+var ErrModelAliasConflict = errors.New("model alias already exists")
+
+// Cost match outcomes stored in activity_feed.cost_match.
+const (
+	CostMatchMatched = "matched"
+	CostMatchAlias   = "alias"
+	CostMatchMissing = "missing"
+)
+
+// ModelAlias prices activities reported under Alias with the pricing rows of
+// Target, for models the catalog knows under another name.
+type ModelAlias struct {
+	ID        string    `gorm:"type:char(36);primaryKey" json:"id"`
+	Alias     string    `gorm:"uniqueIndex:idx_model_aliases_alias" json:"alias"`
+	Target    string    `json:"target"`
+	Notes     string    `json:"notes"`
+	CreatedAt time.Time `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (ModelAlias) TableName() string {
+	return "model_aliases"
+}
+
+func (a *ModelAlias) BeforeCreate(_ *gorm.DB) error {
+	if a.ID == "" {
+		a.ID = generateUUIDv4()
+	}
+	return nil
+}
+
+// ModelAliasUpdate carries the fields a PATCH may change; nil fields are left
+// untouched.
+type ModelAliasUpdate struct {
+	Alias  *string `json:"alias,omitempty"`
+	Target *string `json:"target,omitempty"`
+	Notes  *string `json:"notes,omitempty"`
+}
+
+// UnmatchedModel aggregates the activities of one model that no pricing row
+// or alias matched.
+type UnmatchedModel struct {
+	Model                 string    `json:"model"`
+	ActivityCount         int64     `json:"activity_count"`
+	TokensInTotal         int64     `json:"tokens_in_total"`
+	TokensOutTotal        int64     `json:"tokens_out_total"`
+	CacheReadTokensTotal  int64     `json:"cache_read_tokens_total"`
+	CacheWriteTokensTotal int64     `json:"cache_write_tokens_total"`
+	FirstSeenAt           time.Time `json:"first_seen_at"`
+	LastSeenAt            time.Time `json:"last_seen_at"`
+}
+
+func (s *service) ListModelAliases(ctx context.Context) ([]ModelAlias, error) {
+	var aliases []ModelAlias
+	if err := s.db.WithContext(ctx).Order("alias asc").Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	return aliases, nil
+}
+
+func (s *service) CreateModelAlias(ctx context.Context, alias *ModelAlias) error {
+	if alias == nil {
+		return fmt.Errorf("%w: alias is required", ErrInvalidModelAlias)
+	}
+
+	alias.Alias = strings.TrimSpace(alias.Alias)
+	alias.Target = strings.TrimSpace(alias.Target)
+	alias.Notes = strings.TrimSpace(alias.Notes)
+	if err := validateModelAlias(*alias); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := ensureModelAliasFree(tx, alias.Alias, ""); err != nil {
+			return err
+		}
+		return tx.Create(alias).Error
+	})
+}
+
+func (s *service) UpdateModelAlias(ctx context.Context, id string, update ModelAliasUpdate) (ModelAlias, error) {
+	var alias ModelAlias
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&alias, "id = ?", strings.TrimSpace(id)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrModelAliasNotFound
+			}
+			return err
+		}
+
+		if update.Alias != nil {
+			alias.Alias = strings.TrimSpace(*update.Alias)
+		}
+		if update.Target != nil {
+			alias.Target = strings.TrimSpace(*update.Target)
+		}
+		if update.Notes != nil {
+			alias.Notes = strings.TrimSpace(*update.Notes)
+		}
+		if err := validateModelAlias(alias); err != nil {
+			return err
+		}
+		if update.Alias != nil {
+			if err := ensureModelAliasFree(tx, alias.Alias, alias.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&alias).Error
+	})
+	if err != nil {
+		return ModelAlias{}, err
+	}
+	return alias, nil
+}
+
+func (s *service) DeleteModelAlias(ctx context.Context, id string) error {
+	result := s.db.WithContext(ctx).Delete(&ModelAlias{}, "id = ?", strings.TrimSpace(id))
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrModelAliasNotFound
+	}
+	return nil
+}
+
+// ListUnmatchedModels reports the models whose activities were stored
+// without a pricing match, most frequent first.
+func (s *service) ListUnmatchedModels(ctx context.Context, filters ActivityFilters) ([]UnmatchedModel, error) {
+	tx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		Model                 string
+		ActivityCount         int64
+		TokensInTotal         int64
+		TokensOutTotal        int64
+		CacheReadTokensTotal  int64
+		CacheWriteTokensTotal int64
+		FirstSeenAt           sqlTime
+		LastSeenAt            sqlTime
+	}
+	if err := tx.
+		Select(`activity_feed.model AS model,
+			COUNT(*) AS activity_count,
+			COALESCE(SUM(activity_feed.tokens_in), 0) AS tokens_in_total,
+			COALESCE(SUM(activity_feed.tokens_out), 0) AS tokens_out_total,
+			COALESCE(SUM(activity_feed.cache_read_tokens), 0) AS cache_read_tokens_total,
+			COALESCE(SUM(activity_feed.cache_write_tokens), 0) AS cache_write_tokens_total,
+			MIN(activity_feed.created_at) AS first_seen_at,
+			MAX(activity_feed.created_at) AS last_seen_at`).
+		Where("activity_feed.cost_match = ?", CostMatchMissing).
+		Group("activity_feed.model").
+		Order("activity_count desc, model asc").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	models := make([]UnmatchedModel, 0, len(rows))
+	for _, row := range rows {
+		models = append(models, UnmatchedModel{
+			Model:                 row.Model,
+			ActivityCount:         row.ActivityCount,
+			TokensInTotal:         row.TokensInTotal,
+			TokensOutTotal:        row.TokensOutTotal,
+			CacheReadTokensTotal:  row.CacheReadTokensTotal,
+			CacheWriteTokensTotal: row.CacheWriteTokensTotal,
+			FirstSeenAt:           row.FirstSeenAt.Time,
+			LastSeenAt:            row.LastSeenAt.Time,
+		})
+	}
+	return models, nil
+}
+
+// modelAliasTargets returns the pricing candidates for model's alias, if it
+// has one, including the provider-qualified forms of the target.
+func modelAliasTargets(ctx context.Context, db *gorm.DB, model string) ([]string, error) {
+	// Find with a limit avoids GORM logging a "record not found" error for
+	// every model without an alias.
+	var aliases []ModelAlias
+	if err := db.WithContext(ctx).Where("alias = ?", model).Limit(1).Find(&aliases).Error; err != nil {
+		return nil, err
+	}
+	if len(aliases) == 0 {
+		return nil, nil
+	}
+	target := aliases[0].Target
+	return uniqueStrings(append([]string{target}, providerQualifiedModelCandidates(target)...)), nil
+}
+
+func validateModelAlias(alias ModelAlias) error {
+	switch {
+	case alias.Alias == "":
+		return fmt.Errorf("%w: alias is required", ErrInvalidModelAlias)
+	case alias.Target == "":
+		return fmt.Errorf("%w: target is required", ErrInvalidModelAlias)
+	case alias.Alias == alias.Target:
+		return fmt.Errorf("%w: alias and target must differ", ErrInvalidModelAlias)
+	}
+	return nil
+}
+
+func ensureModelAliasFree(tx *gorm.DB, alias, exceptID string) error {
+	query := tx.Model(&ModelAlias{}).Where("alias = ?", alias)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrModelAliasConflict
+	}
+	return nil
+}
@@ -0,0 +1,102 @@
+package database
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+)
+
+func TestCreateActivityRecordsCostMatch(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+
+	matched := &ActivityFeed{SessionKey: "match-1", Model: "gpt-5-mini", TokensIn: 1000, ProjectID: projectID}
+	aliased := &ActivityFeed{SessionKey: "match-2", Model: "gpt-5-codex-mini", TokensIn: 1000, ProjectID: projectID}
+	missing := &ActivityFeed{SessionKey: "match-3", Model: "in-house-7b", TokensIn: 1000, ProjectID: projectID}
+	for _, activity := range []*ActivityFeed{matched, aliased, missing} {
+		mustCreateActivity(t, svc, activity)
+	}
+
+	if matched.CostMatch != CostMatchMatched || aliased.CostMatch != CostMatchAlias || missing.CostMatch != CostMatchMissing {
+		t.Fatalf("expected matched/alias/missing, got %q/%q/%q", matched.CostMatch, aliased.CostMatch, missing.CostMatch)
+	}
+	if !nearlyEqual(aliased.CostEstimate, matched.CostEstimate) || aliased.PricingID == nil {
+		t.Fatalf("expected the seeded alias to price codex-mini as gpt-5-mini, got %#v", aliased)
+	}
+	if missing.CostEstimate != 0 || missing.PricingID != nil {
+		t.Fatalf("expected unmatched model to stay uncosted, got %#v", missing)
+	}
+}
+
+func TestModelAliasesPriceUnmatchedModelsAfterRecompute(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "clawtivity")
+
+	base := time.Date(2026, 3, 1, 10, 0, 0, 0, time.UTC)
+	for i, tokens := range []int{100, 300} {
+		mustCreateActivity(t, svc, &ActivityFeed{
+			SessionKey: fmt.Sprintf("alias-%d", i),
+			Model:      "in-house-7b",
+			TokensIn:   tokens,
+			TokensOut:  10,
+			ProjectID:  projectID,
+			CreatedAt:  base.Add(time.Duration(i) * time.Hour),
+		})
+	}
+
+	unmatched, err := svc.ListUnmatchedModels(t.Context(), ActivityFilters{})
+	if err != nil {
+		t.Fatalf("expected unmatched report to succeed: %v", err)
+	}
+	if len(unmatched) != 1 || unmatched[0].Model != "in-house-7b" || unmatched[0].ActivityCount != 2 || unmatched[0].TokensInTotal != 400 || unmatched[0].TokensOutTotal != 20 {
+		t.Fatalf("expected in-house-7b to be reported once with its volume, got %#v", unmatched)
+	}
+	if !unmatched[0].FirstSeenAt.Equal(base) || !unmatched[0].LastSeenAt.Equal(base.Add(time.Hour)) {
+		t.Fatalf("expected first/last seen from created_at, got %s/%s", unmatched[0].FirstSeenAt, unmatched[0].LastSeenAt)
+	}
+
+	alias := &ModelAlias{Alias: "in-house-7b", Target: "gpt-5-mini"}
+	if err := svc.CreateModelAlias(t.Context(), alias); err != nil {
+		t.Fatalf("expected alias create to succeed: %v", err)
+	}
+	if err := svc.CreateModelAlias(t.Context(), &ModelAlias{Alias: "in-house-7b", Target: "gpt-5"}); !errors.Is(err, ErrModelAliasConflict) {
+		t.Fatalf("expected ErrModelAliasConflict, got %v", err)
+	}
+	if err := svc.CreateModelAlias(t.Context(), &ModelAlias{Alias: "gpt-5", Target: "gpt-5"}); !errors.Is(err, ErrInvalidModelAlias) {
+		t.Fatalf("expected ErrInvalidModelAlias, got %v", err)
+	}
+
+	report, err := svc.RecomputeActivityCosts(t.Context(), CostRecomputeRequest{})
+	if err != nil {
+		t.Fatalf("expected recompute to succeed: %v", err)
+	}
+	if report.Changed != 2 || report.Unmatched != 0 {
+		t.Fatalf("expected both rows to be repriced through the alias, got %#v", report)
+	}
+
+	rows, err := svc.ListActivities(t.Context(), ActivityFilters{Models: []string{"in-house-7b"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, row := range rows {
+		if row.CostMatch != CostMatchAlias || row.CostEstimate <= 0 {
+			t.Fatalf("expected aliased cost after recompute, got %#v", row)
+		}
+	}
+	if unmatched, err := svc.ListUnmatchedModels(t.Context(), ActivityFilters{}); err != nil || len(unmatched) != 0 {
+		t.Fatalf("expected no unmatched models after aliasing, got %#v (%v)", unmatched, err)
+	}
+
+	target := "gpt-5"
+	updated, err := svc.UpdateModelAlias(t.Context(), alias.ID, ModelAliasUpdate{Target: &target})
+	if err != nil || updated.Target != "gpt-5" {
+		t.Fatalf("expected alias update to succeed, got %#v (%v)", updated, err)
+	}
+	if err := svc.DeleteModelAlias(t.Context(), alias.ID); err != nil {
+		t.Fatalf("expected alias delete to succeed: %v", err)
+	}
+	if err := svc.DeleteModelAlias(t.Context(), alias.ID); !errors.Is(err, ErrModelAliasNotFound) {
+		t.Fatalf("expected ErrModelAliasNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,199 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrModelPricingNotFound = errors.New("model pricing not found")
+var ErrInvalidModelPricing = errors.New("invalid model pricing")
+
+// ErrModelPricingConflict is returned when a row already exists for the same
+// provider, model and effective_from (the idx_model_pricing_lookup key).
+var ErrModelPricingConflict = errors.New("model pricing already exists for provider, model and effective_from")
+
+// ErrModelPricingInUse is returned when deleting a row that activities still
+// reference through pricing_id.
+var ErrModelPricingInUse = errors.New("model pricing is referenced by activities")
+
+const manualPricingSource = "manual"
+
+// ModelPricingUpdate carries the fields a PATCH may change; nil fields are
+// left untouched. Verified stamps last_verified_at and clears is_stale.
+type ModelPricingUpdate struct {
+	EffectiveFrom       *time.Time `json:"effective_from,omitempty"`
+	InputCostPer1M      *float64   `json:"input_cost_per_1m,omitempty"`
+	OutputCostPer1M     *float64   `json:"output_cost_per_1m,omitempty"`
+	ReasoningCostPer1M  *float64   `json:"reasoning_cost_per_1m,omitempty"`
+	CacheReadCostPer1M  *float64   `json:"cache_read_cost_per_1m,omitempty"`
+	CacheWriteCostPer1M *float64   `json:"cache_write_cost_per_1m,omitempty"`
+	Currency            *string    `json:"currency,omitempty"`
+	Source              *string    `json:"source,omitempty"`
+	IsEstimated         *bool      `json:"is_estimated,omitempty"`
+	VerificationNotes   *string    `json:"verification_notes,omitempty"`
+	Verified            bool       `json:"verified,omitempty"`
+}
+
+func (s *service) CreateModelPricing(ctx context.Context, pricing *ModelPricing) error {
+	if pricing == nil {
+		return fmt.Errorf("%w: pricing is required", ErrInvalidModelPricing)
+	}
+
+	pricing.Provider = strings.ToLower(strings.TrimSpace(pricing.Provider))
+	pricing.Model = strings.TrimSpace(pricing.Model)
+	pricing.Currency = strings.ToUpper(strings.TrimSpace(pricing.Currency))
+	pricing.Source = strings.TrimSpace(pricing.Source)
+	if pricing.Currency == "" {
+		pricing.Currency = "USD"
+	}
+	if pricing.Source == "" {
+		pricing.Source = manualPricingSource
+	}
+	if pricing.EffectiveFrom.IsZero() {
+		pricing.EffectiveFrom = time.Now().UTC()
+	}
+	pricing.EffectiveFrom = pricing.EffectiveFrom.UTC()
+	pricing.IsStale = false
+	if err := validateModelPricing(*pricing); err != nil {
+		return err
+	}
+
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := ensureModelPricingKeyFree(tx, *pricing, ""); err != nil {
+			return err
+		}
+		return tx.Create(pricing).Error
+	})
+}
+
+func (s *service) UpdateModelPricing(ctx context.Context, id string, update ModelPricingUpdate) (ModelPricing, error) {
+	var pricing ModelPricing
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&pricing, "id = ?", strings.TrimSpace(id)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrModelPricingNotFound
+			}
+			return err
+		}
+
+		if update.EffectiveFrom != nil {
+			pricing.EffectiveFrom = update.EffectiveFrom.UTC()
+		}
+		if update.InputCostPer1M != nil {
+			pricing.InputCostPer1M = *update.InputCostPer1M
+		}
+		if update.OutputCostPer1M != nil {
+			pricing.OutputCostPer1M = *update.OutputCostPer1M
+		}
+		if update.ReasoningCostPer1M != nil {
+			reasoning := *update.ReasoningCostPer1M
+			pricing.ReasoningCostPer1M = &reasoning
+		}
+		if update.CacheReadCostPer1M != nil {
+			cacheRead := *update.CacheReadCostPer1M
+			pricing.CacheReadCostPer1M = &cacheRead
+		}
+		if update.CacheWriteCostPer1M != nil {
+			cacheWrite := *update.CacheWriteCostPer1M
+			pricing.CacheWriteCostPer1M = &cacheWrite
+		}
+		if update.Currency != nil {
+			pricing.Currency = strings.ToUpper(strings.TrimSpace(*update.Currency))
+		}
+		if update.Source != nil {
+			pricing.Source = strings.TrimSpace(*update.Source)
+		}
+		if update.IsEstimated != nil {
+			pricing.IsEstimated = *update.IsEstimated
+		}
+		if update.VerificationNotes != nil {
+			pricing.VerificationNotes = strings.TrimSpace(*update.VerificationNotes)
+		}
+		if update.Verified {
+			verifiedAt := time.Now().UTC()
+			pricing.LastVerifiedAt = &verifiedAt
+			pricing.IsStale = false
+		}
+		if err := validateModelPricing(pricing); err != nil {
+			return err
+		}
+		if update.EffectiveFrom != nil {
+			if err := ensureModelPricingKeyFree(tx, pricing, pricing.ID); err != nil {
+				return err
+			}
+		}
+		return tx.Save(&pricing).Error
+	})
+	if err != nil {
+		return ModelPricing{}, err
+	}
+	return pricing, nil
+}
+
+// DeleteModelPricing deletes a pricing row unless activities were costed with
+// it; recompute those activities against other rows first.
+func (s *service) DeleteModelPricing(ctx context.Context, id string) error {
+	id = strings.TrimSpace(id)
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var references int64
+		if err := tx.Model(&ActivityFeed{}).Where("pricing_id = ?", id).Count(&references).Error; err != nil {
+			return err
+		}
+		if references > 0 {
+			return fmt.Errorf("%w: %d activities", ErrModelPricingInUse, references)
+		}
+
+		result := tx.Delete(&ModelPricing{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrModelPricingNotFound
+		}
+		return nil
+	})
+}
+
+func validateModelPricing(pricing ModelPricing) error {
+	switch {
+	case pricing.Provider == "":
+		return fmt.Errorf("%w: provider is required", ErrInvalidModelPricing)
+	case pricing.Model == "":
+		return fmt.Errorf("%w: model is required", ErrInvalidModelPricing)
+	case pricing.InputCostPer1M < 0 || pricing.OutputCostPer1M < 0:
+		return fmt.Errorf("%w: costs must not be negative", ErrInvalidModelPricing)
+	case isNegativeRate(pricing.ReasoningCostPer1M), isNegativeRate(pricing.CacheReadCostPer1M), isNegativeRate(pricing.CacheWriteCostPer1M):
+		return fmt.Errorf("%w: costs must not be negative", ErrInvalidModelPricing)
+	case pricing.Currency == "":
+		return fmt.Errorf("%w: currency is required", ErrInvalidModelPricing)
+	}
+	return nil
+}
+
+func isNegativeRate(rate *float64) bool {
+	return rate != nil && *rate < 0
+}
+
+// ensureModelPricingKeyFree checks idx_model_pricing_lookup up front so a
+// clash is reported as ErrModelPricingConflict rather than a driver error.
+func ensureModelPricingKeyFree(tx *gorm.DB, pricing ModelPricing, exceptID string) error {
+	query := tx.Model(&ModelPricing{}).
+		Where("provider = ? AND model = ? AND effective_from = ?", pricing.Provider, pricing.Model, pricing.EffectiveFrom)
+	if exceptID != "" {
+		query = query.Where("id <> ?", exceptID)
+	}
+
+	var count int64
+	if err := query.Count(&count).Error; err != nil {
+		return err
+	}
+	if count > 0 {
+		return ErrModelPricingConflict
+	}
+	return nil
+}
@@ -0,0 +1,430 @@
+package database
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"gorm.io/gorm"
+)
+
+const defaultPricingSources = "openrouter"
+
+// PricingSource supplies model pricing rows to the refresh worker. Sources
+// run in configured order and upsert on provider, model and effective_from,
+// so a later source only rewrites an earlier one's row with that exact key.
+// Order does not decide lookup: the row with the latest effective_from in
+// effect wins, OpenRouter rows only breaking ties on the same date.
+type PricingSource interface {
+	// Name identifies the source in logs, metrics and pricing_source_status.
+	Name() string
+	Fetch(ctx context.Context) ([]ModelPricing, error)
+}
+
+// pricingSourceScheduler replaces the default interval check for sources
+// that know better when their data changed.
+type pricingSourceScheduler interface {
+	refreshDue(ctx context.Context, db *gorm.DB, now time.Time, interval time.Duration, status PricingSourceStatus) (bool, error)
+}
+
+// pricingSourceStaleMarker flags a source's rows stale once they have not
+// been re-verified within staleAfter.
+type pricingSourceStaleMarker interface {
+	markStale(ctx context.Context, db *gorm.DB, now time.Time, staleAfter time.Duration) error
+}
+
+// PricingSourceStatus records the outcome of the latest refresh of one
+// configured pricing source.
+type PricingSourceStatus struct {
+	Name           string     `gorm:"primaryKey" json:"name"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	LastSuccessAt  *time.Time `json:"last_success_at,omitempty"`
+	LastError      string     `json:"last_error"`
+	ModelsImported int        `json:"models_imported"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (PricingSourceStatus) TableName() string {
+	return "pricing_source_status"
+}
+
+type openRouterPricingSource struct{}
+
+func (openRouterPricingSource) Name() string { return "openrouter" }
+
+func (openRouterPricingSource) Fetch(ctx context.Context) ([]ModelPricing, error) {
+	return openRouterModelsFetcher(ctx)
+}
+
+// refreshDue keys off the catalog's last_verified_at so databases that were
+// refreshed before source status was tracked are not fetched again early.
+func (openRouterPricingSource) refreshDue(ctx context.Context, db *gorm.DB, now time.Time, interval time.Duration, _ PricingSourceStatus) (bool, error) {
+	return openRouterPricingRefreshDue(ctx, db, now, interval)
+}
+
+func (openRouterPricingSource) markStale(ctx context.Context, db *gorm.DB, now time.Time, staleAfter time.Duration) error {
+	return markOpenRouterPricingStale(ctx, db, now, staleAfter)
+}
+
+// liteLLMPricingSource reads a LiteLLM model_prices_and_context_window.json
+// style file, keyed by model with per-token USD rates.
+type liteLLMPricingSource struct {
+	path string
+}
+
+func (s liteLLMPricingSource) Name() string { return "litellm:" + s.path }
+
+func (s liteLLMPricingSource) refreshDue(_ context.Context, _ *gorm.DB, now time.Time, interval time.Duration, status PricingSourceStatus) (bool, error) {
+	return pricingFileRefreshDue(s.path, now, interval, status)
+}
+
+func (s liteLLMPricingSource) Fetch(_ context.Context) ([]ModelPricing, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries map[string]struct {
+		Provider               string   `json:"litellm_provider"`
+		InputCostPerToken      *float64 `json:"input_cost_per_token"`
+		OutputCostPerToken     *float64 `json:"output_cost_per_token"`
+		ReasoningCostPerToken  *float64 `json:"output_cost_per_reasoning_token"`
+		CacheReadCostPerToken  *float64 `json:"cache_read_input_token_cost"`
+		CacheWriteCostPerToken *float64 `json:"cache_creation_input_token_cost"`
+	}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("parse litellm pricing file %s: %w", s.path, err)
+	}
+
+	now := time.Now().UTC()
+	rows := make([]ModelPricing, 0, len(entries))
+	for model, entry := range entries {
+		model = strings.TrimSpace(model)
+		// sample_spec documents the schema and carries no real prices.
+		if model == "" || model == "sample_spec" {
+			continue
+		}
+		if entry.InputCostPerToken == nil && entry.OutputCostPerToken == nil {
+			continue
+		}
+
+		provider := strings.ToLower(strings.TrimSpace(entry.Provider))
+		if provider == "" {
+			provider = "litellm"
+		}
+		verifiedAt := now
+		rows = append(rows, ModelPricing{
+			Provider:            provider,
+			Model:               model,
+			EffectiveFrom:       now,
+			InputCostPer1M:      perTokenToPerMillion(entry.InputCostPerToken),
+			OutputCostPer1M:     perTokenToPerMillion(entry.OutputCostPerToken),
+			ReasoningCostPer1M:  optionalPerTokenToPerMillion(entry.ReasoningCostPerToken),
+			CacheReadCostPer1M:  optionalPerTokenToPerMillion(entry.CacheReadCostPerToken),
+			CacheWriteCostPer1M: optionalPerTokenToPerMillion(entry.CacheWriteCostPerToken),
+			Currency:            "USD",
+			Source:              s.Name(),
+			LastVerifiedAt:      &verifiedAt,
+			VerificationNotes:   "Imported from LiteLLM model prices file.",
+		})
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		return rows[i].Provider+"/"+rows[i].Model < rows[j].Provider+"/"+rows[j].Model
+	})
+	return rows, nil
+}
+
+// yamlPricingSource reads a user-maintained catalog with rates already per
+// million tokens:
+//
+//	models:
+//	  - provider: local
+//	    model: qwen3-coder
+//	    effective_from: 2026-03-01
+//	    input_cost_per_1m: 0.10
+//	    output_cost_per_1m: 0.40
+type yamlPricingSource struct {
+	path string
+}
+
+func (s yamlPricingSource) Name() string { return "yaml:" + s.path }
+
+func (s yamlPricingSource) refreshDue(_ context.Context, _ *gorm.DB, now time.Time, interval time.Duration, status PricingSourceStatus) (bool, error) {
+	return pricingFileRefreshDue(s.path, now, interval, status)
+}
+
+func (s yamlPricingSource) Fetch(_ context.Context) ([]ModelPricing, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return nil, err
+	}
+
+	var document struct {
+		Models []struct {
+			Provider            string   `yaml:"provider"`
+			Model               string   `yaml:"model"`
+			EffectiveFrom       string   `yaml:"effective_from"`
+			InputCostPer1M      float64  `yaml:"input_cost_per_1m"`
+			OutputCostPer1M     float64  `yaml:"output_cost_per_1m"`
+			ReasoningCostPer1M  *float64 `yaml:"reasoning_cost_per_1m"`
+			CacheReadCostPer1M  *float64 `yaml:"cache_read_cost_per_1m"`
+			CacheWriteCostPer1M *float64 `yaml:"cache_write_cost_per_1m"`
+			Currency            string   `yaml:"currency"`
+			IsEstimated         bool     `yaml:"is_estimated"`
+			Notes               string   `yaml:"notes"`
+		} `yaml:"models"`
+	}
+	if err := yaml.Unmarshal(data, &document); err != nil {
+		return nil, fmt.Errorf("parse yaml pricing file %s: %w", s.path, err)
+	}
+
+	now := time.Now().UTC()
+	rows := make([]ModelPricing, 0, len(document.Models))
+	for i, entry := range document.Models {
+		row := ModelPricing{
+			Provider:            strings.ToLower(strings.TrimSpace(entry.Provider)),
+			Model:               strings.TrimSpace(entry.Model),
+			EffectiveFrom:       now,
+			InputCostPer1M:      entry.InputCostPer1M,
+			OutputCostPer1M:     entry.OutputCostPer1M,
+			ReasoningCostPer1M:  entry.ReasoningCostPer1M,
+			CacheReadCostPer1M:  entry.CacheReadCostPer1M,
+			CacheWriteCostPer1M: entry.CacheWriteCostPer1M,
+			Currency:            strings.ToUpper(strings.TrimSpace(entry.Currency)),
+			Source:              s.Name(),
+			IsEstimated:         entry.IsEstimated,
+			LastVerifiedAt:      &now,
+			VerificationNotes:   entry.Notes,
+		}
+		if row.Currency == "" {
+			row.Currency = "USD"
+		}
+		if raw := strings.TrimSpace(entry.EffectiveFrom); raw != "" {
+			effectiveFrom, err := parsePricingFileTime(raw)
+			if err != nil {
+				return nil, fmt.Errorf("parse yaml pricing file %s: models[%d].effective_from: %w", s.path, i, err)
+			}
+			row.EffectiveFrom = effectiveFrom
+		}
+		if err := validateModelPricing(row); err != nil {
+			return nil, fmt.Errorf("parse yaml pricing file %s: models[%d]: %w", s.path, i, err)
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func parsePricingFileTime(value string) (time.Time, error) {
+	if parsed, err := time.Parse(time.RFC3339, value); err == nil {
+		return parsed.UTC(), nil
+	}
+	return time.Parse(time.DateOnly, value)
+}
+
+// pricingFileRefreshDue re-imports a file as soon as it changes on disk, and
+// otherwise once per interval like remote sources.
+func pricingFileRefreshDue(path string, now time.Time, interval time.Duration, status PricingSourceStatus) (bool, error) {
+	if status.LastSuccessAt == nil {
+		return true, nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false, err
+	}
+	if info.ModTime().After(*status.LastSuccessAt) {
+		return true, nil
+	}
+	return interval > 0 && now.Sub(status.LastSuccessAt.UTC()) >= interval, nil
+}
+
+func perTokenToPerMillion(value *float64) float64 {
+	if value == nil {
+		return 0
+	}
+	return *value * 1_000_000
+}
+
+func optionalPerTokenToPerMillion(value *float64) *float64 {
+	if value == nil {
+		return nil
+	}
+	converted := *value * 1_000_000
+	return &converted
+}
+
+// parsePricingSources reads a comma-separated list such as
+// "openrouter,litellm=/etc/model_prices.json,yaml=./pricing.yaml". File
+// sources need a path; unknown kinds are an error.
+func parsePricingSources(value string) ([]PricingSource, error) {
+	var sources []PricingSource
+	seen := map[string]struct{}{}
+	for _, entry := range strings.Split(value, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+
+		kind, path, _ := strings.Cut(entry, "=")
+		kind = strings.ToLower(strings.TrimSpace(kind))
+		path = strings.TrimSpace(path)
+
+		var source PricingSource
+		switch kind {
+		case "openrouter":
+			source = openRouterPricingSource{}
+		case "litellm", "yaml":
+			if path == "" {
+				return nil, fmt.Errorf("pricing source %q needs a file path (%s=/path)", kind, kind)
+			}
+			if absolute, err := filepath.Abs(path); err == nil {
+				path = absolute
+			}
+			if kind == "litellm" {
+				source = liteLLMPricingSource{path: path}
+			} else {
+				source = yamlPricingSource{path: path}
+			}
+		default:
+			return nil, fmt.Errorf("unknown pricing source %q", kind)
+		}
+
+		if _, duplicate := seen[source.Name()]; duplicate {
+			continue
+		}
+		seen[source.Name()] = struct{}{}
+		sources = append(sources, source)
+	}
+	return sources, nil
+}
+
+func resolvePricingSources() []PricingSource {
+	value, ok := os.LookupEnv("CLAWTIVITY_PRICING_SOURCES")
+	if !ok {
+		value = defaultPricingSources
+	}
+	sources, err := parsePricingSources(value)
+	if err != nil {
+		log.Printf("invalid CLAWTIVITY_PRICING_SOURCES, using %s: %v", defaultPricingSources, err)
+		sources, _ = parsePricingSources(defaultPricingSources)
+	}
+	return sources
+}
+
+func hasOpenRouterPricingSource(sources []PricingSource) bool {
+	for _, source := range sources {
+		if _, ok := source.(openRouterPricingSource); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// refreshPricingSourcesIfDue refreshes each configured source in order. A
+// failing source is logged and does not stop the ones after it.
+func refreshPricingSourcesIfDue(ctx context.Context, db *gorm.DB, now time.Time, config pricingRefreshConfig) {
+	for _, source := range config.Sources {
+		if err := refreshPricingSourceIfDue(ctx, db, now, config, source); err != nil {
+			log.Printf("%s pricing refresh failed: %v", source.Name(), err)
+		}
+	}
+}
+
+func refreshPricingSourceIfDue(ctx context.Context, db *gorm.DB, now time.Time, config pricingRefreshConfig, source PricingSource) error {
+	if !config.Enabled {
+		return nil
+	}
+
+	marker, marksStale := source.(pricingSourceStaleMarker)
+	if marksStale {
+		if err := marker.markStale(ctx, db, now, config.StaleAfter); err != nil {
+			return err
+		}
+	}
+
+	status, err := loadPricingSourceStatus(ctx, db, source.Name())
+	if err != nil {
+		return err
+	}
+
+	var due bool
+	if scheduler, ok := source.(pricingSourceScheduler); ok {
+		due, err = scheduler.refreshDue(ctx, db, now, config.Interval, status)
+	} else {
+		due = status.LastSuccessAt == nil || (config.Interval > 0 && now.Sub(status.LastSuccessAt.UTC()) >= config.Interval)
+	}
+	if err != nil {
+		return err
+	}
+	if !due {
+		pricingRefreshTotal.Inc(source.Name(), "skipped")
+		return nil
+	}
+
+	attemptedAt := now.UTC()
+	status.LastAttemptAt = &attemptedAt
+
+	rows, err := source.Fetch(ctx)
+	if err == nil {
+		err = upsertModelPricing(ctx, db, rows)
+	}
+	if err != nil {
+		pricingRefreshTotal.Inc(source.Name(), "failure")
+		status.LastError = err.Error()
+		if notifyErr := enqueueWebhookEvent(ctx, db, WebhookEventPricingRefreshFailed, map[string]any{
+			"source": source.Name(),
+			"error":  err.Error(),
+		}); notifyErr != nil {
+			log.Printf("pricing refresh webhook for %s not queued: %v", source.Name(), notifyErr)
+		}
+		if saveErr := savePricingSourceStatus(ctx, db, status); saveErr != nil {
+			return saveErr
+		}
+		if marksStale {
+			if staleErr := marker.markStale(ctx, db, now, config.StaleAfter); staleErr != nil {
+				return staleErr
+			}
+		}
+		return err
+	}
+
+	pricingRefreshTotal.Inc(source.Name(), "success")
+	status.LastSuccessAt = &attemptedAt
+	status.LastError = ""
+	status.ModelsImported = len(rows)
+	return savePricingSourceStatus(ctx, db, status)
+}
+
+func loadPricingSourceStatus(ctx context.Context, db *gorm.DB, name string) (PricingSourceStatus, error) {
+	var status PricingSourceStatus
+	err := db.WithContext(ctx).Where("name = ?", name).First(&status).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return PricingSourceStatus{Name: name}, nil
+	}
+	return status, err
+}
+
+func savePricingSourceStatus(ctx context.Context, db *gorm.DB, status PricingSourceStatus) error {
+	return db.WithContext(ctx).Save(&status).Error
+}
+
+// ListPricingSources reports the configured sources in refresh order with
+// their latest refresh outcome. Sources that never ran have no timestamps.
+func (s *service) ListPricingSources(ctx context.Context) ([]PricingSourceStatus, error) {
+	statuses := make([]PricingSourceStatus, 0, len(s.pricingRefresh.Sources))
+	for _, source := range s.pricingRefresh.Sources {
+		status, err := loadPricingSourceStatus(ctx, s.db, source.Name())
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
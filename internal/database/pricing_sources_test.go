@@ -0,0 +1,245 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParsePricingSourcesKeepsConfiguredOrder(t *testing.T) {
+	dir := t.TempDir()
+	sources, err := parsePricingSources(" yaml=" + filepath.Join(dir, "pricing.yaml") + ", openrouter ,litellm=" + filepath.Join(dir, "prices.json") + ",openrouter")
+	if err != nil {
+		t.Fatalf("expected sources to parse: %v", err)
+	}
+
+	want := []string{
+		"yaml:" + filepath.Join(dir, "pricing.yaml"),
+		"openrouter",
+		"litellm:" + filepath.Join(dir, "prices.json"),
+	}
+	if len(sources) != len(want) {
+		t.Fatalf("expected %d sources, got %d", len(want), len(sources))
+	}
+	for i, source := range sources {
+		if source.Name() != want[i] {
+			t.Fatalf("source %d: expected %s, got %s", i, want[i], source.Name())
+		}
+	}
+
+	for _, invalid := range []string{"yaml", "litellm=", "pricelist=/tmp/x"} {
+		if _, err := parsePricingSources(invalid); err == nil {
+			t.Fatalf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestLiteLLMPricingSourceConvertsPerTokenRates(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model_prices.json")
+	writePricingFile(t, path, `{
+		"sample_spec": {"input_cost_per_token": 0, "litellm_provider": "one of https://docs.litellm.ai/docs/providers"},
+		"gpt-5-mini": {"input_cost_per_token": 2.5e-7, "output_cost_per_token": 2e-6, "cache_read_input_token_cost": 2.5e-8, "litellm_provider": "openai"},
+		"text-embedding-3-small": {"litellm_provider": "openai", "mode": "embedding"}
+	}`)
+
+	rows, err := liteLLMPricingSource{path: path}.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("expected litellm file to parse: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected only the priced model, got %#v", rows)
+	}
+	row := rows[0]
+	if row.Provider != "openai" || row.Model != "gpt-5-mini" || row.Source != "litellm:"+path {
+		t.Fatalf("unexpected row identity: %#v", row)
+	}
+	if !nearlyEqual(row.InputCostPer1M, 0.25) || !nearlyEqual(row.OutputCostPer1M, 2) {
+		t.Fatalf("expected per-1M rates 0.25/2, got %f/%f", row.InputCostPer1M, row.OutputCostPer1M)
+	}
+	if row.CacheReadCostPer1M == nil || !nearlyEqual(*row.CacheReadCostPer1M, 0.025) || row.CacheWriteCostPer1M != nil {
+		t.Fatalf("expected cache read rate only, got %v/%v", row.CacheReadCostPer1M, row.CacheWriteCostPer1M)
+	}
+}
+
+func TestYAMLPricingSourceReadsEffectiveDatedRows(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	writePricingFile(t, path, `models:
+  - provider: Local
+    model: qwen3-coder
+    effective_from: 2026-03-01
+    input_cost_per_1m: 0.1
+    output_cost_per_1m: 0.4
+    cache_read_cost_per_1m: 0.01
+    notes: self-hosted estimate
+`)
+
+	rows, err := yamlPricingSource{path: path}.Fetch(context.Background())
+	if err != nil {
+		t.Fatalf("expected yaml file to parse: %v", err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected one row, got %#v", rows)
+	}
+	row := rows[0]
+	if row.Provider != "local" || row.Currency != "USD" || row.VerificationNotes != "self-hosted estimate" {
+		t.Fatalf("unexpected row: %#v", row)
+	}
+	if !row.EffectiveFrom.Equal(time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected effective_from 2026-03-01, got %s", row.EffectiveFrom)
+	}
+	if row.CacheReadCostPer1M == nil || *row.CacheReadCostPer1M != 0.01 {
+		t.Fatalf("expected cache read rate, got %v", row.CacheReadCostPer1M)
+	}
+
+	writePricingFile(t, path, "models:\n  - model: missing-provider\n")
+	if _, err := (yamlPricingSource{path: path}).Fetch(context.Background()); !errors.Is(err, ErrInvalidModelPricing) {
+		t.Fatalf("expected ErrInvalidModelPricing, got %v", err)
+	}
+}
+
+func TestUpsertModelPricingKeepsOutOfOrderAndBackDatedRows(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	source := yamlPricingSource{path: path}
+	importFile := func(content string) {
+		t.Helper()
+		writePricingFile(t, path, content)
+		rows, err := source.Fetch(context.Background())
+		if err != nil {
+			t.Fatalf("expected yaml file to parse: %v", err)
+		}
+		if err := upsertModelPricing(context.Background(), svc.db, rows); err != nil {
+			t.Fatalf("expected rows to upsert: %v", err)
+		}
+	}
+
+	// The newer row is listed first.
+	importFile(`models:
+  - provider: local
+    model: qwen3-coder
+    effective_from: 2026-04-01
+    input_cost_per_1m: 2
+    output_cost_per_1m: 8
+  - provider: local
+    model: qwen3-coder
+    effective_from: 2026-03-01
+    input_cost_per_1m: 1
+    output_cost_per_1m: 4
+`)
+	// A later import back-dates a row before both and reprices April.
+	importFile(`models:
+  - provider: local
+    model: qwen3-coder
+    effective_from: 2026-04-01
+    input_cost_per_1m: 2.5
+    output_cost_per_1m: 10
+  - provider: local
+    model: qwen3-coder
+    effective_from: 2026-02-01
+    input_cost_per_1m: 0.5
+    output_cost_per_1m: 2
+`)
+
+	var rows []ModelPricing
+	if err := svc.db.Where("provider = ? AND model = ?", "local", "qwen3-coder").Order("effective_from asc").Find(&rows).Error; err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		month         time.Month
+		input, output float64
+	}{
+		{time.February, 0.5, 2},
+		{time.March, 1, 4},
+		{time.April, 2.5, 10},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("expected %d dated rows, got %#v", len(want), rows)
+	}
+	for i, w := range want {
+		row := rows[i]
+		if !row.EffectiveFrom.Equal(time.Date(2026, w.month, 1, 0, 0, 0, 0, time.UTC)) || row.InputCostPer1M != w.input || row.OutputCostPer1M != w.output {
+			t.Fatalf("row %d: expected %s %v/%v, got %s %v/%v", i, w.month, w.input, w.output, row.EffectiveFrom, row.InputCostPer1M, row.OutputCostPer1M)
+		}
+	}
+}
+
+func TestRefreshPricingSourcesRecordsStatusPerSource(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	dir := t.TempDir()
+	yamlPath := filepath.Join(dir, "pricing.yaml")
+	writePricingFile(t, yamlPath, `models:
+  - provider: local
+    model: qwen3-coder
+    effective_from: 2026-03-01
+    input_cost_per_1m: 0.1
+    output_cost_per_1m: 0.4
+`)
+
+	config := pricingRefreshConfig{
+		Enabled:    true,
+		Interval:   7 * 24 * time.Hour,
+		StaleAfter: 7 * 24 * time.Hour,
+		Sources: []PricingSource{
+			liteLLMPricingSource{path: filepath.Join(dir, "missing.json")},
+			yamlPricingSource{path: yamlPath},
+		},
+	}
+	now := time.Now().UTC()
+	refreshPricingSourcesIfDue(context.Background(), svc.db, now, config)
+
+	failed, err := loadPricingSourceStatus(context.Background(), svc.db, config.Sources[0].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if failed.LastAttemptAt == nil || failed.LastSuccessAt != nil || failed.LastError == "" {
+		t.Fatalf("expected failed attempt to be recorded, got %#v", failed)
+	}
+
+	imported, err := loadPricingSourceStatus(context.Background(), svc.db, config.Sources[1].Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if imported.LastSuccessAt == nil || !imported.LastSuccessAt.Equal(now) || imported.ModelsImported != 1 || imported.LastError != "" {
+		t.Fatalf("expected successful import to be recorded, got %#v", imported)
+	}
+
+	var row ModelPricing
+	if err := svc.db.Where("provider = ? AND model = ?", "local", "qwen3-coder").First(&row).Error; err != nil {
+		t.Fatalf("expected yaml row to be imported despite the earlier failing source: %v", err)
+	}
+
+	// An edited file is re-imported before the interval elapses.
+	writePricingFile(t, yamlPath, `models:
+  - provider: local
+    model: qwen3-coder
+    effective_from: 2026-04-01
+    input_cost_per_1m: 0.2
+    output_cost_per_1m: 0.8
+`)
+	future := now.Add(time.Minute)
+	if err := os.Chtimes(yamlPath, future, future); err != nil {
+		t.Fatal(err)
+	}
+	if err := refreshPricingSourceIfDue(context.Background(), svc.db, future.Add(time.Second), config, config.Sources[1]); err != nil {
+		t.Fatalf("expected changed file to refresh: %v", err)
+	}
+
+	var count int64
+	if err := svc.db.Model(&ModelPricing{}).Where("provider = ? AND model = ?", "local", "qwen3-coder").Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected the repriced row to be added alongside the original, got %d rows", count)
+	}
+}
+
+func writePricingFile(t *testing.T, path, content string) {
+	t.Helper()
+
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,188 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestModelPricingCreateUpdateAndDelete(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	effectiveFrom := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+
+	pricing := &ModelPricing{Provider: " Local ", Model: "qwen3-coder", EffectiveFrom: effectiveFrom, InputCostPer1M: 0.1, OutputCostPer1M: 0.4}
+	if err := svc.CreateModelPricing(t.Context(), pricing); err != nil {
+		t.Fatalf("expected create to succeed: %v", err)
+	}
+	if pricing.ID == "" || pricing.Provider != "local" || pricing.Currency != "USD" || pricing.Source != manualPricingSource {
+		t.Fatalf("expected normalized defaults, got %#v", pricing)
+	}
+
+	duplicate := &ModelPricing{Provider: "local", Model: "qwen3-coder", EffectiveFrom: effectiveFrom}
+	if err := svc.CreateModelPricing(t.Context(), duplicate); !errors.Is(err, ErrModelPricingConflict) {
+		t.Fatalf("expected ErrModelPricingConflict, got %v", err)
+	}
+
+	later := &ModelPricing{Provider: "local", Model: "qwen3-coder", EffectiveFrom: effectiveFrom.AddDate(0, 1, 0)}
+	if err := svc.CreateModelPricing(t.Context(), later); err != nil {
+		t.Fatalf("expected later effective row to be allowed: %v", err)
+	}
+	if _, err := svc.UpdateModelPricing(t.Context(), later.ID, ModelPricingUpdate{EffectiveFrom: &effectiveFrom}); !errors.Is(err, ErrModelPricingConflict) {
+		t.Fatalf("expected moving onto an existing effective_from to conflict, got %v", err)
+	}
+
+	if err := svc.db.Model(pricing).Update("is_stale", true).Error; err != nil {
+		t.Fatal(err)
+	}
+	output := 0.6
+	updated, err := svc.UpdateModelPricing(t.Context(), pricing.ID, ModelPricingUpdate{OutputCostPer1M: &output, Verified: true})
+	if err != nil {
+		t.Fatalf("expected update to succeed: %v", err)
+	}
+	if updated.OutputCostPer1M != 0.6 || updated.InputCostPer1M != 0.1 || updated.IsStale || updated.LastVerifiedAt == nil {
+		t.Fatalf("expected corrected, verified row, got %#v", updated)
+	}
+
+	negative := -1.0
+	if _, err := svc.UpdateModelPricing(t.Context(), pricing.ID, ModelPricingUpdate{InputCostPer1M: &negative}); !errors.Is(err, ErrInvalidModelPricing) {
+		t.Fatalf("expected ErrInvalidModelPricing for negative cost, got %v", err)
+	}
+
+	if err := svc.DeleteModelPricing(t.Context(), pricing.ID); err != nil {
+		t.Fatalf("expected delete to succeed: %v", err)
+	}
+	if err := svc.DeleteModelPricing(t.Context(), pricing.ID); !errors.Is(err, ErrModelPricingNotFound) {
+		t.Fatalf("expected ErrModelPricingNotFound, got %v", err)
+	}
+	if _, err := svc.UpdateModelPricing(t.Context(), pricing.ID, ModelPricingUpdate{}); !errors.Is(err, ErrModelPricingNotFound) {
+		t.Fatalf("expected ErrModelPricingNotFound on update, got %v", err)
+	}
+}
+
+func TestCreateActivityCostsWithPricingInEffectAtCreatedAt(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	april := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	original := &ModelPricing{Provider: "local", Model: "point-in-time", EffectiveFrom: march, InputCostPer1M: 1, OutputCostPer1M: 2}
+	repriced := &ModelPricing{Provider: "local", Model: "point-in-time", EffectiveFrom: april, InputCostPer1M: 10, OutputCostPer1M: 20}
+	for _, pricing := range []*ModelPricing{original, repriced} {
+		if err := svc.CreateModelPricing(t.Context(), pricing); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		createdAt time.Time
+		wantCost  float64
+		wantID    string
+	}{
+		{march.AddDate(0, 0, 10), 3, original.ID},
+		{april, 30, repriced.ID},
+		{april.AddDate(0, 1, 0), 30, repriced.ID},
+		// Older than every known price: fall back to the earliest row.
+		{march.AddDate(0, -1, 0), 3, original.ID},
+	}
+	for _, tc := range cases {
+		activity := &ActivityFeed{SessionKey: "pit", ProjectID: projectID, Model: "point-in-time", TokensIn: 1_000_000, TokensOut: 1_000_000, CreatedAt: tc.createdAt}
+		mustCreateActivity(t, svc, activity)
+		if !nearlyEqual(activity.CostEstimate, tc.wantCost) {
+			t.Fatalf("created %s: expected cost %.2f, got %.2f", tc.createdAt, tc.wantCost, activity.CostEstimate)
+		}
+		if activity.PricingID == nil || *activity.PricingID != tc.wantID {
+			t.Fatalf("created %s: expected pricing_id %s, got %v", tc.createdAt, tc.wantID, activity.PricingID)
+		}
+	}
+
+	unpriced := &ActivityFeed{SessionKey: "pit", ProjectID: projectID, Model: "no-such-model"}
+	mustCreateActivity(t, svc, unpriced)
+	if unpriced.PricingID != nil || unpriced.CostEstimate != 0 {
+		t.Fatalf("expected unmatched model to stay uncosted, got %#v", unpriced)
+	}
+}
+
+func TestDeleteModelPricingRefusesReferencedRows(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	march := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	pricing := &ModelPricing{Provider: "local", Model: "referenced", EffectiveFrom: march, InputCostPer1M: 1, OutputCostPer1M: 1}
+	if err := svc.CreateModelPricing(t.Context(), pricing); err != nil {
+		t.Fatal(err)
+	}
+	activity := &ActivityFeed{SessionKey: "ref", ProjectID: projectID, Model: "referenced", TokensIn: 1_000_000, CreatedAt: march.AddDate(0, 0, 1)}
+	mustCreateActivity(t, svc, activity)
+	if activity.PricingID == nil || *activity.PricingID != pricing.ID {
+		t.Fatalf("expected the activity to reference the row, got %v", activity.PricingID)
+	}
+
+	if err := svc.DeleteModelPricing(t.Context(), pricing.ID); !errors.Is(err, ErrModelPricingInUse) {
+		t.Fatalf("expected ErrModelPricingInUse, got %v", err)
+	}
+	if _, err := svc.UpdateModelPricing(t.Context(), pricing.ID, ModelPricingUpdate{}); err != nil {
+		t.Fatalf("expected the referenced row to be kept: %v", err)
+	}
+
+	if err := svc.db.Model(&ActivityFeed{}).Where("id = ?", activity.ID).Update("pricing_id", nil).Error; err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.DeleteModelPricing(t.Context(), pricing.ID); err != nil {
+		t.Fatalf("expected an unreferenced row to be deleted: %v", err)
+	}
+}
+
+func TestCreateActivityPrefersNewerPricingOverOpenRouter(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	t0 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	t1 := time.Date(2026, 4, 1, 0, 0, 0, 0, time.UTC)
+	openRouter := &ModelPricing{Provider: "openrouter", Model: "mixed-source", EffectiveFrom: t0, InputCostPer1M: 1, OutputCostPer1M: 1}
+	manual := &ModelPricing{Provider: "local", Model: "mixed-source", EffectiveFrom: t1, InputCostPer1M: 5, OutputCostPer1M: 5}
+	sameDay := &ModelPricing{Provider: "local", Model: "mixed-source", EffectiveFrom: t0, InputCostPer1M: 9, OutputCostPer1M: 9}
+	for _, pricing := range []*ModelPricing{openRouter, manual, sameDay} {
+		if err := svc.CreateModelPricing(t.Context(), pricing); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	cases := []struct {
+		name      string
+		createdAt time.Time
+		wantID    string
+	}{
+		{"newer manual row in effect", t1.AddDate(0, 0, 5), manual.ID},
+		{"openrouter wins a same-date tie", t0.AddDate(0, 0, 5), openRouter.ID},
+		{"older than every row uses the earliest", t0.AddDate(0, -1, 0), openRouter.ID},
+	}
+	for _, tc := range cases {
+		activity := &ActivityFeed{SessionKey: "mixed", ProjectID: projectID, Model: "mixed-source", TokensIn: 1_000_000, CreatedAt: tc.createdAt}
+		mustCreateActivity(t, svc, activity)
+		if activity.PricingID == nil || *activity.PricingID != tc.wantID {
+			t.Fatalf("%s: expected pricing_id %s, got %v", tc.name, tc.wantID, activity.PricingID)
+		}
+	}
+}
+
+func TestPriceTokenUsageChargesEachBucketWithFallbacks(t *testing.T) {
+	cacheRead, cacheWrite, reasoning := 0.3, 3.75, 20.0
+	usage := TokenUsage{Input: 1_000_000, Output: 1_000_000, CacheRead: 2_000_000, CacheWrite: 1_000_000, Reasoning: 400_000}
+
+	full := ModelPricing{InputCostPer1M: 3, OutputCostPer1M: 15, CacheReadCostPer1M: &cacheRead, CacheWriteCostPer1M: &cacheWrite, ReasoningCostPer1M: &reasoning}
+	// 3 input + 0.6 cache read + 3.75 cache write + 0.6M*15 output + 0.4M*20 reasoning
+	if got := priceTokenUsage(full, usage); !nearlyEqual(got, 3+0.6+3.75+9+8) {
+		t.Fatalf("expected bucketed cost 24.35, got %.4f", got)
+	}
+
+	// Without dedicated rates cached tokens cost the input rate and reasoning the output rate.
+	base := ModelPricing{InputCostPer1M: 3, OutputCostPer1M: 15}
+	if got := priceTokenUsage(base, usage); !nearlyEqual(got, 3+6+3+15) {
+		t.Fatalf("expected fallback cost 27.00, got %.4f", got)
+	}
+
+	// Reasoning beyond the output count is clamped rather than double-charged.
+	if got := priceTokenUsage(full, TokenUsage{Output: 100_000, Reasoning: 500_000}); !nearlyEqual(got, 2) {
+		t.Fatalf("expected clamped reasoning cost 2.00, got %.4f", got)
+	}
+}
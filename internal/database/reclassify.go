@@ -0,0 +1,181 @@
+package database
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"gorm.io/gorm"
+)
+
+const (
+	defaultReclassifyBatchSize = 500
+	maxReclassifyBatchSize     = 5000
+)
+
+// ActivityClassification is a classifier decision for one activity: the
+// primary category, its reason and the ranked labels.
+type ActivityClassification struct {
+	Category   string
+	Reason     string
+	Categories []ActivityCategory
+}
+
+// ReclassifyRequest selects the activities whose category is re-derived from
+// their stored classifier signals.
+type ReclassifyRequest struct {
+	Filters ActivityFilters
+	// DryRun computes the report without writing any rows.
+	DryRun    bool
+	BatchSize int
+	// Classify runs the current classifier over stored signals.
+	Classify func(features ActivityFeature) ActivityClassification
+}
+
+// ReclassifyReport counts the outcome of a reclassification. Before and After
+// are category counts over the reclassified activities only.
+type ReclassifyReport struct {
+	DryRun  bool  `json:"dry_run"`
+	Scanned int64 `json:"scanned"`
+	Changed int64 `json:"changed"`
+	// SkippedPreserved counts provided categories, user corrections and
+	// explicit overrides whose stored signals no longer contain the override,
+	// which are never overwritten.
+	SkippedPreserved int64 `json:"skipped_preserved"`
+	// SkippedNoSignals counts activities stored without signal storage.
+	SkippedNoSignals int64            `json:"skipped_no_signals"`
+	Batches          int              `json:"batches"`
+	Before           map[string]int64 `json:"before"`
+	After            map[string]int64 `json:"after"`
+}
+
+type reclassifyRow struct {
+	ID             string
+	Category       string
+	CategoryReason string
+	Signals        string
+	PromptTokens   StringList
+	PromptDigest   string
+	PromptText     string
+	AssistantText  string
+	ToolsUsed      StringList
+	Truncated      bool
+}
+
+// ReclassifyActivities re-runs request.Classify over the stored signals of
+// the matching activities in id order, one transaction per batch. Changed
+// activities get the new category, reason and labels.
+func (s *service) ReclassifyActivities(ctx context.Context, request ReclassifyRequest) (ReclassifyReport, error) {
+	if request.Classify == nil {
+		return ReclassifyReport{}, errors.New("reclassify requires a classifier")
+	}
+	batchSize := request.BatchSize
+	if batchSize == 0 {
+		batchSize = defaultReclassifyBatchSize
+	}
+	if batchSize < 0 || batchSize > maxReclassifyBatchSize {
+		return ReclassifyReport{}, fmt.Errorf("%w: batch_size must be between 1 and %d", ErrInvalidFilter, maxReclassifyBatchSize)
+	}
+
+	report := ReclassifyReport{DryRun: request.DryRun, Before: map[string]int64{}, After: map[string]int64{}}
+	lastID := ""
+	for {
+		query, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), request.Filters)
+		if err != nil {
+			return ReclassifyReport{}, err
+		}
+
+		var rows []reclassifyRow
+		if err := query.
+			Joins("LEFT JOIN activity_features ON activity_features.activity_id = activity_feed.id").
+			Select("activity_feed.id, activity_feed.category, activity_feed.category_reason, "+
+				"COALESCE(activity_features.signals, '') AS signals, activity_features.prompt_tokens, "+
+				"COALESCE(activity_features.prompt_digest, '') AS prompt_digest, COALESCE(activity_features.prompt_text, '') AS prompt_text, "+
+				"COALESCE(activity_features.assistant_text, '') AS assistant_text, activity_features.tools_used, "+
+				"COALESCE(activity_features.truncated, ?) AS truncated", false).
+			Where("activity_feed.id > ?", lastID).
+			Order("activity_feed.id asc").
+			Limit(batchSize).
+			Scan(&rows).Error; err != nil {
+			return ReclassifyReport{}, err
+		}
+		if len(rows) == 0 {
+			break
+		}
+		lastID = rows[len(rows)-1].ID
+		report.Batches++
+
+		type reclassification struct {
+			id string
+			ActivityClassification
+		}
+		updates := make([]reclassification, 0, len(rows))
+		for _, row := range rows {
+			report.Scanned++
+			switch {
+			case row.CategoryReason == CategoryReasonProvided || row.CategoryReason == CategoryReasonUserCorrection:
+				report.SkippedPreserved++
+				continue
+			case strings.HasPrefix(row.CategoryReason, CategoryReasonExplicitOverridePrefix) &&
+				(row.Signals != SignalStorageText || row.Truncated):
+				// Prompt tokens and truncated text may have lost the
+				// "category: name" the override came from.
+				report.SkippedPreserved++
+				continue
+			case row.Signals == "":
+				report.SkippedNoSignals++
+				continue
+			}
+
+			result := request.Classify(ActivityFeature{
+				ActivityID:    row.ID,
+				PromptTokens:  row.PromptTokens,
+				Signals:       row.Signals,
+				PromptDigest:  row.PromptDigest,
+				PromptText:    row.PromptText,
+				AssistantText: row.AssistantText,
+				ToolsUsed:     row.ToolsUsed,
+				Truncated:     row.Truncated,
+			})
+			report.Before[row.Category]++
+			report.After[result.Category]++
+			if result.Category == row.Category && result.Reason == row.CategoryReason {
+				continue
+			}
+			report.Changed++
+			updates = append(updates, reclassification{id: row.ID, ActivityClassification: result})
+		}
+
+		if !request.DryRun && len(updates) > 0 {
+			err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+				for _, update := range updates {
+					if err := tx.Model(&ActivityFeed{}).Where("id = ?", update.id).Updates(map[string]any{
+						"category":        update.Category,
+						"category_reason": update.Reason,
+					}).Error; err != nil {
+						return err
+					}
+					if err := tx.Where("activity_id = ?", update.id).Delete(&ActivityCategory{}).Error; err != nil {
+						return err
+					}
+					for _, label := range update.Categories {
+						label.ActivityID = update.id
+						if err := tx.Create(&label).Error; err != nil {
+							return err
+						}
+					}
+				}
+				return nil
+			})
+			if err != nil {
+				return ReclassifyReport{}, err
+			}
+		}
+
+		if len(rows) < batchSize {
+			break
+		}
+	}
+	return report, nil
+}
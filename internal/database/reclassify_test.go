@@ -0,0 +1,117 @@
+package database
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestReclassifyActivitiesRewritesStaleCategories(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	stale := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "general", CategoryReason: "fallback:insufficient_signals",
+		Categories: []ActivityCategory{{Category: "general", Score: 1}},
+		Features:   &ActivityFeature{PromptTokens: StringList{"deploy"}, Signals: SignalStorageText, PromptText: "deploy it", ToolsUsed: StringList{"kubectl"}}}
+	current := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "code", CategoryReason: "tool_signal:git",
+		Features: &ActivityFeature{PromptTokens: StringList{"commit"}, Signals: SignalStorageDigest, PromptDigest: "abc"}}
+	provided := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "admin", CategoryReason: CategoryReasonProvided,
+		Features: &ActivityFeature{Signals: SignalStorageText, PromptText: "deploy it"}}
+	corrected := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "research", CategoryReason: CategoryReasonUserCorrection,
+		Features: &ActivityFeature{Signals: SignalStorageText, PromptText: "deploy it"}}
+	unsignalled := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "general", CategoryReason: "fallback:insufficient_signals",
+		Features: &ActivityFeature{PromptTokens: StringList{"deploy"}}}
+	for _, activity := range []*ActivityFeed{stale, current, provided, corrected, unsignalled} {
+		mustCreateActivity(t, svc, activity)
+	}
+
+	var seen []ActivityFeature
+	classify := func(features ActivityFeature) ActivityClassification {
+		seen = append(seen, features)
+		if features.PromptText == "deploy it" {
+			return ActivityClassification{Category: "deploy", Reason: "prompt_keyword_score:deploy=2", Categories: []ActivityCategory{
+				{Category: "deploy", Rank: 0, Score: 0.75},
+				{Category: "code", Rank: 1, Score: 0.25},
+			}}
+		}
+		return ActivityClassification{Category: "code", Reason: "tool_signal:git"}
+	}
+
+	report, err := svc.ReclassifyActivities(t.Context(), ReclassifyRequest{DryRun: true, BatchSize: 2, Classify: classify})
+	if err != nil {
+		t.Fatalf("expected dry run to succeed: %v", err)
+	}
+	want := ReclassifyReport{
+		DryRun: true, Scanned: 5, Changed: 1, SkippedPreserved: 2, SkippedNoSignals: 1, Batches: 3,
+		Before: map[string]int64{"general": 1, "code": 1},
+		After:  map[string]int64{"deploy": 1, "code": 1},
+	}
+	if !reflect.DeepEqual(report, want) {
+		t.Fatalf("expected %#v, got %#v", want, report)
+	}
+	if len(seen) != 2 {
+		t.Fatalf("expected only signalled, unpreserved activities to be classified, got %#v", seen)
+	}
+	for _, features := range seen {
+		if features.ActivityID == stale.ID && !reflect.DeepEqual([]string(features.ToolsUsed), []string{"kubectl"}) {
+			t.Fatalf("expected stored tools to reach the classifier, got %#v", features)
+		}
+	}
+
+	activities, err := svc.ListActivities(t.Context(), ActivityFilters{Categories: []string{"deploy"}})
+	if err != nil || len(activities) != 0 {
+		t.Fatalf("expected dry run to leave rows untouched, got %v %#v", err, activities)
+	}
+
+	report, err = svc.ReclassifyActivities(t.Context(), ReclassifyRequest{Classify: classify})
+	if err != nil || report.Changed != 1 || report.DryRun {
+		t.Fatalf("expected reclassification to apply, got %v %#v", err, report)
+	}
+	activities, err = svc.ListActivities(t.Context(), ActivityFilters{Categories: []string{"deploy"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 1 || activities[0].ID != stale.ID || activities[0].CategoryReason != "prompt_keyword_score:deploy=2" {
+		t.Fatalf("expected the stale activity to be reclassified, got %#v", activities)
+	}
+	if labels := activities[0].Categories; len(labels) != 2 || labels[0].Category != "deploy" || labels[1].Category != "code" {
+		t.Fatalf("expected labels to be replaced, got %#v", labels)
+	}
+
+	if _, err := svc.ReclassifyActivities(t.Context(), ReclassifyRequest{BatchSize: -1, Classify: classify}); !errors.Is(err, ErrInvalidFilter) {
+		t.Fatalf("expected ErrInvalidFilter, got %v", err)
+	}
+}
+
+func TestReclassifyActivitiesPreservesUnrecoverableExplicitOverrides(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	const reason = "explicit_override:category=admin"
+	fromTokens := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "admin", CategoryReason: reason,
+		Features: &ActivityFeature{PromptTokens: StringList{"category", "admin"}}}
+	fromDigest := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "admin", CategoryReason: reason,
+		Features: &ActivityFeature{PromptTokens: StringList{"category", "admin"}, Signals: SignalStorageDigest, PromptDigest: "abc"}}
+	fromTruncated := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "admin", CategoryReason: reason,
+		Features: &ActivityFeature{Signals: SignalStorageText, PromptText: "deploy it", Truncated: true}}
+	fromText := &ActivityFeed{ProjectID: projectID, Model: "gpt-5", Category: "admin", CategoryReason: reason,
+		Features: &ActivityFeature{Signals: SignalStorageText, PromptText: "deploy it"}}
+	for _, activity := range []*ActivityFeed{fromTokens, fromDigest, fromTruncated, fromText} {
+		mustCreateActivity(t, svc, activity)
+	}
+
+	var seen []string
+	report, err := svc.ReclassifyActivities(t.Context(), ReclassifyRequest{Classify: func(features ActivityFeature) ActivityClassification {
+		seen = append(seen, features.ActivityID)
+		return ActivityClassification{Category: "deploy", Reason: "prompt_keyword_score:deploy=2"}
+	}})
+	if err != nil {
+		t.Fatalf("expected reclassification to succeed: %v", err)
+	}
+	if report.Scanned != 4 || report.SkippedPreserved != 3 || report.Changed != 1 {
+		t.Fatalf("expected only the untruncated text override to be reclassified, got %#v", report)
+	}
+	if len(seen) != 1 || seen[0] != fromText.ID {
+		t.Fatalf("expected only %s to be classified, got %v", fromText.ID, seen)
+	}
+}
@@ -0,0 +1,328 @@
+package database
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+var ErrSessionNotFound = errors.New("session not found")
+
+const (
+	defaultSessionListSize = 100
+	failedActivityStatus   = "failed"
+)
+
+// SessionSummary aggregates the activity rows sharing a session_key. The
+// dominant project and category are the most frequent values, ties broken
+// alphabetically.
+type SessionSummary struct {
+	SessionKey       string    `json:"session_key"`
+	FirstSeen        time.Time `json:"first_seen"`
+	LastSeen         time.Time `json:"last_seen"`
+	TurnCount        int64     `json:"turn_count"`
+	MemoryCount      int64     `json:"memory_count"`
+	TokensInTotal    int64     `json:"tokens_in_total"`
+	TokensOutTotal   int64     `json:"tokens_out_total"`
+	CostTotal        float64   `json:"cost_total"`
+	Currency         string    `json:"currency"`
+	DurationMSTotal  int64     `json:"duration_ms_total"`
+	FailureCount     int64     `json:"failure_count"`
+	DominantProject  string    `json:"dominant_project"`
+	DominantCategory string    `json:"dominant_category"`
+}
+
+// SessionTimelineEntry is either an activity row or a turn memory; Type says
+// which field is set.
+type SessionTimelineEntry struct {
+	Type      string        `json:"type"`
+	CreatedAt time.Time     `json:"created_at"`
+	Activity  *ActivityFeed `json:"activity,omitempty"`
+	Memory    *TurnMemory   `json:"memory,omitempty"`
+}
+
+type SessionDetail struct {
+	SessionSummary
+	Timeline []SessionTimelineEntry `json:"timeline"`
+}
+
+// sqlTime scans aggregate timestamps, which SQLite returns as text rather
+// than as a typed column.
+type sqlTime struct {
+	time.Time
+}
+
+var sqliteTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02T15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999",
+	time.RFC3339Nano,
+}
+
+func (t *sqlTime) Scan(value any) error {
+	var raw string
+	switch v := value.(type) {
+	case nil:
+		t.Time = time.Time{}
+		return nil
+	case time.Time:
+		t.Time = v.UTC()
+		return nil
+	case string:
+		raw = v
+	case []byte:
+		raw = string(v)
+	default:
+		return fmt.Errorf("unsupported time column type %T", value)
+	}
+
+	for _, layout := range sqliteTimeLayouts {
+		if parsed, err := time.Parse(layout, strings.TrimSpace(raw)); err == nil {
+			t.Time = parsed.UTC()
+			return nil
+		}
+	}
+	return fmt.Errorf("unrecognized time value %q", raw)
+}
+
+func (t sqlTime) Value() (driver.Value, error) {
+	return t.Time, nil
+}
+
+func (s *service) ListSessions(ctx context.Context, filters ActivityFilters) ([]SessionSummary, error) {
+	limit := filters.Limit
+	if limit < 0 || limit > maxActivityPageSize {
+		return nil, ErrInvalidLimit
+	}
+	if limit == 0 {
+		limit = defaultSessionListSize
+	}
+
+	currency, err := s.costConversion(ctx, filters)
+	if err != nil {
+		return nil, err
+	}
+	costSQL, costArgs := convertedCostSQL("activity_feed", currency)
+
+	tx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		SessionKey      string
+		FirstSeen       sqlTime
+		LastSeen        sqlTime
+		TurnCount       int64
+		TokensInTotal   int64
+		TokensOutTotal  int64
+		CostTotal       float64
+		DurationMSTotal int64
+		FailureCount    int64
+	}
+	if err := tx.Select(
+		"activity_feed.session_key AS session_key, "+
+			"MIN(activity_feed.created_at) AS first_seen, "+
+			"MAX(activity_feed.created_at) AS last_seen, "+
+			"COUNT(*) AS turn_count, "+
+			"COALESCE(SUM(activity_feed.tokens_in), 0) AS tokens_in_total, "+
+			"COALESCE(SUM(activity_feed.tokens_out), 0) AS tokens_out_total, "+
+			"COALESCE(SUM("+costSQL+"), 0) AS cost_total, "+
+			"COALESCE(SUM(activity_feed.duration_ms), 0) AS duration_ms_total, "+
+			"SUM(CASE WHEN activity_feed.status = ? THEN 1 ELSE 0 END) AS failure_count",
+		append(costArgs, failedActivityStatus)...,
+	).
+		Where("activity_feed.session_key <> ''").
+		Group("activity_feed.session_key").
+		Order("last_seen desc").
+		Order("activity_feed.session_key asc").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	sessions := make([]SessionSummary, 0, len(rows))
+	keys := make([]string, 0, len(rows))
+	for _, row := range rows {
+		sessions = append(sessions, SessionSummary{
+			SessionKey:      row.SessionKey,
+			FirstSeen:       row.FirstSeen.Time,
+			LastSeen:        row.LastSeen.Time,
+			TurnCount:       row.TurnCount,
+			TokensInTotal:   row.TokensInTotal,
+			TokensOutTotal:  row.TokensOutTotal,
+			CostTotal:       row.CostTotal,
+			Currency:        currency,
+			DurationMSTotal: row.DurationMSTotal,
+			FailureCount:    row.FailureCount,
+		})
+		keys = append(keys, row.SessionKey)
+	}
+	if len(keys) == 0 {
+		return sessions, nil
+	}
+
+	projects, err := s.dominantSessionValues(ctx, filters, keys, "COALESCE(session_projects.slug, '')")
+	if err != nil {
+		return nil, err
+	}
+	categories, err := s.dominantSessionValues(ctx, filters, keys, "activity_feed.category")
+	if err != nil {
+		return nil, err
+	}
+
+	var memoryCounts []struct {
+		SessionKey string
+		Count      int64
+	}
+	if err := s.db.WithContext(ctx).Model(&TurnMemory{}).
+		Select("session_key, COUNT(*) AS count").
+		Where("session_key IN ?", keys).
+		Group("session_key").
+		Scan(&memoryCounts).Error; err != nil {
+		return nil, err
+	}
+	memoriesBySession := make(map[string]int64, len(memoryCounts))
+	for _, row := range memoryCounts {
+		memoriesBySession[row.SessionKey] = row.Count
+	}
+
+	for i := range sessions {
+		key := sessions[i].SessionKey
+		sessions[i].DominantProject = projects[key]
+		sessions[i].DominantCategory = categories[key]
+		sessions[i].MemoryCount = memoriesBySession[key]
+	}
+	return sessions, nil
+}
+
+func (s *service) dominantSessionValues(ctx context.Context, filters ActivityFilters, keys []string, expression string) (map[string]string, error) {
+	tx, err := applyActivityFilters(s.db.WithContext(ctx).Model(&ActivityFeed{}), filters)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []struct {
+		SessionKey string
+		Value      string
+		Count      int64
+	}
+	if err := tx.
+		Joins("LEFT JOIN projects AS session_projects ON session_projects.id = activity_feed.project_id").
+		Select("activity_feed.session_key AS session_key, "+expression+" AS value, COUNT(*) AS count").
+		Where("activity_feed.session_key IN ?", keys).
+		Group("1, 2").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]map[string]int64, len(keys))
+	for _, row := range rows {
+		if counts[row.SessionKey] == nil {
+			counts[row.SessionKey] = map[string]int64{}
+		}
+		counts[row.SessionKey][row.Value] += row.Count
+	}
+	dominant := make(map[string]string, len(counts))
+	for key, values := range counts {
+		dominant[key] = mostFrequent(values)
+	}
+	return dominant, nil
+}
+
+func mostFrequent(counts map[string]int64) string {
+	best := ""
+	var bestCount int64 = -1
+	for value, count := range counts {
+		if count > bestCount || (count == bestCount && value < best) {
+			best = value
+			bestCount = count
+		}
+	}
+	return best
+}
+
+func (s *service) GetSession(ctx context.Context, sessionKey string) (SessionDetail, error) {
+	key := strings.TrimSpace(sessionKey)
+	if key == "" {
+		return SessionDetail{}, ErrSessionNotFound
+	}
+
+	activities, err := s.ListActivities(ctx, ActivityFilters{SessionKeys: []string{key}, Sort: "created_at"})
+	if err != nil {
+		return SessionDetail{}, err
+	}
+	memories := []TurnMemory{}
+	if err := s.db.WithContext(ctx).
+		Where("session_key = ?", key).
+		Order("created_at asc").
+		Order("id asc").
+		Find(&memories).Error; err != nil {
+		return SessionDetail{}, err
+	}
+	if len(activities) == 0 && len(memories) == 0 {
+		return SessionDetail{}, ErrSessionNotFound
+	}
+
+	detail := SessionDetail{
+		SessionSummary: summarizeSession(key, activities, memories),
+		Timeline:       make([]SessionTimelineEntry, 0, len(activities)+len(memories)),
+	}
+	for i := range activities {
+		detail.Timeline = append(detail.Timeline, SessionTimelineEntry{Type: "activity", CreatedAt: activities[i].CreatedAt, Activity: &activities[i]})
+	}
+	for i := range memories {
+		detail.Timeline = append(detail.Timeline, SessionTimelineEntry{Type: "memory", CreatedAt: memories[i].CreatedAt, Memory: &memories[i]})
+	}
+	// Stable sort keeps activities ahead of memories recorded at the same instant.
+	sort.SliceStable(detail.Timeline, func(i, j int) bool {
+		return detail.Timeline[i].CreatedAt.Before(detail.Timeline[j].CreatedAt)
+	})
+	return detail, nil
+}
+
+func summarizeSession(key string, activities []ActivityFeed, memories []TurnMemory) SessionSummary {
+	summary := SessionSummary{
+		SessionKey:  key,
+		TurnCount:   int64(len(activities)),
+		MemoryCount: int64(len(memories)),
+	}
+
+	projects := map[string]int64{}
+	categories := map[string]int64{}
+	for _, activity := range activities {
+		summary.TokensInTotal += int64(activity.TokensIn)
+		summary.TokensOutTotal += int64(activity.TokensOut)
+		summary.CostTotal += activity.CostEstimate
+		summary.DurationMSTotal += activity.DurationMS
+		if activity.Status == failedActivityStatus {
+			summary.FailureCount++
+		}
+		projects[activity.ProjectTag]++
+		categories[activity.Category]++
+		summary.observe(activity.CreatedAt)
+	}
+	if len(activities) == 0 {
+		for _, memory := range memories {
+			summary.observe(memory.CreatedAt)
+		}
+	}
+	summary.DominantProject = mostFrequent(projects)
+	summary.DominantCategory = mostFrequent(categories)
+	return summary
+}
+
+func (s *SessionSummary) observe(at time.Time) {
+	at = at.UTC()
+	if s.FirstSeen.IsZero() || at.Before(s.FirstSeen) {
+		s.FirstSeen = at
+	}
+	if at.After(s.LastSeen) {
+		s.LastSeen = at
+	}
+}
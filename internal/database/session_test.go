@@ -0,0 +1,95 @@
+package database
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestListSessionsAggregatesActivityBySessionKey(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	alpha := mustProjectID(t, svc, "alpha")
+	beta := mustProjectID(t, svc, "beta")
+
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	for _, row := range []*ActivityFeed{
+		{SessionKey: "s-1", ProjectID: alpha, Category: "code", Status: "success", TokensIn: 10, TokensOut: 5, DurationMS: 100, CreatedAt: base},
+		{SessionKey: "s-1", ProjectID: alpha, Category: "code", Status: "failed", TokensIn: 20, TokensOut: 5, DurationMS: 200, CreatedAt: base.Add(time.Minute)},
+		{SessionKey: "s-1", ProjectID: beta, Category: "research", Status: "success", TokensIn: 30, TokensOut: 5, CreatedAt: base.Add(2 * time.Minute)},
+		{SessionKey: "s-2", ProjectID: beta, Category: "admin", Status: "success", TokensIn: 1, CreatedAt: base.Add(time.Hour)},
+	} {
+		mustCreateActivity(t, svc, row)
+	}
+	if err := svc.CreateTurnMemory(t.Context(), &TurnMemory{SessionKey: "s-1", Summary: "first turn"}); err != nil {
+		t.Fatal(err)
+	}
+
+	sessions, err := svc.ListSessions(t.Context(), ActivityFilters{})
+	if err != nil {
+		t.Fatalf("expected session list to succeed: %v", err)
+	}
+	if len(sessions) != 2 || sessions[0].SessionKey != "s-2" {
+		t.Fatalf("expected most recent session first, got %#v", sessions)
+	}
+
+	got := sessions[1]
+	if got.TurnCount != 3 || got.TokensInTotal != 60 || got.TokensOutTotal != 15 || got.DurationMSTotal != 300 {
+		t.Fatalf("expected s-1 totals, got %#v", got)
+	}
+	if got.FailureCount != 1 || got.MemoryCount != 1 {
+		t.Fatalf("expected one failure and one memory, got %#v", got)
+	}
+	if got.DominantProject != "alpha" || got.DominantCategory != "code" {
+		t.Fatalf("expected alpha/code to dominate, got %q/%q", got.DominantProject, got.DominantCategory)
+	}
+	if !got.FirstSeen.Equal(base) || !got.LastSeen.Equal(base.Add(2*time.Minute)) {
+		t.Fatalf("expected first/last seen to span the session, got %s..%s", got.FirstSeen, got.LastSeen)
+	}
+
+	filtered, err := svc.ListSessions(t.Context(), ActivityFilters{ProjectTags: []string{"beta"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(filtered) != 2 || filtered[1].TurnCount != 1 || filtered[1].DominantProject != "beta" {
+		t.Fatalf("expected filters to narrow counted rows, got %#v", filtered)
+	}
+}
+
+func TestGetSessionInterleavesActivitiesAndMemories(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	projectID := mustProjectID(t, svc, "alpha")
+
+	base := time.Date(2026, 2, 18, 10, 0, 0, 0, time.UTC)
+	mustCreateActivity(t, svc, &ActivityFeed{SessionKey: "s-1", ProjectID: projectID, CreatedAt: base})
+	mustCreateActivity(t, svc, &ActivityFeed{SessionKey: "s-1", ProjectID: projectID, CreatedAt: base.Add(2 * time.Minute)})
+	for _, memory := range []*TurnMemory{
+		{SessionKey: "s-1", Summary: "after first", CreatedAt: base.Add(time.Minute)},
+		{SessionKey: "s-1", Summary: "same instant", CreatedAt: base.Add(2 * time.Minute)},
+	} {
+		if err := svc.CreateTurnMemory(t.Context(), memory); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	detail, err := svc.GetSession(t.Context(), "s-1")
+	if err != nil {
+		t.Fatalf("expected session to load: %v", err)
+	}
+	types := make([]string, 0, len(detail.Timeline))
+	for _, entry := range detail.Timeline {
+		types = append(types, entry.Type)
+	}
+	want := []string{"activity", "memory", "activity", "memory"}
+	for i := range want {
+		if i >= len(types) || types[i] != want[i] {
+			t.Fatalf("expected timeline %v, got %v", want, types)
+		}
+	}
+	if detail.TurnCount != 2 || detail.MemoryCount != 2 || detail.DominantProject != "alpha" {
+		t.Fatalf("expected session summary, got %#v", detail.SessionSummary)
+	}
+
+	if _, err := svc.GetSession(t.Context(), "missing"); !errors.Is(err, ErrSessionNotFound) {
+		t.Fatalf("expected ErrSessionNotFound, got %v", err)
+	}
+}
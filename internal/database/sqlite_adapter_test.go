@@ -172,11 +172,11 @@ func TestSQLiteAdapterGeneratesUUIDPrimaryKeys(t *testing.T) {
 	memory := TurnMemory{
 		SessionKey:     "session-123",
 		Summary:        "implemented storage adapter",
-		ToolsUsed:      "[\"go\",\"gorm\"]",
-		FilesTouched:   "[\"internal/database/database.go\"]",
-		KeyDecisions:   "[\"use sqlite for local-first\"]",
+		ToolsUsed:      StringList{"go", "gorm"},
+		FilesTouched:   StringList{"internal/database/database.go"},
+		KeyDecisions:   StringList{"use sqlite for local-first"},
 		ContextSnippet: "storage migration",
-		Tags:           "[\"storage\",\"sqlite\"]",
+		Tags:           StringList{"storage", "sqlite"},
 	}
 
 	if err := svc.db.Create(&memory).Error; err != nil {
@@ -596,22 +596,31 @@ func TestBootstrapOpenRouterModelPricingImportsCatalogWhenMissing(t *testing.T)
 	}
 
 	var prices []ModelPricing
-	if err := svc.db.Where("provider = ?", "openrouter").Order("model asc").Find(&prices).Error; err != nil {
+	if err := svc.db.Where("provider = ?", "openrouter").Order("model asc").Order("effective_from desc").Find(&prices).Error; err != nil {
 		t.Fatalf("expected imported rows to be queryable: %v", err)
 	}
-	if len(prices) != 3 {
-		t.Fatalf("expected 3 openrouter rows after import upsert, got %d", len(prices))
+	// The seeded kimi row is kept as history next to the repriced import.
+	if len(prices) != 4 {
+		t.Fatalf("expected 4 openrouter rows after import upsert, got %d", len(prices))
 	}
 	assertSeededModelPricing(t, prices, "openrouter", "moonshotai/kimi-k2.5", 2.0, 6.0, false)
 	assertSeededModelPricing(t, prices, "openrouter", "openrouter/hunter-alpha", 0.0, 0.0, false)
 
 	var imported ModelPricing
-	if err := svc.db.Where("provider = ? AND model = ?", "openrouter", "moonshotai/kimi-k2.5").First(&imported).Error; err != nil {
+	if err := svc.db.Where("provider = ? AND model = ?", "openrouter", "moonshotai/kimi-k2.5").Order("effective_from desc").First(&imported).Error; err != nil {
 		t.Fatalf("expected imported kimi row to exist: %v", err)
 	}
 	if imported.Source != openRouterModelsAPIURL {
 		t.Fatalf("expected imported source %q, got %q", openRouterModelsAPIURL, imported.Source)
 	}
+
+	var seeded ModelPricing
+	if err := svc.db.Where("provider = ? AND model = ?", "openrouter", "moonshotai/kimi-k2.5").Order("effective_from asc").First(&seeded).Error; err != nil {
+		t.Fatalf("expected seeded kimi row to remain: %v", err)
+	}
+	if seeded.ID == imported.ID || !nearlyEqual(seeded.InputCostPer1M, 0.45) {
+		t.Fatalf("expected seeded kimi pricing to be preserved, got %#v", seeded)
+	}
 }
 
 func TestBootstrapOpenRouterModelPricingSkipsWhenImportedRowsAlreadyExist(t *testing.T) {
@@ -738,7 +747,7 @@ func TestRefreshOpenRouterModelPricingIfDueRefreshesStaleCatalog(t *testing.T) {
 	}
 
 	var refreshed ModelPricing
-	if err := svc.db.Where("provider = ? AND model = ?", "openrouter", "moonshotai/kimi-k2.5").First(&refreshed).Error; err != nil {
+	if err := svc.db.Where("provider = ? AND model = ? AND source = ?", "openrouter", "moonshotai/kimi-k2.5", openRouterModelsAPIURL).Order("effective_from desc").First(&refreshed).Error; err != nil {
 		t.Fatalf("expected refreshed kimi row to exist: %v", err)
 	}
 	if refreshed.InputCostPer1M != 0.5 || refreshed.OutputCostPer1M != 2.4 {
@@ -748,6 +757,14 @@ func TestRefreshOpenRouterModelPricingIfDueRefreshesStaleCatalog(t *testing.T) {
 		t.Fatal("expected refreshed kimi row to be marked fresh")
 	}
 
+	var superseded ModelPricing
+	if err := svc.db.First(&superseded, "id = ?", existing.ID).Error; err != nil {
+		t.Fatalf("expected superseded kimi row to remain: %v", err)
+	}
+	if superseded.InputCostPer1M != 0.45 || !superseded.EffectiveFrom.Equal(oldVerified) {
+		t.Fatalf("expected superseded kimi pricing to be preserved, got %#v", superseded)
+	}
+
 	var added ModelPricing
 	if err := svc.db.Where("provider = ? AND model = ?", "openrouter", "openai/gpt-5.4").First(&added).Error; err != nil {
 		t.Fatalf("expected new model to be imported during refresh: %v", err)
@@ -856,7 +873,7 @@ func TestRefreshOpenRouterModelPricingIfDueMarksRowsStaleOnFailure(t *testing.T)
 	}
 
 	var refreshed ModelPricing
-	if err := svc.db.Where("provider = ? AND model = ?", "openrouter", "moonshotai/kimi-k2.5").First(&refreshed).Error; err != nil {
+	if err := svc.db.First(&refreshed, "id = ?", staleCandidate.ID).Error; err != nil {
 		t.Fatalf("expected stale row lookup to succeed: %v", err)
 	}
 	if !refreshed.IsStale {
@@ -939,6 +956,52 @@ func TestNewSQLiteAdapterBackfillsLegacyProjectTagWithoutLock(t *testing.T) {
 	}
 }
 
+func TestCreateActivitiesInsertsAllRowsInOneTransaction(t *testing.T) {
+	disableOpenRouterBootstrap(t)
+	adapter, err := NewSQLiteAdapter(filepath.Join(t.TempDir(), "clawtivity.db"))
+	if err != nil {
+		t.Fatalf("expected adapter to initialize: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = adapter.Close()
+	})
+
+	svc := adapter.(*service)
+	projectID := mustProjectID(t, svc, "clawtivity")
+
+	batch := []*ActivityFeed{
+		{SessionKey: "batch-1", Model: "gpt-5", TokensIn: 1000, TokensOut: 500, ProjectID: projectID, ProjectTag: "clawtivity"},
+		{SessionKey: "batch-2", Model: "gpt-5", TokensIn: 10, TokensOut: 5, ProjectID: projectID, ProjectTag: "clawtivity"},
+	}
+	if err := adapter.CreateActivities(t.Context(), batch); err != nil {
+		t.Fatalf("expected batch insert to succeed: %v", err)
+	}
+	for _, activity := range batch {
+		if !looksLikeUUID(activity.ID) {
+			t.Fatalf("expected generated id, got %q", activity.ID)
+		}
+	}
+	if batch[0].CostEstimate <= 0 {
+		t.Fatalf("expected reference cost to be resolved, got %f", batch[0].CostEstimate)
+	}
+
+	invalid := []*ActivityFeed{
+		{SessionKey: "batch-3", Model: "gpt-5", ProjectID: projectID},
+		{SessionKey: "batch-4", Model: "gpt-5"},
+	}
+	if err := adapter.CreateActivities(t.Context(), invalid); err == nil {
+		t.Fatal("expected batch with missing project_id to fail")
+	}
+
+	var count int64
+	if err := svc.db.Model(&ActivityFeed{}).Count(&count).Error; err != nil {
+		t.Fatal(err)
+	}
+	if count != 2 {
+		t.Fatalf("expected failed batch to insert nothing, got %d rows", count)
+	}
+}
+
 func looksLikeUUID(value string) bool {
 	if len(value) != 36 {
 		return false
@@ -0,0 +1,503 @@
+package database
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+var ErrWebhookNotFound = errors.New("webhook not found")
+var ErrInvalidWebhook = errors.New("invalid webhook")
+
+// Webhook events. WebhookEventTest is only sent by TestWebhook and cannot be
+// subscribed to.
+const (
+	WebhookEventActivityFailed         = "activity.failed"
+	WebhookEventQueueReplayFailed      = "queue.replay_failed"
+	WebhookEventPricingRefreshFailed   = "pricing.refresh_failed"
+	WebhookEventBudgetThresholdCrossed = "budget.threshold_crossed"
+	WebhookEventTest                   = "webhook.test"
+)
+
+var webhookEvents = []string{
+	WebhookEventActivityFailed,
+	WebhookEventQueueReplayFailed,
+	WebhookEventPricingRefreshFailed,
+	WebhookEventBudgetThresholdCrossed,
+}
+
+// Webhook delivery states.
+const (
+	WebhookDeliveryPending   = "pending"
+	WebhookDeliverySucceeded = "succeeded"
+	WebhookDeliveryFailed    = "failed"
+)
+
+// WebhookSignatureHeader carries "sha256=" and the hex HMAC-SHA256 of the
+// request body keyed with the webhook secret.
+const WebhookSignatureHeader = "X-Clawtivity-Signature"
+
+const (
+	// defaultWebhookDeliveryLease applies when the client has no timeout.
+	defaultWebhookDeliveryLease = time.Minute
+	webhookDeliveryLeaseGrace   = 5 * time.Second
+)
+
+// Webhook posts the events listed in Events, or every event when Events is
+// empty, to URL.
+type Webhook struct {
+	ID        string     `gorm:"type:char(36);primaryKey" json:"id"`
+	URL       string     `json:"url"`
+	Secret    string     `json:"secret,omitempty"`
+	HasSecret bool       `gorm:"-" json:"has_secret"`
+	Events    StringList `gorm:"type:json" json:"events"`
+	Enabled   bool       `json:"enabled"`
+	CreatedAt time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (Webhook) TableName() string {
+	return "webhooks"
+}
+
+func (w *Webhook) BeforeCreate(_ *gorm.DB) error {
+	if w.ID == "" {
+		w.ID = generateUUIDv4()
+	}
+	return nil
+}
+
+// Redacted returns w without its secret, for API responses.
+func (w Webhook) Redacted() Webhook {
+	w.HasSecret = w.Secret != ""
+	w.Secret = ""
+	return w
+}
+
+func (w Webhook) subscribes(event string) bool {
+	if len(w.Events) == 0 {
+		return true
+	}
+	for _, subscribed := range w.Events {
+		if subscribed == event {
+			return true
+		}
+	}
+	return false
+}
+
+// WebhookUpdate carries the fields a PATCH may change; nil fields are left
+// untouched. An empty secret removes signing.
+type WebhookUpdate struct {
+	URL     *string   `json:"url,omitempty"`
+	Secret  *string   `json:"secret,omitempty"`
+	Events  *[]string `json:"events,omitempty"`
+	Enabled *bool     `json:"enabled,omitempty"`
+}
+
+// WebhookDelivery is one event queued for a webhook and the log of its
+// attempts. Payload is the exact body sent on every attempt.
+type WebhookDelivery struct {
+	ID             string     `gorm:"type:char(36);primaryKey" json:"id"`
+	WebhookID      string     `gorm:"type:char(36);index:idx_webhook_deliveries_webhook_id" json:"webhook_id"`
+	Event          string     `json:"event"`
+	Payload        string     `json:"payload"`
+	Status         string     `gorm:"index:idx_webhook_deliveries_due,priority:1" json:"status"`
+	Attempts       int        `json:"attempts"`
+	NextAttemptAt  *time.Time `gorm:"index:idx_webhook_deliveries_due,priority:2" json:"next_attempt_at,omitempty"`
+	LastAttemptAt  *time.Time `json:"last_attempt_at,omitempty"`
+	ResponseStatus int        `json:"response_status,omitempty"`
+	LastError      string     `json:"last_error,omitempty"`
+	CreatedAt      time.Time  `gorm:"autoCreateTime" json:"created_at"`
+	UpdatedAt      time.Time  `gorm:"autoUpdateTime" json:"updated_at"`
+}
+
+func (WebhookDelivery) TableName() string {
+	return "webhook_deliveries"
+}
+
+func (d *WebhookDelivery) BeforeCreate(_ *gorm.DB) error {
+	if d.ID == "" {
+		d.ID = generateUUIDv4()
+	}
+	return nil
+}
+
+type webhookConfig struct {
+	Interval    time.Duration
+	MaxAttempts int
+	Backoff     time.Duration
+	Client      *http.Client
+}
+
+func (s *service) ListWebhooks(ctx context.Context) ([]Webhook, error) {
+	var webhooks []Webhook
+	if err := s.db.WithContext(ctx).Order("created_at asc").Find(&webhooks).Error; err != nil {
+		return nil, err
+	}
+	return webhooks, nil
+}
+
+func (s *service) CreateWebhook(ctx context.Context, webhook *Webhook) error {
+	if webhook == nil {
+		return fmt.Errorf("%w: webhook is required", ErrInvalidWebhook)
+	}
+	if err := normalizeWebhook(webhook); err != nil {
+		return err
+	}
+	return s.db.WithContext(ctx).Create(webhook).Error
+}
+
+func (s *service) UpdateWebhook(ctx context.Context, id string, update WebhookUpdate) (Webhook, error) {
+	var webhook Webhook
+	err := s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.First(&webhook, "id = ?", strings.TrimSpace(id)).Error; err != nil {
+			if errors.Is(err, gorm.ErrRecordNotFound) {
+				return ErrWebhookNotFound
+			}
+			return err
+		}
+
+		if update.URL != nil {
+			webhook.URL = *update.URL
+		}
+		if update.Secret != nil {
+			webhook.Secret = *update.Secret
+		}
+		if update.Events != nil {
+			webhook.Events = *update.Events
+		}
+		if update.Enabled != nil {
+			webhook.Enabled = *update.Enabled
+		}
+		if err := normalizeWebhook(&webhook); err != nil {
+			return err
+		}
+		return tx.Save(&webhook).Error
+	})
+	if err != nil {
+		return Webhook{}, err
+	}
+	return webhook, nil
+}
+
+func (s *service) DeleteWebhook(ctx context.Context, id string) error {
+	return s.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		trimmed := strings.TrimSpace(id)
+		result := tx.Delete(&Webhook{}, "id = ?", trimmed)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrWebhookNotFound
+		}
+		return tx.Delete(&WebhookDelivery{}, "webhook_id = ?", trimmed).Error
+	})
+}
+
+// ListWebhookDeliveries returns a webhook's deliveries, newest first.
+func (s *service) ListWebhookDeliveries(ctx context.Context, webhookID string, limit int) ([]WebhookDelivery, error) {
+	if err := s.db.WithContext(ctx).First(&Webhook{}, "id = ?", strings.TrimSpace(webhookID)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil, ErrWebhookNotFound
+		}
+		return nil, err
+	}
+	if limit <= 0 || limit > maxActivityPageSize {
+		limit = defaultActivityPageSize
+	}
+
+	var deliveries []WebhookDelivery
+	if err := s.db.WithContext(ctx).
+		Where("webhook_id = ?", strings.TrimSpace(webhookID)).
+		Order("created_at desc, id desc").
+		Limit(limit).
+		Find(&deliveries).Error; err != nil {
+		return nil, err
+	}
+	return deliveries, nil
+}
+
+// EnqueueWebhookEvent queues event for every enabled webhook subscribed to
+// it; the background worker delivers it.
+func (s *service) EnqueueWebhookEvent(ctx context.Context, event string, data any) error {
+	return enqueueWebhookEvent(ctx, s.db, event, data)
+}
+
+// TestWebhook sends a webhook.test event to the webhook right away, even if
+// it is disabled, and returns the delivery after the first attempt. A failed
+// attempt is retried like any other delivery.
+func (s *service) TestWebhook(ctx context.Context, id string) (WebhookDelivery, error) {
+	var webhook Webhook
+	if err := s.db.WithContext(ctx).First(&webhook, "id = ?", strings.TrimSpace(id)).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return WebhookDelivery{}, ErrWebhookNotFound
+		}
+		return WebhookDelivery{}, err
+	}
+
+	// Created leased so the worker does not pick it up while the first
+	// attempt is in flight, but retries it if that attempt is abandoned.
+	now := time.Now().UTC()
+	leasedUntil := now.Add(webhookDeliveryLease(s.webhooks))
+	delivery, err := newWebhookDelivery(webhook.ID, WebhookEventTest, map[string]any{"webhook_id": webhook.ID}, &leasedUntil)
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	if err := s.db.WithContext(ctx).Create(&delivery).Error; err != nil {
+		return WebhookDelivery{}, err
+	}
+	if err := attemptWebhookDelivery(ctx, s.db, s.webhooks, webhook, &delivery, now); err != nil {
+		return WebhookDelivery{}, err
+	}
+	return delivery, nil
+}
+
+func enqueueWebhookEvent(ctx context.Context, db *gorm.DB, event string, data any) error {
+	var webhooks []Webhook
+	if err := db.WithContext(ctx).Where("enabled = ?", true).Find(&webhooks).Error; err != nil {
+		return err
+	}
+
+	now := time.Now().UTC()
+	for _, webhook := range webhooks {
+		if !webhook.subscribes(event) {
+			continue
+		}
+		delivery, err := newWebhookDelivery(webhook.ID, event, data, &now)
+		if err != nil {
+			return err
+		}
+		if err := db.WithContext(ctx).Create(&delivery).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newWebhookDelivery(webhookID, event string, data any, nextAttemptAt *time.Time) (WebhookDelivery, error) {
+	delivery := WebhookDelivery{
+		ID:            generateUUIDv4(),
+		WebhookID:     webhookID,
+		Event:         event,
+		Status:        WebhookDeliveryPending,
+		NextAttemptAt: nextAttemptAt,
+	}
+	payload, err := json.Marshal(map[string]any{
+		"id":         delivery.ID,
+		"event":      event,
+		"created_at": time.Now().UTC(),
+		"data":       data,
+	})
+	if err != nil {
+		return WebhookDelivery{}, err
+	}
+	delivery.Payload = string(payload)
+	return delivery, nil
+}
+
+// deliverDueWebhooks attempts every pending delivery whose next attempt is
+// due, for enabled webhooks.
+func deliverDueWebhooks(ctx context.Context, db *gorm.DB, config webhookConfig, now time.Time) error {
+	var due []WebhookDelivery
+	if err := db.WithContext(ctx).
+		Joins("JOIN webhooks ON webhooks.id = webhook_deliveries.webhook_id").
+		Where("webhook_deliveries.status = ? AND webhook_deliveries.next_attempt_at <= ? AND webhooks.enabled = ?", WebhookDeliveryPending, now, true).
+		Order("webhook_deliveries.next_attempt_at asc").
+		Limit(100).
+		Find(&due).Error; err != nil {
+		return err
+	}
+
+	for i := range due {
+		delivery := &due[i]
+		claimed, err := claimWebhookDelivery(ctx, db, *delivery, now.Add(webhookDeliveryLease(config)))
+		if err != nil {
+			return err
+		}
+		if !claimed {
+			continue
+		}
+
+		var webhook Webhook
+		if err := db.WithContext(ctx).First(&webhook, "id = ?", delivery.WebhookID).Error; err != nil {
+			return err
+		}
+		if err := attemptWebhookDelivery(ctx, db, config, webhook, delivery, now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// claimWebhookDelivery leases delivery until the given time by moving its
+// next_attempt_at, so an overlapping run does not send it twice. A delivery
+// abandoned mid-attempt becomes due again once the lease runs out.
+func claimWebhookDelivery(ctx context.Context, db *gorm.DB, delivery WebhookDelivery, until time.Time) (bool, error) {
+	claimed := db.WithContext(ctx).Model(&WebhookDelivery{}).
+		Where("id = ? AND next_attempt_at = ?", delivery.ID, delivery.NextAttemptAt).
+		Update("next_attempt_at", until.UTC())
+	if claimed.Error != nil {
+		return false, claimed.Error
+	}
+	return claimed.RowsAffected > 0, nil
+}
+
+// webhookDeliveryLease outlasts one attempt: the client timeout plus a grace
+// period for recording the outcome.
+func webhookDeliveryLease(config webhookConfig) time.Duration {
+	if config.Client == nil || config.Client.Timeout <= 0 {
+		return defaultWebhookDeliveryLease
+	}
+	return config.Client.Timeout + webhookDeliveryLeaseGrace
+}
+
+// attemptWebhookDelivery posts delivery once and records the outcome. Non-2xx
+// responses and transport errors schedule a retry with exponential backoff
+// until MaxAttempts is reached. Only a failure to record the outcome is
+// returned.
+func attemptWebhookDelivery(ctx context.Context, db *gorm.DB, config webhookConfig, webhook Webhook, delivery *WebhookDelivery, now time.Time) error {
+	status, err := postWebhook(ctx, config.Client, webhook, *delivery)
+
+	attemptedAt := now.UTC()
+	delivery.Attempts++
+	delivery.LastAttemptAt = &attemptedAt
+	delivery.ResponseStatus = status
+	delivery.NextAttemptAt = nil
+	switch {
+	case err == nil:
+		delivery.Status = WebhookDeliverySucceeded
+		delivery.LastError = ""
+	case delivery.Attempts >= config.MaxAttempts:
+		delivery.Status = WebhookDeliveryFailed
+		delivery.LastError = err.Error()
+	default:
+		delivery.LastError = err.Error()
+		next := attemptedAt.Add(config.Backoff * time.Duration(1<<(delivery.Attempts-1)))
+		delivery.NextAttemptAt = &next
+	}
+
+	return db.WithContext(ctx).Model(&WebhookDelivery{}).Where("id = ?", delivery.ID).Updates(map[string]any{
+		"status":          delivery.Status,
+		"attempts":        delivery.Attempts,
+		"last_attempt_at": delivery.LastAttemptAt,
+		"next_attempt_at": delivery.NextAttemptAt,
+		"response_status": delivery.ResponseStatus,
+		"last_error":      delivery.LastError,
+	}).Error
+}
+
+func postWebhook(ctx context.Context, client *http.Client, webhook Webhook, delivery WebhookDelivery) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewBufferString(delivery.Payload))
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "clawtivity-webhooks")
+	req.Header.Set("X-Clawtivity-Event", delivery.Event)
+	req.Header.Set("X-Clawtivity-Delivery", delivery.ID)
+	if webhook.Secret != "" {
+		req.Header.Set(WebhookSignatureHeader, SignWebhookPayload(webhook.Secret, []byte(delivery.Payload)))
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("unexpected status %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+// SignWebhookPayload returns the X-Clawtivity-Signature value for body.
+func SignWebhookPayload(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+func normalizeWebhook(webhook *Webhook) error {
+	webhook.URL = strings.TrimSpace(webhook.URL)
+	parsed, err := url.Parse(webhook.URL)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http or https URL", ErrInvalidWebhook)
+	}
+
+	events := StringList{}
+	for _, event := range webhook.Events {
+		event = strings.ToLower(strings.TrimSpace(event))
+		if event == "" {
+			continue
+		}
+		if !isWebhookEvent(event) {
+			return fmt.Errorf("%w: unknown event %q, expected one of %s", ErrInvalidWebhook, event, strings.Join(webhookEvents, ", "))
+		}
+		events = append(events, event)
+	}
+	webhook.Events = uniqueStrings(events)
+	return nil
+}
+
+func isWebhookEvent(event string) bool {
+	for _, known := range webhookEvents {
+		if known == event {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *service) startWebhookWorker(config webhookConfig) {
+	ctx, cancel := context.WithCancel(context.Background())
+	s.webhookCancel = cancel
+
+	ticker := time.NewTicker(config.Interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if err := deliverDueWebhooks(ctx, s.db, config, time.Now().UTC()); err != nil && ctx.Err() == nil {
+					log.Printf("webhook delivery failed: %v", err)
+				}
+			}
+		}
+	}()
+}
+
+func resolveWebhookConfig() webhookConfig {
+	maxAttempts := 5
+	if raw := strings.TrimSpace(os.Getenv("CLAWTIVITY_WEBHOOK_MAX_ATTEMPTS")); raw != "" {
+		if parsed, err := strconv.Atoi(raw); err == nil && parsed > 0 {
+			maxAttempts = parsed
+		}
+	}
+
+	return webhookConfig{
+		Interval:    resolveDurationEnv("CLAWTIVITY_WEBHOOK_INTERVAL", 5*time.Second),
+		MaxAttempts: maxAttempts,
+		Backoff:     resolveDurationEnv("CLAWTIVITY_WEBHOOK_BACKOFF", 30*time.Second),
+		Client:      &http.Client{Timeout: 10 * time.Second},
+	}
+}
@@ -0,0 +1,232 @@
+package database
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+)
+
+type webhookReceiver struct {
+	mu       sync.Mutex
+	statuses []int
+	requests []*http.Request
+	bodies   []string
+}
+
+func newWebhookReceiver(t *testing.T, statuses ...int) (*webhookReceiver, *httptest.Server) {
+	t.Helper()
+
+	receiver := &webhookReceiver{statuses: statuses}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		receiver.mu.Lock()
+		defer receiver.mu.Unlock()
+		receiver.requests = append(receiver.requests, r)
+		receiver.bodies = append(receiver.bodies, string(body))
+		status := http.StatusNoContent
+		if len(receiver.statuses) > 0 {
+			status, receiver.statuses = receiver.statuses[0], receiver.statuses[1:]
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(server.Close)
+	return receiver, server
+}
+
+func TestCreateWebhookValidatesURLAndEvents(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+
+	for _, invalid := range []Webhook{
+		{URL: "ftp://example.com/hook"},
+		{URL: "/relative"},
+		{URL: "https://example.com/hook", Events: StringList{"activity.created"}},
+		{URL: "https://example.com/hook", Events: StringList{WebhookEventTest}},
+	} {
+		if err := svc.CreateWebhook(t.Context(), &invalid); !errors.Is(err, ErrInvalidWebhook) {
+			t.Fatalf("expected ErrInvalidWebhook for %#v, got %v", invalid, err)
+		}
+	}
+
+	webhook := Webhook{URL: " https://example.com/hook ", Events: StringList{"Activity.Failed", "activity.failed"}, Enabled: true, Secret: "s3cret"}
+	if err := svc.CreateWebhook(t.Context(), &webhook); err != nil {
+		t.Fatalf("expected webhook to be created: %v", err)
+	}
+	if webhook.URL != "https://example.com/hook" || len(webhook.Events) != 1 || webhook.Events[0] != WebhookEventActivityFailed {
+		t.Fatalf("expected normalized webhook, got %#v", webhook)
+	}
+	if redacted := webhook.Redacted(); redacted.Secret != "" || !redacted.HasSecret {
+		t.Fatalf("expected secret to be redacted, got %#v", redacted)
+	}
+}
+
+func TestWebhookDeliveriesAreSignedFilteredAndRetried(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	receiver, server := newWebhookReceiver(t, http.StatusInternalServerError, http.StatusInternalServerError)
+
+	subscribed := Webhook{URL: server.URL, Secret: "s3cret", Events: StringList{WebhookEventPricingRefreshFailed}, Enabled: true}
+	other := Webhook{URL: server.URL, Events: StringList{WebhookEventActivityFailed}, Enabled: true}
+	disabled := Webhook{URL: server.URL, Enabled: false}
+	for _, webhook := range []*Webhook{&subscribed, &other, &disabled} {
+		if err := svc.CreateWebhook(t.Context(), webhook); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := svc.EnqueueWebhookEvent(t.Context(), WebhookEventPricingRefreshFailed, map[string]any{"source": "openrouter"}); err != nil {
+		t.Fatalf("expected event to be queued: %v", err)
+	}
+	var queued int64
+	if err := svc.db.Model(&WebhookDelivery{}).Count(&queued).Error; err != nil {
+		t.Fatal(err)
+	}
+	if queued != 1 {
+		t.Fatalf("expected only the subscribed, enabled webhook to get a delivery, got %d", queued)
+	}
+
+	config := webhookConfig{MaxAttempts: 3, Backoff: time.Minute, Client: server.Client()}
+	now := time.Now().UTC()
+	if err := deliverDueWebhooks(t.Context(), svc.db, config, now); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := svc.ListWebhookDeliveries(t.Context(), subscribed.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	delivery := deliveries[0]
+	if delivery.Status != WebhookDeliveryPending || delivery.Attempts != 1 || delivery.ResponseStatus != 500 || delivery.NextAttemptAt == nil {
+		t.Fatalf("expected a scheduled retry, got %#v", delivery)
+	}
+	if !delivery.NextAttemptAt.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected the first retry after one backoff, got %s", delivery.NextAttemptAt)
+	}
+
+	// Not due yet, then due twice more: the second retry doubles the backoff.
+	if err := deliverDueWebhooks(t.Context(), svc.db, config, now.Add(30*time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverDueWebhooks(t.Context(), svc.db, config, now.Add(time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, _ = svc.ListWebhookDeliveries(t.Context(), subscribed.ID, 0)
+	if deliveries[0].Attempts != 2 || !deliveries[0].NextAttemptAt.Equal(now.Add(3*time.Minute)) {
+		t.Fatalf("expected a second retry after two backoffs, got %#v", deliveries[0])
+	}
+	if err := deliverDueWebhooks(t.Context(), svc.db, config, now.Add(3*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, _ = svc.ListWebhookDeliveries(t.Context(), subscribed.ID, 0)
+	if deliveries[0].Status != WebhookDeliverySucceeded || deliveries[0].Attempts != 3 || deliveries[0].NextAttemptAt != nil {
+		t.Fatalf("expected the third attempt to succeed, got %#v", deliveries[0])
+	}
+
+	receiver.mu.Lock()
+	defer receiver.mu.Unlock()
+	if len(receiver.requests) != 3 {
+		t.Fatalf("expected 3 requests, got %d", len(receiver.requests))
+	}
+	request := receiver.requests[2]
+	if request.Header.Get("X-Clawtivity-Event") != WebhookEventPricingRefreshFailed || request.Header.Get("X-Clawtivity-Delivery") != delivery.ID {
+		t.Fatalf("unexpected event headers: %v", request.Header)
+	}
+	if got, want := request.Header.Get(WebhookSignatureHeader), SignWebhookPayload("s3cret", []byte(receiver.bodies[2])); got != want {
+		t.Fatalf("expected signature %s, got %s", want, got)
+	}
+	if receiver.bodies[0] != receiver.bodies[2] {
+		t.Fatal("expected retries to resend the same body")
+	}
+}
+
+func TestAbandonedWebhookDeliveryIsRetriedAfterLease(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	receiver, server := newWebhookReceiver(t)
+	client := server.Client()
+	client.Timeout = 10 * time.Second
+	config := webhookConfig{MaxAttempts: 3, Backoff: time.Minute, Client: client}
+
+	webhook := Webhook{URL: server.URL, Enabled: true}
+	if err := svc.CreateWebhook(t.Context(), &webhook); err != nil {
+		t.Fatal(err)
+	}
+	if err := svc.EnqueueWebhookEvent(t.Context(), WebhookEventActivityFailed, map[string]any{"id": "a1"}); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, err := svc.ListWebhookDeliveries(t.Context(), webhook.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// A run that claims the delivery and dies before recording an outcome.
+	now := time.Now().UTC()
+	leasedUntil := now.Add(webhookDeliveryLease(config))
+	claimed, err := claimWebhookDelivery(t.Context(), svc.db, deliveries[0], leasedUntil)
+	if err != nil || !claimed {
+		t.Fatalf("expected the delivery to be claimed, got %v %v", claimed, err)
+	}
+
+	if err := deliverDueWebhooks(t.Context(), svc.db, config, now.Add(client.Timeout)); err != nil {
+		t.Fatal(err)
+	}
+	receiver.mu.Lock()
+	sent := len(receiver.requests)
+	receiver.mu.Unlock()
+	if sent != 0 {
+		t.Fatalf("expected the leased delivery to be left alone, got %d requests", sent)
+	}
+
+	if err := deliverDueWebhooks(t.Context(), svc.db, config, leasedUntil); err != nil {
+		t.Fatal(err)
+	}
+	deliveries, _ = svc.ListWebhookDeliveries(t.Context(), webhook.ID, 0)
+	if deliveries[0].Status != WebhookDeliverySucceeded || deliveries[0].Attempts != 1 {
+		t.Fatalf("expected the abandoned delivery to be retried once the lease ran out, got %#v", deliveries[0])
+	}
+}
+
+func TestWebhookDeliveryFailsAfterMaxAttempts(t *testing.T) {
+	svc := newActivityQueryTestService(t)
+	_, server := newWebhookReceiver(t, http.StatusBadGateway, http.StatusBadGateway)
+	svc.webhooks = webhookConfig{MaxAttempts: 2, Backoff: time.Minute, Client: server.Client()}
+
+	webhook := Webhook{URL: server.URL}
+	if err := svc.CreateWebhook(t.Context(), &webhook); err != nil {
+		t.Fatal(err)
+	}
+
+	delivery, err := svc.TestWebhook(t.Context(), webhook.ID)
+	if err != nil {
+		t.Fatalf("expected test delivery to be attempted: %v", err)
+	}
+	if delivery.Event != WebhookEventTest || delivery.Attempts != 1 || delivery.ResponseStatus != http.StatusBadGateway || delivery.NextAttemptAt == nil {
+		t.Fatalf("expected a failed first attempt with a retry, got %#v", delivery)
+	}
+
+	// The webhook is disabled, so the worker leaves the retry alone until it
+	// is enabled again.
+	later := delivery.NextAttemptAt.Add(time.Second)
+	if err := deliverDueWebhooks(t.Context(), svc.db, svc.webhooks, later); err != nil {
+		t.Fatal(err)
+	}
+	enabled := true
+	if _, err := svc.UpdateWebhook(t.Context(), webhook.ID, WebhookUpdate{Enabled: &enabled}); err != nil {
+		t.Fatal(err)
+	}
+	if err := deliverDueWebhooks(t.Context(), svc.db, svc.webhooks, later); err != nil {
+		t.Fatal(err)
+	}
+
+	deliveries, err := svc.ListWebhookDeliveries(t.Context(), webhook.ID, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if deliveries[0].Status != WebhookDeliveryFailed || deliveries[0].Attempts != 2 || deliveries[0].NextAttemptAt != nil || deliveries[0].LastError == "" {
+		t.Fatalf("expected the delivery to give up after 2 attempts, got %#v", deliveries[0])
+	}
+
+	if _, err := svc.TestWebhook(t.Context(), "missing"); !errors.Is(err, ErrWebhookNotFound) {
+		t.Fatalf("expected ErrWebhookNotFound, got %v", err)
+	}
+}
@@ -0,0 +1,262 @@
+// Package metrics implements the small subset of the Prometheus text
+// exposition format Clawtivity needs: labelled counters, histograms and
+// scrape-time gauges, without pulling in the client library.
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// DefaultBuckets are latency buckets in seconds, matching the Prometheus
+// client defaults.
+var DefaultBuckets = []float64{0.005, 0.01, 0.025, 0.05, 0.1, 0.25, 0.5, 1, 2.5, 5, 10}
+
+// Default is the process-wide registry served on /metrics.
+var Default = NewRegistry()
+
+type collector interface {
+	name() string
+	write(w io.Writer)
+}
+
+// Registry holds metric families and writes them in name order.
+type Registry struct {
+	mu         sync.Mutex
+	collectors map[string]collector
+}
+
+func NewRegistry() *Registry {
+	return &Registry{collectors: map[string]collector{}}
+}
+
+func (r *Registry) register(c collector) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if _, exists := r.collectors[c.name()]; exists {
+		panic("metrics: duplicate registration of " + c.name())
+	}
+	r.collectors[c.name()] = c
+}
+
+// Write writes every registered family in text exposition format.
+func (r *Registry) Write(w io.Writer) {
+	r.mu.Lock()
+	names := make([]string, 0, len(r.collectors))
+	for name := range r.collectors {
+		names = append(names, name)
+	}
+	collectors := make([]collector, 0, len(names))
+	sort.Strings(names)
+	for _, name := range names {
+		collectors = append(collectors, r.collectors[name])
+	}
+	r.mu.Unlock()
+
+	for _, c := range collectors {
+		c.write(w)
+	}
+}
+
+type series struct {
+	labels []string
+	value  float64
+}
+
+// CounterVec is a monotonically increasing counter partitioned by labels.
+type CounterVec struct {
+	metricName string
+	help       string
+	labelNames []string
+
+	mu     sync.Mutex
+	series map[string]*series
+}
+
+func (r *Registry) NewCounterVec(name, help string, labelNames ...string) *CounterVec {
+	c := &CounterVec{metricName: name, help: help, labelNames: labelNames, series: map[string]*series{}}
+	r.register(c)
+	return c
+}
+
+// Add increases the series identified by labelValues by delta.
+func (c *CounterVec) Add(delta float64, labelValues ...string) {
+	key := seriesKey(c.labelNames, labelValues)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.series[key]
+	if !ok {
+		s = &series{labels: append([]string(nil), labelValues...)}
+		c.series[key] = s
+	}
+	s.value += delta
+}
+
+func (c *CounterVec) Inc(labelValues ...string) {
+	c.Add(1, labelValues...)
+}
+
+func (c *CounterVec) name() string { return c.metricName }
+
+func (c *CounterVec) write(w io.Writer) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	writeHeader(w, c.metricName, c.help, "counter")
+	for _, key := range sortedKeys(c.series) {
+		s := c.series[key]
+		writeSample(w, c.metricName, c.labelNames, s.labels, s.value)
+	}
+}
+
+type histogramSeries struct {
+	labels []string
+	counts []uint64
+	count  uint64
+	sum    float64
+}
+
+// HistogramVec tracks observations in cumulative buckets partitioned by labels.
+type HistogramVec struct {
+	metricName string
+	help       string
+	labelNames []string
+	buckets    []float64
+
+	mu     sync.Mutex
+	series map[string]*histogramSeries
+}
+
+func (r *Registry) NewHistogramVec(name, help string, buckets []float64, labelNames ...string) *HistogramVec {
+	sorted := append([]float64(nil), buckets...)
+	sort.Float64s(sorted)
+	h := &HistogramVec{metricName: name, help: help, labelNames: labelNames, buckets: sorted, series: map[string]*histogramSeries{}}
+	r.register(h)
+	return h
+}
+
+func (h *HistogramVec) Observe(value float64, labelValues ...string) {
+	key := seriesKey(h.labelNames, labelValues)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s, ok := h.series[key]
+	if !ok {
+		s = &histogramSeries{labels: append([]string(nil), labelValues...), counts: make([]uint64, len(h.buckets))}
+		h.series[key] = s
+	}
+	for i, upper := range h.buckets {
+		if value <= upper {
+			s.counts[i]++
+		}
+	}
+	s.count++
+	s.sum += value
+}
+
+func (h *HistogramVec) name() string { return h.metricName }
+
+func (h *HistogramVec) write(w io.Writer) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	writeHeader(w, h.metricName, h.help, "histogram")
+	bucketLabels := append(append([]string(nil), h.labelNames...), "le")
+	for _, key := range sortedKeys(h.series) {
+		s := h.series[key]
+		for i, upper := range h.buckets {
+			writeSample(w, h.metricName+"_bucket", bucketLabels, append(append([]string(nil), s.labels...), formatFloat(upper)), float64(s.counts[i]))
+		}
+		writeSample(w, h.metricName+"_bucket", bucketLabels, append(append([]string(nil), s.labels...), "+Inf"), float64(s.count))
+		writeSample(w, h.metricName+"_sum", h.labelNames, s.labels, s.sum)
+		writeSample(w, h.metricName+"_count", h.labelNames, s.labels, float64(s.count))
+	}
+}
+
+// Sample is one series of a family written at scrape time.
+type Sample struct {
+	Labels []string
+	Value  float64
+}
+
+// WriteFamily writes a family whose samples are computed at scrape time, such
+// as gauges read from the database. kind is "gauge" or "counter".
+func WriteFamily(w io.Writer, name, help, kind string, labelNames []string, samples []Sample) {
+	writeHeader(w, name, help, kind)
+	for _, sample := range samples {
+		writeSample(w, name, labelNames, sample.Labels, sample.Value)
+	}
+}
+
+func writeHeader(w io.Writer, name, help, kind string) {
+	fmt.Fprintf(w, "# HELP %s %s\n", name, escapeHelp(help))
+	fmt.Fprintf(w, "# TYPE %s %s\n", name, kind)
+}
+
+func writeSample(w io.Writer, name string, labelNames, labelValues []string, value float64) {
+	var b strings.Builder
+	b.WriteString(name)
+	if len(labelNames) > 0 {
+		b.WriteByte('{')
+		for i, label := range labelNames {
+			if i > 0 {
+				b.WriteByte(',')
+			}
+			labelValue := ""
+			if i < len(labelValues) {
+				labelValue = labelValues[i]
+			}
+			b.WriteString(label)
+			b.WriteString(`="`)
+			b.WriteString(escapeLabelValue(labelValue))
+			b.WriteByte('"')
+		}
+		b.WriteByte('}')
+	}
+	b.WriteByte(' ')
+	b.WriteString(formatFloat(value))
+	b.WriteByte('\n')
+	_, _ = io.WriteString(w, b.String())
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+var labelValueEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`, `"`, `\"`)
+
+func escapeLabelValue(value string) string {
+	return labelValueEscaper.Replace(value)
+}
+
+var helpEscaper = strings.NewReplacer(`\`, `\\`, "\n", `\n`)
+
+func escapeHelp(help string) string {
+	return helpEscaper.Replace(help)
+}
+
+func seriesKey(labelNames, labelValues []string) string {
+	if len(labelValues) != len(labelNames) {
+		panic(fmt.Sprintf("metrics: expected %d label values, got %d", len(labelNames), len(labelValues)))
+	}
+	return strings.Join(labelValues, "\xff")
+}
+
+func sortedKeys[T any](m map[string]T) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
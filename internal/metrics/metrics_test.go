@@ -0,0 +1,51 @@
+package metrics
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRegistryWritesCountersAndHistogramsInTextFormat(t *testing.T) {
+	registry := NewRegistry()
+	requests := registry.NewCounterVec("test_requests_total", "Requests served.", "route")
+	latency := registry.NewHistogramVec("test_latency_seconds", "Request latency.", []float64{0.1, 1}, "route")
+
+	requests.Inc("/api/activity")
+	requests.Add(2, `/api/"quoted"`)
+	latency.Observe(0.05, "/api/activity")
+	latency.Observe(0.5, "/api/activity")
+	latency.Observe(3, "/api/activity")
+
+	var out strings.Builder
+	registry.Write(&out)
+	got := out.String()
+
+	for _, want := range []string{
+		"# TYPE test_latency_seconds histogram\n",
+		`test_latency_seconds_bucket{route="/api/activity",le="0.1"} 1`,
+		`test_latency_seconds_bucket{route="/api/activity",le="1"} 2`,
+		`test_latency_seconds_bucket{route="/api/activity",le="+Inf"} 3`,
+		`test_latency_seconds_sum{route="/api/activity"} 3.55`,
+		`test_latency_seconds_count{route="/api/activity"} 3`,
+		"# HELP test_requests_total Requests served.\n# TYPE test_requests_total counter\n",
+		`test_requests_total{route="/api/activity"} 1`,
+		`test_requests_total{route="/api/\"quoted\""} 2`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected output to contain %q, got:\n%s", want, got)
+		}
+	}
+	if strings.Index(got, "test_latency_seconds") > strings.Index(got, "test_requests_total") {
+		t.Fatalf("expected families in name order, got:\n%s", got)
+	}
+}
+
+func TestWriteFamilyWritesScrapeTimeGauges(t *testing.T) {
+	var out strings.Builder
+	WriteFamily(&out, "test_queue_depth", "Queued items.", "gauge", nil, []Sample{{Value: 4}})
+
+	want := "# HELP test_queue_depth Queued items.\n# TYPE test_queue_depth gauge\ntest_queue_depth 4\n"
+	if out.String() != want {
+		t.Fatalf("expected %q, got %q", want, out.String())
+	}
+}
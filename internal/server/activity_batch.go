@@ -0,0 +1,255 @@
+package server
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+const maxActivityBatchSize = 500
+
+// maxActivityBatchBytes caps the request body so the item limit cannot be
+// bypassed by a few huge payloads; the body is read whole before splitting.
+const maxActivityBatchBytes = 16 << 20
+
+var errEmptyActivityBatch = errors.New("batch must contain at least one activity")
+
+type activityBatchItemResult struct {
+	Index  int    `json:"index"`
+	Status string `json:"status"`
+	ID     string `json:"id,omitempty"`
+	Error  string `json:"error,omitempty"`
+}
+
+type activityBatchResponse struct {
+	Created    int                       `json:"created"`
+	Duplicates int                       `json:"duplicates"`
+	Failed     int                       `json:"failed"`
+	Results    []activityBatchItemResult `json:"results"`
+}
+
+// createActivityBatchHandler godoc
+// @Summary Create activities in bulk
+// @Description Create many activity entries from a JSON array or NDJSON stream. Valid items are inserted in one transaction; per-item results report created IDs, duplicates of existing idempotency keys, or validation errors.
+// @Tags activities
+// @Accept json
+// @Accept application/x-ndjson
+// @Produce json
+// @Param activities body []database.ActivityFeed true "Activity payloads"
+// @Success 201 {object} activityBatchResponse
+// @Success 207 {object} activityBatchResponse
+// @Failure 400 {object} activityBatchResponse
+// @Failure 413 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/activity/batch [post]
+func (s *Server) createActivityBatchHandler(c *gin.Context) {
+	defer observeIngestDuration("batch", time.Now())
+
+	body, err := io.ReadAll(http.MaxBytesReader(c.Writer, c.Request.Body, maxActivityBatchBytes))
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		c.JSON(http.StatusRequestEntityTooLarge, gin.H{"error": fmt.Sprintf("batch body exceeds maximum of %d bytes", maxActivityBatchBytes)})
+		return
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var items []json.RawMessage
+	if isNDJSONContentType(c.ContentType()) {
+		items, err = splitNDJSON(body)
+	} else {
+		items, err = splitJSONArray(body)
+	}
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(items) > maxActivityBatchSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("batch exceeds maximum of %d activities", maxActivityBatchSize)})
+		return
+	}
+
+	response := activityBatchResponse{Results: make([]activityBatchItemResult, len(items))}
+	pending := make([]*database.ActivityFeed, 0, len(items))
+	pendingIndexes := make([]int, 0, len(items))
+	pendingByKey := map[string]*database.ActivityFeed{}
+	repeatedInBatch := map[int]*database.ActivityFeed{}
+	for index, raw := range items {
+		response.Results[index] = activityBatchItemResult{Index: index}
+
+		var input activityIngest
+		if err := json.Unmarshal(raw, &input); err != nil {
+			response.Results[index].Status = "failed"
+			response.Results[index].Error = err.Error()
+			continue
+		}
+		if err := validateBatchActivity(input.ActivityFeed); err != nil {
+			response.Results[index].Status = "failed"
+			response.Results[index].Error = err.Error()
+			continue
+		}
+		if err := prepareActivityIngest(c.Request.Context(), s.db, s.classifierSignals, &input); err != nil {
+			response.Results[index].Status = "failed"
+			response.Results[index].Error = "failed to resolve project"
+			continue
+		}
+
+		if key := input.ActivityFeed.IdempotencyKey; key != nil {
+			if first, seen := pendingByKey[*key]; seen {
+				response.Results[index].Status = "duplicate"
+				repeatedInBatch[index] = first
+				continue
+			}
+			existing, found, err := s.db.FindActivityByIdempotencyKey(c.Request.Context(), *key)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create activities"})
+				return
+			}
+			if found {
+				response.Results[index].Status = "duplicate"
+				response.Results[index].ID = existing.ID
+				response.Duplicates++
+				continue
+			}
+		}
+
+		activity := input.ActivityFeed
+		if key := activity.IdempotencyKey; key != nil {
+			pendingByKey[*key] = &activity
+		}
+		pending = append(pending, &activity)
+		pendingIndexes = append(pendingIndexes, index)
+	}
+
+	for {
+		err := s.db.CreateActivities(c.Request.Context(), pending)
+		if err == nil {
+			break
+		}
+		if !errors.Is(err, database.ErrDuplicateActivity) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create activities"})
+			return
+		}
+		// Another writer stored some of these keys after the lookups above;
+		// report those items as duplicates and insert the remainder.
+		remaining, remainingIndexes, err := s.dropStoredActivities(c.Request.Context(), pending, pendingIndexes, &response)
+		if err != nil || len(remaining) == len(pending) {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create activities"})
+			return
+		}
+		pending, pendingIndexes = remaining, remainingIndexes
+	}
+
+	for i, activity := range pending {
+		index := pendingIndexes[i]
+		response.Results[index].Status = "created"
+		response.Results[index].ID = activity.ID
+		incActivitiesCreated()
+	}
+	for index, first := range repeatedInBatch {
+		response.Results[index].ID = first.ID
+		response.Duplicates++
+	}
+	response.Created = len(pending)
+	activitiesStored(c.Request.Context(), s.db, pending...)
+	response.Failed = len(items) - len(pending) - response.Duplicates
+
+	logEvent("info", "api_ingest_batch", map[string]any{
+		"received":   len(items),
+		"created":    response.Created,
+		"failed":     response.Failed,
+		"duplicates": response.Duplicates,
+		"queue_root": resolveQueueDir(),
+	}, currentQueueDepth())
+
+	status := http.StatusCreated
+	switch {
+	case response.Failed > 0 && response.Created+response.Duplicates == 0:
+		status = http.StatusBadRequest
+	case response.Failed > 0:
+		status = http.StatusMultiStatus
+	case response.Created == 0:
+		status = http.StatusOK
+	}
+	c.JSON(status, response)
+}
+
+// dropStoredActivities removes pending activities whose idempotency key is
+// already stored, marking their batch items as duplicates of the stored row.
+func (s *Server) dropStoredActivities(ctx context.Context, pending []*database.ActivityFeed, indexes []int, response *activityBatchResponse) ([]*database.ActivityFeed, []int, error) {
+	remaining := make([]*database.ActivityFeed, 0, len(pending))
+	remainingIndexes := make([]int, 0, len(pending))
+	for i, activity := range pending {
+		if activity.IdempotencyKey != nil {
+			existing, found, err := s.db.FindActivityByIdempotencyKey(ctx, *activity.IdempotencyKey)
+			if err != nil {
+				return nil, nil, err
+			}
+			if found {
+				// Repeats of this item later in the batch pick up the stored ID.
+				*activity = existing
+				response.Results[indexes[i]].Status = "duplicate"
+				response.Results[indexes[i]].ID = existing.ID
+				response.Duplicates++
+				continue
+			}
+		}
+		remaining = append(remaining, activity)
+		remainingIndexes = append(remainingIndexes, indexes[i])
+	}
+	return remaining, remainingIndexes, nil
+}
+
+func isNDJSONContentType(contentType string) bool {
+	value := strings.ToLower(strings.TrimSpace(contentType))
+	return strings.Contains(value, "ndjson") || strings.Contains(value, "jsonl") || strings.Contains(value, "json-seq")
+}
+
+func splitJSONArray(body []byte) ([]json.RawMessage, error) {
+	trimmed := bytes.TrimSpace(body)
+	if len(trimmed) == 0 {
+		return nil, errEmptyActivityBatch
+	}
+
+	var items []json.RawMessage
+	if err := json.Unmarshal(trimmed, &items); err != nil {
+		return nil, fmt.Errorf("expected a JSON array of activities: %w", err)
+	}
+	if len(items) == 0 {
+		return nil, errEmptyActivityBatch
+	}
+	return items, nil
+}
+
+func splitNDJSON(body []byte) ([]json.RawMessage, error) {
+	scanner := bufio.NewScanner(bytes.NewReader(body))
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+
+	var items []json.RawMessage
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+		items = append(items, json.RawMessage(append([]byte(nil), line...)))
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if len(items) == 0 {
+		return nil, errEmptyActivityBatch
+	}
+	return items, nil
+}
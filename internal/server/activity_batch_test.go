@@ -0,0 +1,183 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestPostActivityBatchCreatesAllEntries(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	body := `[
+		{"session_key":"batch-1","model":"gpt-5","tokens_in":10,"tokens_out":5,"project_tag":"proj-alpha","channel":"webchat","user_id":"u1"},
+		{"session_key":"batch-2","model":"gpt-5","tokens_in":20,"tokens_out":8,"project_tag":"proj-beta","channel":"webchat","user_id":"u1","prompt_text":"please research and compare options"}
+	]`
+
+	rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/json", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	got := decodeBatchResponse(t, rr)
+	if got.Created != 2 || got.Failed != 0 {
+		t.Fatalf("expected created=2 failed=0, got created=%d failed=%d", got.Created, got.Failed)
+	}
+	for i, result := range got.Results {
+		if result.Index != i {
+			t.Fatalf("expected result index %d, got %d", i, result.Index)
+		}
+		if result.Status != "created" || result.ID == "" {
+			t.Fatalf("expected created result with id, got %#v", result)
+		}
+	}
+
+	activities := listActivities(t, handler, "/api/activity")
+	if len(activities) != 2 {
+		t.Fatalf("expected 2 activities, got %d", len(activities))
+	}
+}
+
+func TestPostActivityBatchReportsPartialFailures(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	body := `[
+		{"session_key":"batch-ok","model":"gpt-5","tokens_in":10,"tokens_out":5,"project_tag":"proj-alpha"},
+		{"session_key":"batch-bad-type","tokens_in":"lots"},
+		{"session_key":"batch-negative","tokens_in":-4}
+	]`
+
+	rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/json", body)
+	if rr.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusMultiStatus, rr.Code, rr.Body.String())
+	}
+
+	got := decodeBatchResponse(t, rr)
+	if got.Created != 1 || got.Failed != 2 {
+		t.Fatalf("expected created=1 failed=2, got created=%d failed=%d", got.Created, got.Failed)
+	}
+	if got.Results[0].Status != "created" || got.Results[0].ID == "" {
+		t.Fatalf("expected first item created, got %#v", got.Results[0])
+	}
+	for _, index := range []int{1, 2} {
+		if got.Results[index].Status != "failed" || got.Results[index].Error == "" {
+			t.Fatalf("expected item %d to fail with error, got %#v", index, got.Results[index])
+		}
+	}
+
+	activities := listActivities(t, handler, "/api/activity")
+	if len(activities) != 1 || activities[0].SessionKey != "batch-ok" {
+		t.Fatalf("expected only batch-ok to be stored, got %#v", activities)
+	}
+}
+
+func TestPostActivityBatchAcceptsNDJSON(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	body := strings.Join([]string{
+		`{"session_key":"nd-1","model":"gpt-5","project_tag":"proj-alpha"}`,
+		``,
+		`{"session_key":"nd-2","model":"gpt-5","project_tag":"proj-alpha"}`,
+		`not-json`,
+	}, "\n")
+
+	rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/x-ndjson", body)
+	if rr.Code != http.StatusMultiStatus {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusMultiStatus, rr.Code, rr.Body.String())
+	}
+
+	got := decodeBatchResponse(t, rr)
+	if got.Created != 2 || got.Failed != 1 {
+		t.Fatalf("expected created=2 failed=1, got created=%d failed=%d", got.Created, got.Failed)
+	}
+	if got.Results[2].Status != "failed" {
+		t.Fatalf("expected malformed line to fail, got %#v", got.Results[2])
+	}
+}
+
+func TestPostActivityBatchRejectsEmptyAndMalformedBodies(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for _, body := range []string{"", "[]", `{"session_key":"not-an-array"}`} {
+		rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/json", body)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for body %q, got %d body=%s", http.StatusBadRequest, body, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestPostActivityBatchRejectsOversizedBody(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	line := `{"session_key":"big","model":"gpt-5","project_tag":"clawtivity","channel":"webchat","user_id":"u1"}` + "\n"
+	body := strings.Repeat(line, maxActivityBatchBytes/len(line)+1)
+	rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/x-ndjson", body)
+	if rr.Code != http.StatusRequestEntityTooLarge {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusRequestEntityTooLarge, rr.Code, rr.Body.String())
+	}
+}
+
+func TestPostActivityBatchRequiresAPIKeyWhenConfigured(t *testing.T) {
+	t.Setenv("CLAWTIVITY_API_KEY", "secret-123")
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/json", `[{"session_key":"s"}]`)
+	if rr.Code != http.StatusUnauthorized {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusUnauthorized, rr.Code, rr.Body.String())
+	}
+}
+
+func performRaw(t *testing.T, handler http.Handler, method, path, contentType, body string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	req, err := http.NewRequest(method, path, bytes.NewBufferString(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	return rr
+}
+
+func decodeBatchResponse(t *testing.T, rr *httptest.ResponseRecorder) activityBatchResponse {
+	t.Helper()
+
+	var got activityBatchResponse
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	return got
+}
+
+func listActivities(t *testing.T, handler http.Handler, path string) []database.ActivityFeed {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var got []database.ActivityFeed
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	return got
+}
@@ -0,0 +1,86 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestGetActivityAcceptsRepeatedAndCommaSeparatedFilters(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for _, payload := range []map[string]any{
+		{"session_key": "filter-1", "model": "gpt-5", "project_tag": "proj-alpha", "channel": "slack", "status": "success", "tokens_in": 100, "tokens_out": 20, "created_at": "2026-02-18T10:00:00Z"},
+		{"session_key": "filter-2", "model": "gpt-5", "project_tag": "proj-beta", "channel": "telegram", "status": "failed", "tokens_in": 5, "tokens_out": 5, "created_at": "2026-02-18T11:00:00Z"},
+		{"session_key": "filter-3", "model": "gpt-5", "project_tag": "proj-gamma", "channel": "webchat", "status": "success", "tokens_in": 50, "tokens_out": 50, "created_at": "2026-02-18T12:00:00Z"},
+	} {
+		createActivity(t, handler, payload)
+	}
+
+	got := listActivities(t, handler, "/api/activity?project=proj-alpha&project=proj-beta&channel=slack,telegram&min_tokens=20")
+	if len(got) != 1 || got[0].SessionKey != "filter-1" {
+		t.Fatalf("expected only filter-1, got %#v", got)
+	}
+
+	got = listActivities(t, handler, "/api/activity?status=success&from=2026-02-18T11:00:00Z&to=2026-02-19T00:00:00Z")
+	if len(got) != 1 || got[0].SessionKey != "filter-3" {
+		t.Fatalf("expected only filter-3, got %#v", got)
+	}
+}
+
+func TestGetActivityRejectsMalformedFilters(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for _, path := range []string{
+		"/api/activity?min_cost=cheap",
+		"/api/activity?max_tokens=1.5",
+		"/api/activity?reasoning=maybe",
+		"/api/activity?from=2026-02-18",
+		"/api/activity/summary?from=2026-02-19T00:00:00Z&to=2026-02-18T00:00:00Z",
+		"/api/projects?include_stats=true&min_cost=cheap",
+	} {
+		rr := performRaw(t, handler, http.MethodGet, path, "", "")
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for %s, got %d body=%s", http.StatusBadRequest, path, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestGetActivitySummaryGroupsByRequestedDimensions(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for _, payload := range []map[string]any{
+		{"session_key": "group-1", "model": "gpt-5", "project_tag": "proj-alpha", "tokens_in": 10, "created_at": "2026-02-18T10:00:00Z"},
+		{"session_key": "group-2", "model": "gpt-5", "project_tag": "proj-alpha", "tokens_in": 20, "created_at": "2026-02-18T11:00:00Z"},
+		{"session_key": "group-3", "model": "gpt-5", "project_tag": "proj-beta", "tokens_in": 30, "created_at": "2026-02-19T11:00:00Z"},
+	} {
+		createActivity(t, handler, payload)
+	}
+
+	rr := performRaw(t, handler, http.MethodGet, "/api/activity/summary?group_by=project,day", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var got database.ActivityGroupSummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &got); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	if len(got.Groups) != 2 {
+		t.Fatalf("expected 2 groups, got %#v", got.Groups)
+	}
+	first := got.Groups[0]
+	if first.Keys["project"] != "proj-alpha" || first.Keys["day"] != "2026-02-18" || first.Count != 2 || first.TokensInTotal != 30 {
+		t.Fatalf("expected proj-alpha 2026-02-18 with 2 rows, got %#v", first)
+	}
+
+	bad := performRaw(t, handler, http.MethodGet, "/api/activity/summary?group_by=cost", "", "")
+	if bad.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, bad.Code, bad.Body.String())
+	}
+}
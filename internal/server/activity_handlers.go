@@ -2,10 +2,12 @@ package server
 
 import (
 	"errors"
+	"fmt"
 	"net/http"
+	"strconv"
 	"strings"
+	"time"
 
-	"clawtivity/internal/classifier"
 	"clawtivity/internal/database"
 	"github.com/gin-gonic/gin"
 )
@@ -21,37 +23,49 @@ type APIError struct {
 // @Accept json
 // @Produce json
 // @Param activity body database.ActivityFeed true "Activity data"
+// @Param Idempotency-Key header string false "Deduplicates retries; defaults to a key derived from session_key, external_ref and created_at"
+// @Success 200 {object} database.ActivityFeed "Existing activity for a repeated idempotency key"
 // @Success 201 {object} database.ActivityFeed
 // @Failure 400 {object} APIError
 // @Failure 500 {object} APIError
 // @Router /api/activity [post]
 func (s *Server) createActivityHandler(c *gin.Context) {
+	defer observeIngestDuration("single", time.Now())
+
 	var input activityIngest
 	if err := c.ShouldBindJSON(&input); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
+	if key := strings.TrimSpace(c.GetHeader("Idempotency-Key")); key != "" {
+		input.IdempotencyKey = &key
+	}
 
-	// Always generate a fresh ID server-side.
-	input.ActivityFeed.ID = ""
-	normalizeActivity(&input.ActivityFeed)
-	applyProjectAssociation(&input.ActivityFeed, input.PromptText, input.AssistantText)
-	applyActivityClassification(&input.ActivityFeed, classifier.Signals{
-		PromptText:    input.PromptText,
-		AssistantText: input.AssistantText,
-		ToolsUsed:     input.ToolsUsed,
-	})
-	if err := ensureProjectRegistry(c.Request.Context(), s.db, &input.ActivityFeed); err != nil {
+	if err := validateIdempotencyKey(input.ActivityFeed); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := prepareActivityIngest(c.Request.Context(), s.db, s.classifierSignals, &input); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to resolve project"})
 		return
 	}
 
 	if err := s.db.CreateActivity(c.Request.Context(), &input.ActivityFeed); err != nil {
+		if errors.Is(err, database.ErrDuplicateActivity) {
+			logEvent("info", "api_ingest_duplicate", map[string]any{
+				"session_key": input.ActivityFeed.SessionKey,
+				"activity_id": input.ActivityFeed.ID,
+			}, currentQueueDepth())
+			c.JSON(http.StatusOK, input.ActivityFeed)
+			return
+		}
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create activity"})
 		return
 	}
 
 	incActivitiesCreated()
+	activitiesStored(c.Request.Context(), s.db, &input.ActivityFeed)
 	queueDepth := currentQueueDepth()
 	logEvent("info", "api_ingest", map[string]any{
 		"session_key":    input.ActivityFeed.SessionKey,
@@ -67,26 +81,59 @@ func (s *Server) createActivityHandler(c *gin.Context) {
 
 // listActivitiesHandler godoc
 // @Summary List activities
-// @Description List activity entries with optional filters.
+// @Description List activity entries with optional filters. Passing limit or cursor switches the response to a paginated envelope with next_cursor.
 // @Tags activities
 // @Produce json
-// @Param project query string false "Filter by project_tag"
-// @Param model query string false "Filter by model"
+// @Param project query []string false "Filter by project_tag (repeated or comma-separated)"
+// @Param model query []string false "Filter by model (repeated or comma-separated)"
 // @Param date query string false "Filter by created_at date (YYYY-MM-DD)"
+// @Param user_id query []string false "Filter by user_id (repeated or comma-separated)"
+// @Param channel query []string false "Filter by channel (repeated or comma-separated)"
+// @Param status query []string false "Filter by status (repeated or comma-separated)"
+// @Param category query []string false "Filter by category (repeated or comma-separated)"
+// @Param session_key query []string false "Filter by session_key (repeated or comma-separated)"
+// @Param thinking query []string false "Filter by thinking level (repeated or comma-separated)"
+// @Param reasoning query bool false "Filter by reasoning flag"
+// @Param from query string false "Inclusive lower bound on created_at (RFC3339)"
+// @Param to query string false "Exclusive upper bound on created_at (RFC3339)"
+// @Param min_cost query number false "Minimum cost_estimate"
+// @Param max_cost query number false "Maximum cost_estimate"
+// @Param min_tokens query int false "Minimum tokens_in + tokens_out"
+// @Param max_tokens query int false "Maximum tokens_in + tokens_out"
+// @Param limit query int false "Page size (1-1000, default 100 when paginating)"
+// @Param cursor query string false "Opaque cursor from a previous page's next_cursor"
+// @Param sort query string false "Sort order: -created_at (default) or created_at"
+// @Param currency query string false "Convert each row's cost_estimate to this ISO 4217 code at the rate in effect at its created_at"
 // @Success 200 {array} database.ActivityFeed
+// @Success 200 {object} database.ActivityPage "When limit or cursor is set"
 // @Failure 400 {object} APIError
 // @Failure 500 {object} APIError
 // @Router /api/activity [get]
 func (s *Server) listActivitiesHandler(c *gin.Context) {
-	filters := database.ActivityFilters{
-		ProjectTag: c.Query("project"),
-		Model:      c.Query("model"),
-		Date:       c.Query("date"),
+	filters, err := activityFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	paginated := c.Query("limit") != "" || c.Query("cursor") != ""
+	if paginated {
+		page, err := s.db.ListActivityPage(c.Request.Context(), filters)
+		if err != nil {
+			if isActivityQueryError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to query activities"})
+			return
+		}
+		c.JSON(http.StatusOK, page)
+		return
 	}
 
 	activities, err := s.db.ListActivities(c.Request.Context(), filters)
 	if err != nil {
-		if errors.Is(err, database.ErrInvalidDateFilter) {
+		if isActivityQueryError(err) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -99,26 +146,56 @@ func (s *Server) listActivitiesHandler(c *gin.Context) {
 
 // activitySummaryHandler godoc
 // @Summary Get activity summary
-// @Description Get aggregated activity stats with optional filters.
+// @Description Get aggregated activity stats with optional filters. Passing group_by returns one row per combination of the requested dimensions instead of a single total.
 // @Tags activities
 // @Produce json
-// @Param project query string false "Filter by project_tag"
-// @Param model query string false "Filter by model"
+// @Param project query []string false "Filter by project_tag (repeated or comma-separated)"
+// @Param model query []string false "Filter by model (repeated or comma-separated)"
 // @Param date query string false "Filter by created_at date (YYYY-MM-DD)"
+// @Param user_id query []string false "Filter by user_id (repeated or comma-separated)"
+// @Param channel query []string false "Filter by channel (repeated or comma-separated)"
+// @Param status query []string false "Filter by status (repeated or comma-separated)"
+// @Param category query []string false "Filter by category (repeated or comma-separated)"
+// @Param session_key query []string false "Filter by session_key (repeated or comma-separated)"
+// @Param thinking query []string false "Filter by thinking level (repeated or comma-separated)"
+// @Param reasoning query bool false "Filter by reasoning flag"
+// @Param from query string false "Inclusive lower bound on created_at (RFC3339)"
+// @Param to query string false "Exclusive upper bound on created_at (RFC3339)"
+// @Param min_cost query number false "Minimum cost_estimate"
+// @Param max_cost query number false "Maximum cost_estimate"
+// @Param min_tokens query int false "Minimum tokens_in + tokens_out"
+// @Param max_tokens query int false "Maximum tokens_in + tokens_out"
+// @Param group_by query []string false "Dimensions: project, model, category, channel, user_id, status and one of hour, day, week, month"
+// @Param currency query string false "Report cost_total in this ISO 4217 code (default USD), converting each activity at the rate in effect at its created_at"
 // @Success 200 {object} database.ActivitySummary
+// @Success 200 {object} database.ActivityGroupSummary "When group_by is set"
 // @Failure 400 {object} APIError
 // @Failure 500 {object} APIError
 // @Router /api/activity/summary [get]
 func (s *Server) activitySummaryHandler(c *gin.Context) {
-	filters := database.ActivityFilters{
-		ProjectTag: c.Query("project"),
-		Model:      c.Query("model"),
-		Date:       c.Query("date"),
+	filters, err := activityFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if groupBy := queryList(c, "group_by"); len(groupBy) > 0 {
+		groups, err := s.db.SummarizeActivityGroups(c.Request.Context(), filters, groupBy)
+		if err != nil {
+			if isActivityQueryError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to summarize activities"})
+			return
+		}
+		c.JSON(http.StatusOK, groups)
+		return
 	}
 
 	summary, err := s.db.SummarizeActivities(c.Request.Context(), filters)
 	if err != nil {
-		if errors.Is(err, database.ErrInvalidDateFilter) {
+		if isActivityQueryError(err) {
 			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
@@ -131,12 +208,15 @@ func (s *Server) activitySummaryHandler(c *gin.Context) {
 
 // listProjectsHandler godoc
 // @Summary List projects
-// @Description List known projects with optional status filter and aggregated stats.
+// @Description List known projects with optional status filter and aggregated stats. With include_stats, the activity filters from /api/activity narrow which activities are aggregated.
 // @Tags projects
 // @Produce json
 // @Param status query string false "Filter by project status (active, archived)"
 // @Param include_stats query bool false "Include activity aggregates"
+// @Param activity_status query []string false "With include_stats, only aggregate activities with these statuses"
+// @Param currency query string false "With include_stats, report cost_total in this ISO 4217 code (default USD)"
 // @Success 200 {array} database.Project
+// @Failure 400 {object} APIError
 // @Failure 500 {object} APIError
 // @Router /api/projects [get]
 func (s *Server) listProjectsHandler(c *gin.Context) {
@@ -144,8 +224,20 @@ func (s *Server) listProjectsHandler(c *gin.Context) {
 	includeStats := strings.EqualFold(c.Query("include_stats"), "true")
 
 	if includeStats {
-		projects, err := s.db.ListProjectsWithStats(c.Request.Context(), status)
+		filters, err := activityFiltersFromQuery(c)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		// status selects projects here; activity status uses activity_status.
+		filters.Statuses = queryList(c, "activity_status")
+
+		projects, err := s.db.ListProjectsWithStats(c.Request.Context(), status, filters)
 		if err != nil {
+			if isActivityQueryError(err) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
 			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list projects"})
 			return
 		}
@@ -161,3 +253,102 @@ func (s *Server) listProjectsHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, projects)
 }
+
+func activityFiltersFromQuery(c *gin.Context) (database.ActivityFilters, error) {
+	filters := database.ActivityFilters{
+		ProjectTags: queryList(c, "project"),
+		Models:      queryList(c, "model"),
+		UserIDs:     queryList(c, "user_id"),
+		Channels:    queryList(c, "channel"),
+		Statuses:    queryList(c, "status"),
+		Categories:  queryList(c, "category"),
+		SessionKeys: queryList(c, "session_key"),
+		Thinking:    queryList(c, "thinking"),
+		Date:        c.Query("date"),
+		From:        c.Query("from"),
+		To:          c.Query("to"),
+		Cursor:      c.Query("cursor"),
+		Sort:        c.Query("sort"),
+		Currency:    c.Query("currency"),
+	}
+
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return database.ActivityFilters{}, database.ErrInvalidLimit
+		}
+		filters.Limit = limit
+	}
+
+	if raw := strings.TrimSpace(c.Query("reasoning")); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return database.ActivityFilters{}, fmt.Errorf("%w: reasoning must be true or false", database.ErrInvalidFilter)
+		}
+		filters.Reasoning = &value
+	}
+
+	var err error
+	if filters.MinCost, err = queryFloat(c, "min_cost"); err != nil {
+		return database.ActivityFilters{}, err
+	}
+	if filters.MaxCost, err = queryFloat(c, "max_cost"); err != nil {
+		return database.ActivityFilters{}, err
+	}
+	if filters.MinTokens, err = queryInt(c, "min_tokens"); err != nil {
+		return database.ActivityFilters{}, err
+	}
+	if filters.MaxTokens, err = queryInt(c, "max_tokens"); err != nil {
+		return database.ActivityFilters{}, err
+	}
+
+	return filters, nil
+}
+
+// queryList collects a multi-value parameter given either repeated
+// (?status=a&status=b) or comma-separated (?status=a,b).
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, raw := range c.QueryArray(key) {
+		for _, part := range strings.Split(raw, ",") {
+			if trimmed := strings.TrimSpace(part); trimmed != "" {
+				values = append(values, trimmed)
+			}
+		}
+	}
+	return values
+}
+
+func queryFloat(c *gin.Context, key string) (*float64, error) {
+	raw := strings.TrimSpace(c.Query(key))
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be a number", database.ErrInvalidFilter, key)
+	}
+	return &value, nil
+}
+
+func queryInt(c *gin.Context, key string) (*int64, error) {
+	raw := strings.TrimSpace(c.Query(key))
+	if raw == "" {
+		return nil, nil
+	}
+	value, err := strconv.ParseInt(raw, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %s must be an integer", database.ErrInvalidFilter, key)
+	}
+	return &value, nil
+}
+
+func isActivityQueryError(err error) bool {
+	return errors.Is(err, database.ErrInvalidDateFilter) ||
+		errors.Is(err, database.ErrInvalidFilter) ||
+		errors.Is(err, database.ErrInvalidGroupBy) ||
+		errors.Is(err, database.ErrInvalidCursor) ||
+		errors.Is(err, database.ErrInvalidSort) ||
+		errors.Is(err, database.ErrInvalidLimit) ||
+		errors.Is(err, database.ErrMissingExchangeRate)
+}
@@ -0,0 +1,229 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestPostActivityRepeatedPayloadReturnsExistingRow(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	payload := map[string]any{
+		"session_key":  "session-idem-1",
+		"model":        "gpt-5",
+		"tokens_in":    10,
+		"tokens_out":   5,
+		"project_tag":  "proj-alpha",
+		"external_ref": "turn-7",
+		"created_at":   "2026-02-18T10:00:00Z",
+	}
+
+	first := performJSON(t, handler, http.MethodPost, "/api/activity", payload)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, first.Code, first.Body.String())
+	}
+	second := performJSON(t, handler, http.MethodPost, "/api/activity", payload)
+	if second.Code != http.StatusOK {
+		t.Fatalf("expected status %d for duplicate, got %d body=%s", http.StatusOK, second.Code, second.Body.String())
+	}
+
+	var created, repeated database.ActivityFeed
+	if err := json.Unmarshal(first.Body.Bytes(), &created); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(second.Body.Bytes(), &repeated); err != nil {
+		t.Fatal(err)
+	}
+	if created.ID != repeated.ID {
+		t.Fatalf("expected duplicate to return existing id %q, got %q", created.ID, repeated.ID)
+	}
+	if repeated.ProjectTag != "proj-alpha" {
+		t.Fatalf("expected duplicate response to carry project_tag, got %q", repeated.ProjectTag)
+	}
+
+	if got := listActivities(t, handler, "/api/activity"); len(got) != 1 {
+		t.Fatalf("expected 1 stored activity, got %d", len(got))
+	}
+}
+
+func TestPostActivityIdempotencyKeyHeaderWins(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	headers := map[string]string{"Idempotency-Key": "client-key-1"}
+	first := performJSONWithHeaders(t, handler, http.MethodPost, "/api/activity", map[string]any{
+		"session_key": "session-idem-2",
+		"created_at":  "2026-02-18T10:00:00Z",
+	}, headers)
+	if first.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, first.Code, first.Body.String())
+	}
+
+	second := performJSONWithHeaders(t, handler, http.MethodPost, "/api/activity", map[string]any{
+		"session_key": "session-idem-2",
+		"created_at":  "2026-02-18T10:00:05Z",
+	}, headers)
+	if second.Code != http.StatusOK {
+		t.Fatalf("expected status %d for repeated header key, got %d body=%s", http.StatusOK, second.Code, second.Body.String())
+	}
+
+	third := performJSON(t, handler, http.MethodPost, "/api/activity", map[string]any{
+		"session_key": "session-idem-2",
+	})
+	fourth := performJSON(t, handler, http.MethodPost, "/api/activity", map[string]any{
+		"session_key": "session-idem-2",
+	})
+	if third.Code != http.StatusCreated || fourth.Code != http.StatusCreated {
+		t.Fatalf("expected payloads without created_at to be stored, got %d and %d", third.Code, fourth.Code)
+	}
+
+	if got := listActivities(t, handler, "/api/activity"); len(got) != 3 {
+		t.Fatalf("expected 3 stored activities, got %d", len(got))
+	}
+}
+
+func TestPostActivityBatchReportsDuplicates(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	createActivity(t, handler, map[string]any{
+		"session_key": "batch-dup-existing",
+		"created_at":  "2026-02-18T10:00:00Z",
+	})
+
+	body := `[
+		{"session_key":"batch-dup-existing","created_at":"2026-02-18T10:00:00Z"},
+		{"session_key":"batch-dup-new","created_at":"2026-02-18T11:00:00Z"},
+		{"session_key":"batch-dup-new","created_at":"2026-02-18T11:00:00Z"}
+	]`
+	rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/json", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	got := decodeBatchResponse(t, rr)
+	if got.Created != 1 || got.Duplicates != 2 || got.Failed != 0 {
+		t.Fatalf("expected created=1 duplicates=2 failed=0, got %#v", got)
+	}
+	if got.Results[0].Status != "duplicate" || got.Results[0].ID == "" {
+		t.Fatalf("expected first item to reference existing row, got %#v", got.Results[0])
+	}
+	if got.Results[2].Status != "duplicate" || got.Results[2].ID != got.Results[1].ID {
+		t.Fatalf("expected in-batch repeat to reference created row, got %#v", got.Results)
+	}
+
+	if activities := listActivities(t, handler, "/api/activity"); len(activities) != 2 {
+		t.Fatalf("expected 2 stored activities, got %d", len(activities))
+	}
+}
+
+// racingActivityStore stores a copy of the first activity of the first batch
+// just before inserting it, as a concurrent writer winning the race would.
+type racingActivityStore struct {
+	database.Service
+	raced bool
+}
+
+func (s *racingActivityStore) CreateActivities(ctx context.Context, activities []*database.ActivityFeed) error {
+	if !s.raced && len(activities) > 0 {
+		s.raced = true
+		winner := *activities[0]
+		winner.ID = ""
+		if err := s.Service.CreateActivity(ctx, &winner); err != nil {
+			return err
+		}
+	}
+	return s.Service.CreateActivities(ctx, activities)
+}
+
+func TestPostActivityBatchReportsDuplicatesThatLoseTheInsertRace(t *testing.T) {
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	s.db = &racingActivityStore{Service: s.db}
+	handler := s.RegisterRoutes()
+
+	body := `[
+		{"session_key":"race-lost","created_at":"2026-02-18T10:00:00Z"},
+		{"session_key":"race-new","created_at":"2026-02-18T11:00:00Z"},
+		{"session_key":"race-lost","created_at":"2026-02-18T10:00:00Z"}
+	]`
+	rr := performRaw(t, handler, http.MethodPost, "/api/activity/batch", "application/json", body)
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+
+	got := decodeBatchResponse(t, rr)
+	if got.Created != 1 || got.Duplicates != 2 || got.Failed != 0 {
+		t.Fatalf("expected created=1 duplicates=2 failed=0, got %#v", got)
+	}
+	if got.Results[0].Status != "duplicate" || got.Results[0].ID == "" || got.Results[2].ID != got.Results[0].ID {
+		t.Fatalf("expected the raced item and its repeat to reference the stored row, got %#v", got.Results)
+	}
+	if got.Results[1].Status != "created" {
+		t.Fatalf("expected the remaining item to be created, got %#v", got.Results[1])
+	}
+
+	if activities := listActivities(t, handler, "/api/activity"); len(activities) != 2 {
+		t.Fatalf("expected 2 stored activities, got %d", len(activities))
+	}
+}
+
+func TestFlushQueuedActivitiesSkipsAlreadyIngestedTurns(t *testing.T) {
+	adapter, cleanup := newQueueTestAdapter(t)
+	defer cleanup()
+
+	raw := `{"session_key":"q-dup","model":"gpt-5","project_tag":"clawtivity","channel":"webchat","user_id":"u1","created_at":"2026-02-19T00:00:00Z"}`
+
+	var live activityIngest
+	if err := json.Unmarshal([]byte(raw), &live); err != nil {
+		t.Fatal(err)
+	}
+	if err := prepareActivityIngest(context.Background(), adapter, classifierSignalConfig{}, &live); err != nil {
+		t.Fatal(err)
+	}
+	if err := adapter.CreateActivity(context.Background(), &live.ActivityFeed); err != nil {
+		t.Fatalf("expected live insert to succeed: %v", err)
+	}
+
+	queueRoot := t.TempDir()
+	body := strings.Join([]string{
+		"# Clawtivity Fallback Queue (2026-02-19)",
+		"",
+		"## queued_at: 2026-02-19T00:00:00Z",
+		"```json",
+		raw,
+		"```",
+		"",
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(queueRoot, "2026-02-19.md"), []byte(body), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := flushQueuedActivities(context.Background(), adapter, queueRoot, classifierSignalConfig{}); err != nil {
+		t.Fatalf("expected replay to succeed: %v", err)
+	}
+
+	activities, err := adapter.ListActivities(context.Background(), database.ActivityFilters{ProjectTag: "clawtivity"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 1 {
+		t.Fatalf("expected replay to reuse existing row, got %d activities", len(activities))
+	}
+
+	files, err := filepath.Glob(filepath.Join(queueRoot, "*.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 0 {
+		t.Fatalf("expected replayed duplicate to be removed from queue, got %v", files)
+	}
+}
@@ -2,6 +2,10 @@ package server
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -25,6 +29,8 @@ var projectOverrideStopwords = map[string]struct{}{
 	"for": {},
 }
 
+const maxIdempotencyKeyLength = 255
+
 type activityIngest struct {
 	database.ActivityFeed
 	PromptText    string   `json:"prompt_text"`
@@ -32,6 +38,82 @@ type activityIngest struct {
 	ToolsUsed     []string `json:"tools_used"`
 }
 
+// prepareActivityIngest runs the shared ingest pipeline (normalization, project
+// association, classification and project registration) for one payload.
+func prepareActivityIngest(ctx context.Context, db database.Service, signalConfig classifierSignalConfig, input *activityIngest) error {
+	// Always generate a fresh ID server-side.
+	input.ActivityFeed.ID = ""
+	assignIdempotencyKey(&input.ActivityFeed)
+	normalizeActivity(&input.ActivityFeed)
+	applyProjectAssociation(&input.ActivityFeed, input.PromptText, input.AssistantText)
+	applyActivityClassification(&input.ActivityFeed, classifier.Signals{
+		PromptText:    input.PromptText,
+		AssistantText: input.AssistantText,
+		ToolsUsed:     input.ToolsUsed,
+	}, signalConfig)
+	return ensureProjectRegistry(ctx, db, &input.ActivityFeed)
+}
+
+// assignIdempotencyKey keeps a client-supplied key or derives one from the
+// session, external reference and client timestamp so retries and queue
+// replays of the same turn map to the same row. It must run before
+// normalizeActivity fills in a server-side created_at.
+func assignIdempotencyKey(activity *database.ActivityFeed) {
+	if activity == nil {
+		return
+	}
+
+	if activity.IdempotencyKey != nil {
+		key := strings.TrimSpace(*activity.IdempotencyKey)
+		if key != "" {
+			activity.IdempotencyKey = &key
+			return
+		}
+		activity.IdempotencyKey = nil
+	}
+
+	sessionKey := strings.TrimSpace(activity.SessionKey)
+	if sessionKey == "" || activity.CreatedAt.IsZero() {
+		return
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join([]string{
+		sessionKey,
+		strings.TrimSpace(activity.ExternalRef),
+		activity.CreatedAt.UTC().Format(time.RFC3339Nano),
+	}, "\n")))
+	key := "derived:" + hex.EncodeToString(sum[:])
+	activity.IdempotencyKey = &key
+}
+
+func validateIdempotencyKey(activity database.ActivityFeed) error {
+	if activity.IdempotencyKey != nil && len(strings.TrimSpace(*activity.IdempotencyKey)) > maxIdempotencyKeyLength {
+		return fmt.Errorf("idempotency key must be at most %d characters", maxIdempotencyKeyLength)
+	}
+	return nil
+}
+
+// validateBatchActivity rejects batch items whose counters are negative so a
+// single bad line is reported per item instead of skewing the stored totals.
+func validateBatchActivity(activity database.ActivityFeed) error {
+	if err := validateIdempotencyKey(activity); err != nil {
+		return err
+	}
+	if activity.TokensIn < 0 {
+		return errors.New("tokens_in must not be negative")
+	}
+	if activity.TokensOut < 0 {
+		return errors.New("tokens_out must not be negative")
+	}
+	if activity.CacheReadTokens < 0 || activity.CacheWriteTokens < 0 || activity.ReasoningTokens < 0 {
+		return errors.New("cache_read_tokens, cache_write_tokens and reasoning_tokens must not be negative")
+	}
+	if activity.DurationMS < 0 {
+		return errors.New("duration_ms must not be negative")
+	}
+	return nil
+}
+
 func normalizeActivity(activity *database.ActivityFeed) {
 	if strings.TrimSpace(activity.ProjectTag) == "" {
 		activity.ProjectTag = "workspace"
@@ -57,25 +139,30 @@ func normalizeActivity(activity *database.ActivityFeed) {
 	if activity.CreatedAt.IsZero() {
 		activity.CreatedAt = time.Now().UTC()
 	}
+	// Store timestamps in UTC so created_at ordering and cursors stay consistent.
+	activity.CreatedAt = activity.CreatedAt.UTC()
 }
 
-func applyActivityClassification(activity *database.ActivityFeed, signals classifier.Signals) {
+func applyActivityClassification(activity *database.ActivityFeed, signals classifier.Signals, signalConfig classifierSignalConfig) {
 	if activity == nil {
 		return
 	}
+	activity.Features = activityFeatures(signals, signalConfig)
 
 	category := strings.ToLower(strings.TrimSpace(activity.Category))
 	if category != "" && category != "general" {
 		activity.Category = category
 		if strings.TrimSpace(activity.CategoryReason) == "" {
-			activity.CategoryReason = "provided:category"
+			activity.CategoryReason = database.CategoryReasonProvided
 		}
+		activity.Categories = []database.ActivityCategory{{Category: category, Score: 1}}
 		return
 	}
 
-	derivedCategory, reason := classifier.Classify(signals)
-	activity.Category = derivedCategory
-	activity.CategoryReason = reason
+	classification := classifier.ClassifyLabels(signals)
+	activity.Category = classification.Category
+	activity.CategoryReason = classification.Reason
+	activity.Categories = activityCategoryLabels(classification.Labels)
 }
 
 func applyProjectAssociation(activity *database.ActivityFeed, promptText, assistantText string) {
@@ -0,0 +1,90 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestGetActivityPaginatesWithCursorEnvelope(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for i := 0; i < 3; i++ {
+		createActivity(t, handler, map[string]any{
+			"session_key": fmt.Sprintf("page-%d", i),
+			"model":       "gpt-5",
+			"project_tag": "proj-alpha",
+			"created_at":  fmt.Sprintf("2026-02-18T1%d:00:00Z", i),
+		})
+	}
+
+	first := getActivityPage(t, handler, "/api/activity?limit=2")
+	if len(first.Data) != 2 || first.NextCursor == "" {
+		t.Fatalf("expected 2 rows and a next cursor, got %d rows cursor=%q", len(first.Data), first.NextCursor)
+	}
+	if first.Data[0].SessionKey != "page-2" {
+		t.Fatalf("expected newest row first, got %q", first.Data[0].SessionKey)
+	}
+
+	second := getActivityPage(t, handler, "/api/activity?limit=2&cursor="+url.QueryEscape(first.NextCursor))
+	if len(second.Data) != 1 || second.NextCursor != "" {
+		t.Fatalf("expected final page with 1 row and no cursor, got %d rows cursor=%q", len(second.Data), second.NextCursor)
+	}
+	if second.Data[0].SessionKey != "page-0" {
+		t.Fatalf("expected oldest row on final page, got %q", second.Data[0].SessionKey)
+	}
+
+	ascending := listActivities(t, handler, "/api/activity?sort=created_at")
+	if len(ascending) != 3 || ascending[0].SessionKey != "page-0" {
+		t.Fatalf("expected unpaginated ascending array, got %#v", ascending)
+	}
+}
+
+func TestGetActivityRejectsInvalidPaginationParameters(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for _, path := range []string{
+		"/api/activity?limit=0",
+		"/api/activity?limit=abc",
+		"/api/activity?limit=5000",
+		"/api/activity?cursor=bogus",
+		"/api/activity?sort=tokens",
+	} {
+		req, err := http.NewRequest(http.MethodGet, path, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rr := httptest.NewRecorder()
+		handler.ServeHTTP(rr, req)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for %s, got %d body=%s", http.StatusBadRequest, path, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func getActivityPage(t *testing.T, handler http.Handler, path string) database.ActivityPage {
+	t.Helper()
+
+	req, err := http.NewRequest(http.MethodGet, path, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+
+	var page database.ActivityPage
+	if err := json.Unmarshal(rr.Body.Bytes(), &page); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	return page
+}
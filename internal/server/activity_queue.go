@@ -3,6 +3,7 @@ package server
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -58,20 +59,20 @@ func CountQueueDepth(queueRoot string) int {
 	return count
 }
 
-func flushQueueOnStartup(db database.Service) {
+func flushQueueOnStartup(db database.Service, signalConfig classifierSignalConfig) {
 	if db == nil {
 		return
 	}
 
 	queueDir := resolveQueueDir()
-	_, _ = flushQueuedActivitiesWithOptions(context.Background(), db, queueDir, true)
+	_, _ = flushQueuedActivitiesWithOptions(context.Background(), db, queueDir, signalConfig, true)
 }
 
-func flushQueuedActivities(ctx context.Context, db database.Service, queueDir string) (int, error) {
-	return flushQueuedActivitiesWithOptions(ctx, db, queueDir, false)
+func flushQueuedActivities(ctx context.Context, db database.Service, queueDir string, signalConfig classifierSignalConfig) (int, error) {
+	return flushQueuedActivitiesWithOptions(ctx, db, queueDir, signalConfig, false)
 }
 
-func flushQueuedActivitiesWithOptions(ctx context.Context, db database.Service, queueDir string, startup bool) (int, error) {
+func flushQueuedActivitiesWithOptions(ctx context.Context, db database.Service, queueDir string, signalConfig classifierSignalConfig, startup bool) (int, error) {
 	if strings.TrimSpace(queueDir) == "" {
 		return 0, nil
 	}
@@ -129,6 +130,7 @@ func flushQueuedActivitiesWithOptions(ctx context.Context, db database.Service,
 				continue
 			}
 			activity := entry.ingest.ActivityFeed
+			assignIdempotencyKey(&activity)
 			normalizeActivity(&activity)
 			applyProjectAssociation(&activity, entry.ingest.PromptText, entry.ingest.AssistantText)
 			if err := ensureProjectRegistry(ctx, db, &activity); err != nil {
@@ -148,32 +150,46 @@ func flushQueuedActivitiesWithOptions(ctx context.Context, db database.Service,
 					"error":       err.Error(),
 					"session_key": activity.SessionKey,
 				}, CountQueueDepth(queueDir))
+				notifyWebhooks(ctx, db, database.WebhookEventQueueReplayFailed, map[string]any{
+					"file":        filePath,
+					"error":       err.Error(),
+					"session_key": activity.SessionKey,
+				})
 				continue
 			}
 			applyActivityClassification(&activity, classifier.Signals{
 				PromptText:    entry.ingest.PromptText,
 				AssistantText: entry.ingest.AssistantText,
 				ToolsUsed:     entry.ingest.ToolsUsed,
-			})
-			if err := db.CreateActivity(ctx, &activity); err != nil {
+			}, signalConfig)
+			createErr := db.CreateActivity(ctx, &activity)
+			if createErr != nil && !errors.Is(createErr, database.ErrDuplicateActivity) {
 				remaining = append(remaining, entry)
 				incQueueFlushFailed()
 				logEvent("warn", "queue_flush_failed", map[string]any{
 					"queue_root":  queueDir,
 					"file":        filePath,
 					"startup":     startup,
-					"error":       err.Error(),
+					"error":       createErr.Error(),
 					"session_key": activity.SessionKey,
 				}, CountQueueDepth(queueDir))
 				logEvent("warn", "replay_failed", map[string]any{
 					"queue_root":  queueDir,
 					"file":        filePath,
 					"startup":     startup,
-					"error":       err.Error(),
+					"error":       createErr.Error(),
 					"session_key": activity.SessionKey,
 				}, CountQueueDepth(queueDir))
+				notifyWebhooks(ctx, db, database.WebhookEventQueueReplayFailed, map[string]any{
+					"file":        filePath,
+					"error":       createErr.Error(),
+					"session_key": activity.SessionKey,
+				})
 				continue
 			}
+			if createErr == nil {
+				activitiesStored(ctx, db, &activity)
+			}
 			totalFlushed++
 			incQueueFlushSucceeded()
 			queueDepthAfter := CountQueueDepth(queueDir)
@@ -31,7 +31,7 @@ func TestFlushQueuedActivitiesImportsAndDeletesProcessedFile(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	flushed, err := flushQueuedActivities(context.Background(), adapter, queueRoot)
+	flushed, err := flushQueuedActivities(context.Background(), adapter, queueRoot, classifierSignalConfig{})
 	if err != nil {
 		t.Fatalf("expected no error flushing queue: %v", err)
 	}
@@ -84,7 +84,7 @@ func TestFlushQueuedActivitiesKeepsMalformedQueueEntries(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	flushed, err := flushQueuedActivities(context.Background(), adapter, queueRoot)
+	flushed, err := flushQueuedActivities(context.Background(), adapter, queueRoot, classifierSignalConfig{})
 	if err != nil {
 		t.Fatalf("expected no error flushing malformed queue: %v", err)
 	}
@@ -122,7 +122,7 @@ func TestFlushQueueOnStartupUsesConfiguredQueueDir(t *testing.T) {
 		t.Fatal(err)
 	}
 
-	flushQueueOnStartup(adapter)
+	flushQueueOnStartup(adapter, classifierSignalConfig{})
 
 	activities, err := adapter.ListActivities(context.Background(), database.ActivityFilters{ProjectTag: "clawtivity"})
 	if err != nil {
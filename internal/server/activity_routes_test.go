@@ -166,6 +166,59 @@ func TestPostActivityAutoCategorizesAndSetsReason(t *testing.T) {
 	}
 }
 
+func TestPostActivityStoresRankedCategoryLabels(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	rr := performJSON(t, handler, http.MethodPost, "/api/activity", map[string]any{
+		"session_key": "session-labels-1",
+		"model":       "gpt-5",
+		"project_tag": "proj-alpha",
+		"channel":     "webchat",
+		"status":      "success",
+		"user_id":     "art",
+		"prompt_text": "fix the bug and update the docs",
+		"tools_used":  []string{"edit_file"},
+	})
+	if rr.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, rr.Code, rr.Body.String())
+	}
+	var created database.ActivityFeed
+	if err := json.Unmarshal(rr.Body.Bytes(), &created); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	if created.Category != "code" || len(created.Categories) != 2 || created.Categories[0].Category != "code" || created.Categories[1].Category != "admin" {
+		t.Fatalf("expected code primary with admin secondary, got %s", rr.Body.String())
+	}
+	createActivity(t, handler, map[string]any{
+		"session_key": "session-labels-2",
+		"model":       "gpt-5",
+		"project_tag": "proj-alpha",
+		"category":    "admin",
+		"channel":     "webchat",
+		"status":      "success",
+		"user_id":     "art",
+	})
+
+	listed := performRaw(t, handler, http.MethodGet, "/api/activity?session_key=session-labels-1", "", "")
+	var activities []database.ActivityFeed
+	if err := json.Unmarshal(listed.Body.Bytes(), &activities); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	if len(activities) != 1 || len(activities[0].Categories) != 2 || activities[0].Categories[1].Rank != 1 {
+		t.Fatalf("expected listed labels, got %s", listed.Body.String())
+	}
+
+	summary := performRaw(t, handler, http.MethodGet, "/api/activity/summary?project=proj-alpha", "", "")
+	var got database.ActivitySummary
+	if err := json.Unmarshal(summary.Body.Bytes(), &got); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	if got.ByCategory["code"] != (database.CategoryLabelCount{Primary: 1}) || got.ByCategory["admin"] != (database.CategoryLabelCount{Primary: 1, Secondary: 1}) {
+		t.Fatalf("expected primary and secondary counts, got %s", summary.Body.String())
+	}
+}
+
 func TestPostActivityPromptProjectOverrideWins(t *testing.T) {
 	handler, cleanup := newTestHandler(t)
 	defer cleanup()
@@ -666,6 +719,13 @@ func TestActivityEndpointsRejectInvalidDateFilter(t *testing.T) {
 func newTestHandler(t *testing.T) (http.Handler, func()) {
 	t.Helper()
 
+	s, cleanup := newTestServer(t)
+	return s.RegisterRoutes(), cleanup
+}
+
+func newTestServer(t *testing.T) (*Server, func()) {
+	t.Helper()
+
 	dbPath := filepath.Join(t.TempDir(), fmt.Sprintf("clawtivity-%d.db", time.Now().UnixNano()))
 	adapter, err := database.NewSQLiteAdapter(dbPath)
 	if err != nil {
@@ -678,7 +738,7 @@ func newTestHandler(t *testing.T) (http.Handler, func()) {
 		_ = adapter.Close()
 	}
 
-	return s.RegisterRoutes(), cleanup
+	return s, cleanup
 }
 
 func createActivity(t *testing.T, handler http.Handler, payload map[string]any) {
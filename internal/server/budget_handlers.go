@@ -0,0 +1,155 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// listBudgetsHandler godoc
+// @Summary List budget status
+// @Description List budgets with spend in the current UTC period, remaining amount, percent used, projected end-of-period spend and the alert thresholds already crossed. Global budgets (no project) come first.
+// @Tags budgets
+// @Produce json
+// @Success 200 {array} database.BudgetStatus
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/budgets [get]
+func (s *Server) listBudgetsHandler(c *gin.Context) {
+	statuses, err := s.db.ListBudgetStatuses(c.Request.Context(), time.Now().UTC())
+	if err != nil {
+		if errors.Is(err, database.ErrMissingExchangeRate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list budgets"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// createBudgetHandler godoc
+// @Summary Create budget
+// @Description Cap spend per daily, weekly (Monday start) or monthly UTC period for the project named by project_tag, or for all projects when it is omitted. currency defaults to USD. A second budget for the same project and period is a conflict.
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param budget body database.Budget true "Budget"
+// @Success 201 {object} database.Budget
+// @Failure 400 {object} APIError
+// @Failure 409 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/budgets [post]
+func (s *Server) createBudgetHandler(c *gin.Context) {
+	var budget database.Budget
+	if err := c.ShouldBindJSON(&budget); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget.ID = ""
+	if err := s.db.CreateBudget(c.Request.Context(), &budget); err != nil {
+		writeBudgetError(c, err, "failed to create budget")
+		return
+	}
+
+	c.JSON(http.StatusCreated, budget)
+}
+
+// updateBudgetHandler godoc
+// @Summary Update budget
+// @Tags budgets
+// @Accept json
+// @Produce json
+// @Param id path string true "Budget ID"
+// @Param update body database.BudgetUpdate true "Fields to change"
+// @Success 200 {object} database.Budget
+// @Failure 400 {object} APIError
+// @Failure 404 {object} APIError
+// @Failure 409 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/budgets/{id} [patch]
+func (s *Server) updateBudgetHandler(c *gin.Context) {
+	var update database.BudgetUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	budget, err := s.db.UpdateBudget(c.Request.Context(), c.Param("id"), update)
+	if err != nil {
+		writeBudgetError(c, err, "failed to update budget")
+		return
+	}
+
+	c.JSON(http.StatusOK, budget)
+}
+
+// deleteBudgetHandler godoc
+// @Summary Delete budget
+// @Tags budgets
+// @Param id path string true "Budget ID"
+// @Success 204
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/budgets/{id} [delete]
+func (s *Server) deleteBudgetHandler(c *gin.Context) {
+	if err := s.db.DeleteBudget(c.Request.Context(), c.Param("id")); err != nil {
+		writeBudgetError(c, err, "failed to delete budget")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+func writeBudgetError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrInvalidBudget):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrBudgetNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrBudgetConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// evaluateBudgets logs a budget_threshold_crossed event for every threshold
+// the stored activities push a budget past. The activities are evaluated
+// together, once per budget period they touch. Evaluation never fails ingest.
+func evaluateBudgets(ctx context.Context, db database.Service, activities ...*database.ActivityFeed) {
+	if len(activities) == 0 {
+		return
+	}
+	stored := make([]database.ActivityFeed, 0, len(activities))
+	for _, activity := range activities {
+		stored = append(stored, *activity)
+	}
+
+	alerts, err := db.EvaluateBudgets(ctx, stored...)
+	for _, alert := range alerts {
+		logEvent("warn", "budget_threshold_crossed", map[string]any{
+			"budget_id":    alert.BudgetID,
+			"project_tag":  alert.ProjectTag,
+			"period":       alert.Period,
+			"period_start": alert.PeriodStart.Format(time.RFC3339),
+			"threshold":    alert.Threshold,
+			"spend":        alert.Spend,
+			"limit":        alert.Limit,
+			"currency":     alert.Currency,
+		}, currentQueueDepth())
+		notifyWebhooks(ctx, db, database.WebhookEventBudgetThresholdCrossed, alert)
+	}
+	if err != nil {
+		logEvent("warn", "budget_evaluation_failed", map[string]any{
+			"activities": len(stored),
+			"error":      err.Error(),
+		}, currentQueueDepth())
+	}
+}
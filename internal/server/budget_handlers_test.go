@@ -0,0 +1,80 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestBudgetEndpointsAndThresholdEvents(t *testing.T) {
+	t.Setenv("CLAWTIVITY_LOG_LEVEL", "info")
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	if invalid := performJSON(t, handler, http.MethodPost, "/api/budgets", map[string]any{"period": "yearly", "limit": 1}); invalid.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, invalid.Code, invalid.Body.String())
+	}
+	created := performJSON(t, handler, http.MethodPost, "/api/budgets", map[string]any{"period": "daily", "limit": 0.000001})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, created.Code, created.Body.String())
+	}
+	var budget database.Budget
+	if err := json.Unmarshal(created.Body.Bytes(), &budget); err != nil {
+		t.Fatal(err)
+	}
+	if conflict := performJSON(t, handler, http.MethodPost, "/api/budgets", map[string]any{"period": "daily", "limit": 5}); conflict.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusConflict, conflict.Code, conflict.Body.String())
+	}
+
+	output := captureServerLogOutput(t, func() {
+		createActivity(t, handler, map[string]any{
+			"session_key": "budget-1",
+			"model":       "gpt-5-mini",
+			"tokens_in":   1000,
+			"tokens_out":  1000,
+			"project_tag": "clawtivity",
+			"category":    "general",
+			"thinking":    "low",
+			"channel":     "webchat",
+			"status":      "success",
+			"user_id":     "u1",
+		})
+	})
+	var thresholds []float64
+	for _, line := range strings.Split(output, "\n") {
+		if !strings.Contains(line, `"budget_threshold_crossed"`) {
+			continue
+		}
+		entry := decodeServerLogLine(t, line)
+		details := entry["details"].(map[string]any)
+		if details["budget_id"] != budget.ID {
+			t.Fatalf("expected event for budget %s, got %v", budget.ID, details)
+		}
+		thresholds = append(thresholds, details["threshold"].(float64))
+	}
+	if len(thresholds) != 3 || thresholds[2] != 100 {
+		t.Fatalf("expected 50/80/100 crossings, got %v in %s", thresholds, output)
+	}
+
+	rr := performRaw(t, handler, http.MethodGet, "/api/budgets", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var statuses []database.BudgetStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].PercentUsed < 100 || len(statuses[0].ThresholdsCrossed) != 3 {
+		t.Fatalf("expected an exhausted budget, got %s", rr.Body.String())
+	}
+
+	if deleted := performRaw(t, handler, http.MethodDelete, "/api/budgets/"+budget.ID, "", ""); deleted.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, deleted.Code)
+	}
+	if missing := performJSON(t, handler, http.MethodPatch, "/api/budgets/"+budget.ID, map[string]any{"limit": 2}); missing.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, missing.Code)
+	}
+}
@@ -0,0 +1,237 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"clawtivity/internal/classifier"
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultClassifierRulesInterval = 5 * time.Second
+
+// classifierRulesHandler godoc
+// @Summary Active classifier rules
+// @Description Return the category rules the classifier currently uses. source is the rules file path, or embedded for the built-in rules; error explains why a configured file was rejected in favour of the built-in rules.
+// @Tags classifier
+// @Produce json
+// @Success 200 {object} classifier.RuleSet
+// @Router /api/classifier/rules [get]
+func (s *Server) classifierRulesHandler(c *gin.Context) {
+	c.JSON(http.StatusOK, classifier.ActiveRules())
+}
+
+// explainClassificationHandler godoc
+// @Summary Explain classification
+// @Description Classify the given signals without storing anything and return the decision trace: the explicit override check, the learned model prediction, the keyword hits per category for prompt and assistant text with tie detection, the tool matches and the final decision with its ranked labels. Every stage is evaluated; applied marks the one that decided.
+// @Tags classifier
+// @Accept json
+// @Produce json
+// @Param signals body classifier.Signals true "Classifier signals"
+// @Success 200 {object} classifier.Explanation
+// @Failure 400 {object} APIError
+// @Router /api/classifier/explain [post]
+func (s *Server) explainClassificationHandler(c *gin.Context) {
+	var signals classifier.Signals
+	if err := c.ShouldBindJSON(&signals); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	c.JSON(http.StatusOK, classifier.Explain(signals))
+}
+
+type categoryCorrection struct {
+	Category string `json:"category"`
+}
+
+// correctActivityCategoryHandler godoc
+// @Summary Correct activity category
+// @Description Set the category of an activity, recording category_reason user_correction. The category must exist in the active classifier rules. Corrections retrain the learned classifier stage, which runs after explicit overrides and before keyword scoring once it has enough examples.
+// @Tags classifier
+// @Accept json
+// @Produce json
+// @Param id path string true "Activity ID"
+// @Param correction body categoryCorrection true "Corrected category"
+// @Success 200 {object} database.ActivityFeed
+// @Failure 400 {object} APIError
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/activity/{id}/category [patch]
+func (s *Server) correctActivityCategoryHandler(c *gin.Context) {
+	var input categoryCorrection
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	category := strings.ToLower(strings.TrimSpace(input.Category))
+	if _, ok := classifier.ActiveRules().Categories[category]; !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("%s: unknown category %q", database.ErrInvalidCategory, input.Category)})
+		return
+	}
+
+	activity, err := s.db.CorrectActivityCategory(c.Request.Context(), c.Param("id"), category)
+	if err != nil {
+		switch {
+		case errors.Is(err, database.ErrInvalidCategory):
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		case errors.Is(err, database.ErrActivityNotFound):
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		default:
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to correct category"})
+		}
+		return
+	}
+
+	trainClassifierModel(c.Request.Context(), s.db)
+	c.JSON(http.StatusOK, activity)
+}
+
+type reclassifyInput struct {
+	Projects   []string `json:"projects"`
+	Categories []string `json:"categories"`
+	From       string   `json:"from"`
+	To         string   `json:"to"`
+	DryRun     bool     `json:"dry_run"`
+	BatchSize  int      `json:"batch_size"`
+}
+
+// reclassifyHandler godoc
+// @Summary Reclassify activities
+// @Description Re-run the active classifier rules and learned model over the stored classifier signals of matching activities, in batches. from is inclusive and to exclusive (RFC3339); categories matches the current primary category. Provided categories, user corrections and activities stored without signal storage are skipped. The report has before/after category counts of the reclassified activities; dry_run reports without writing.
+// @Tags classifier
+// @Accept json
+// @Produce json
+// @Param request body reclassifyInput false "Reclassify scope"
+// @Success 200 {object} database.ReclassifyReport
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/classifier/reclassify [post]
+func (s *Server) reclassifyHandler(c *gin.Context) {
+	var input reclassifyInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := reclassifyActivities(c.Request.Context(), s.db, database.ReclassifyRequest{
+		Filters: database.ActivityFilters{
+			ProjectTags: input.Projects,
+			Categories:  input.Categories,
+			From:        input.From,
+			To:          input.To,
+		},
+		DryRun:    input.DryRun,
+		BatchSize: input.BatchSize,
+	})
+	if err != nil {
+		if isActivityQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to reclassify activities"})
+		return
+	}
+
+	c.JSON(http.StatusOK, report)
+}
+
+// Reclassify prepares the classifier the way server startup does (configured
+// rules file and learned model) and reclassifies the activities request
+// selects. It is the entry point of the reclassify command.
+func Reclassify(ctx context.Context, db database.Service, request database.ReclassifyRequest) (database.ReclassifyReport, error) {
+	if path := strings.TrimSpace(os.Getenv("CLAWTIVITY_CLASSIFIER_RULES_PATH")); path != "" {
+		if err := classifier.LoadRulesFile(path); err != nil {
+			return database.ReclassifyReport{}, err
+		}
+	}
+	trainClassifierModel(ctx, db)
+	return reclassifyActivities(ctx, db, request)
+}
+
+func reclassifyActivities(ctx context.Context, db database.Service, request database.ReclassifyRequest) (database.ReclassifyReport, error) {
+	request.Classify = classifyStoredSignals
+	report, err := db.ReclassifyActivities(ctx, request)
+	if err != nil {
+		return database.ReclassifyReport{}, err
+	}
+	logEvent("info", "classifier_reclassify", map[string]any{
+		"dry_run":            report.DryRun,
+		"scanned":            report.Scanned,
+		"changed":            report.Changed,
+		"skipped_preserved":  report.SkippedPreserved,
+		"skipped_no_signals": report.SkippedNoSignals,
+	}, currentQueueDepth())
+	return report, nil
+}
+
+// trainClassifierModel retrains the learned classifier stage from every stored
+// category correction. A failed retrain keeps the previous model.
+func trainClassifierModel(ctx context.Context, db database.Service) {
+	corrections, err := db.ListCategoryCorrections(ctx)
+	if err != nil {
+		logEvent("warn", "classifier_model_train_failed", map[string]any{
+			"error": err.Error(),
+		}, currentQueueDepth())
+		return
+	}
+
+	examples := make([]classifier.Example, 0, len(corrections))
+	for _, correction := range corrections {
+		examples = append(examples, classifier.Example{Category: correction.Category, Tokens: correction.PromptTokens})
+	}
+	model := classifier.TrainNaiveBayes(examples)
+	classifier.SetLearnedModel(model)
+	logEvent("info", "classifier_model_trained", map[string]any{
+		"examples":   model.Examples(),
+		"categories": model.Categories(),
+	}, currentQueueDepth())
+}
+
+// watchClassifierRules loads CLAWTIVITY_CLASSIFIER_RULES_PATH, when set, and
+// reloads it on change until ctx is done.
+func watchClassifierRules(ctx context.Context) {
+	path := strings.TrimSpace(os.Getenv("CLAWTIVITY_CLASSIFIER_RULES_PATH"))
+	if path == "" {
+		return
+	}
+
+	err := classifier.LoadRulesFile(path)
+	logClassifierRulesLoad(classifier.ActiveRules(), err)
+	go classifier.WatchRulesFile(ctx, path, resolveClassifierRulesInterval(), logClassifierRulesLoad)
+}
+
+func logClassifierRulesLoad(rules classifier.RuleSet, err error) {
+	if err != nil {
+		logEvent("warn", "classifier_rules_invalid", map[string]any{
+			"error":  err.Error(),
+			"source": rules.Source,
+		}, currentQueueDepth())
+		return
+	}
+	logEvent("info", "classifier_rules_loaded", map[string]any{
+		"source":     rules.Source,
+		"categories": len(rules.Categories),
+	}, currentQueueDepth())
+}
+
+func resolveClassifierRulesInterval() time.Duration {
+	raw := strings.TrimSpace(os.Getenv("CLAWTIVITY_CLASSIFIER_RULES_INTERVAL"))
+	if raw == "" {
+		return defaultClassifierRulesInterval
+	}
+	interval, err := time.ParseDuration(raw)
+	if err != nil || interval <= 0 {
+		return defaultClassifierRulesInterval
+	}
+	return interval
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"clawtivity/internal/classifier"
+	"clawtivity/internal/database"
+)
+
+func TestClassifierRulesEndpointReportsActiveRules(t *testing.T) {
+	t.Cleanup(classifier.UseEmbeddedRules)
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"categories": {"deploy": {"keywords": ["deploy", "rollout"]}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := classifier.LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := performRaw(t, handler, http.MethodGet, "/api/classifier/rules", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var rules classifier.RuleSet
+	if err := json.Unmarshal(rr.Body.Bytes(), &rules); err != nil {
+		t.Fatal(err)
+	}
+	if rules.Source != path || rules.DefaultCategory != "general" || len(rules.Categories["deploy"].Keywords) != 2 {
+		t.Fatalf("expected the file rules, got %s", rr.Body.String())
+	}
+
+	created := performJSON(t, handler, http.MethodPost, "/api/activity", map[string]any{
+		"session_key": "rules-1",
+		"model":       "gpt-5-mini",
+		"project_tag": "clawtivity",
+		"thinking":    "low",
+		"channel":     "webchat",
+		"status":      "success",
+		"user_id":     "u1",
+		"prompt_text": "deploy the rollout",
+	})
+	var activity map[string]any
+	if err := json.Unmarshal(created.Body.Bytes(), &activity); err != nil {
+		t.Fatal(err)
+	}
+	if activity["category"] != "deploy" {
+		t.Fatalf("expected ingest to use the loaded rules, got %s", created.Body.String())
+	}
+}
+
+func TestCorrectActivityCategoryTrainsLearnedModel(t *testing.T) {
+	t.Cleanup(func() { classifier.SetLearnedModel(nil) })
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	ingest := func(sessionKey, prompt string) map[string]any {
+		t.Helper()
+		rr := performJSON(t, handler, http.MethodPost, "/api/activity", map[string]any{
+			"session_key": sessionKey,
+			"model":       "gpt-5-mini",
+			"project_tag": "clawtivity",
+			"thinking":    "low",
+			"channel":     "webchat",
+			"status":      "success",
+			"user_id":     "u1",
+			"prompt_text": prompt,
+		})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+		var activity map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &activity); err != nil {
+			t.Fatal(err)
+		}
+		return activity
+	}
+
+	corrections := []struct{ prompt, category string }{
+		{"summarize the vendor landscape", "research"},
+		{"vendor landscape for observability", "research"},
+		{"collect vendor pricing for the landscape", "research"},
+		{"rotate the staging credentials", "admin"},
+		{"rotate credentials for billing", "admin"},
+		{"renew staging credentials", "admin"},
+	}
+	for i, correction := range corrections {
+		activity := ingest(fmt.Sprintf("learn-%d", i), correction.prompt)
+		rr := performJSON(t, handler, http.MethodPatch, "/api/activity/"+activity["id"].(string)+"/category", map[string]any{"category": correction.category})
+		if rr.Code != http.StatusOK {
+			t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+		}
+		var corrected map[string]any
+		if err := json.Unmarshal(rr.Body.Bytes(), &corrected); err != nil {
+			t.Fatal(err)
+		}
+		if corrected["category"] != correction.category || corrected["category_reason"] != "user_correction" {
+			t.Fatalf("expected corrected category, got %s", rr.Body.String())
+		}
+	}
+
+	activity := ingest("learn-new", "vendor landscape for tracing")
+	if activity["category"] != "research" || !strings.HasPrefix(activity["category_reason"].(string), "learned_model:research=") {
+		t.Fatalf("expected learned research category, got %v %v", activity["category"], activity["category_reason"])
+	}
+
+	rr := performJSON(t, handler, http.MethodPatch, "/api/activity/"+activity["id"].(string)+"/category", map[string]any{"category": "astrology"})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+	rr = performJSON(t, handler, http.MethodPatch, "/api/activity/missing/category", map[string]any{"category": "code"})
+	if rr.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusNotFound, rr.Code, rr.Body.String())
+	}
+}
+
+func TestReclassifyAppliesCurrentRulesToStoredSignals(t *testing.T) {
+	t.Cleanup(classifier.UseEmbeddedRules)
+	s, cleanup := newTestServer(t)
+	defer cleanup()
+	handler := s.RegisterRoutes()
+
+	ingest := func(sessionKey string, extra map[string]any) {
+		t.Helper()
+		payload := map[string]any{
+			"session_key": sessionKey,
+			"model":       "gpt-5-mini",
+			"project_tag": "clawtivity",
+			"channel":     "webchat",
+			"status":      "success",
+			"user_id":     "u1",
+			"prompt_text": "deploy the rollout",
+		}
+		for key, value := range extra {
+			payload[key] = value
+		}
+		createActivity(t, handler, payload)
+	}
+
+	ingest("reclassify-off", nil)
+	s.classifierSignals = classifierSignalConfig{Mode: database.SignalStorageText, MaxChars: defaultClassifierSignalMaxChars}
+	ingest("reclassify-text", nil)
+	ingest("reclassify-provided", map[string]any{"category": "admin"})
+	s.classifierSignals = classifierSignalConfig{Mode: database.SignalStorageDigest, MaxChars: defaultClassifierSignalMaxChars}
+	ingest("reclassify-digest", nil)
+
+	path := filepath.Join(t.TempDir(), "rules.json")
+	if err := os.WriteFile(path, []byte(`{"categories": {"deploy": {"keywords": ["deploy", "rollout"]}}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := classifier.LoadRulesFile(path); err != nil {
+		t.Fatal(err)
+	}
+
+	rr := performJSON(t, handler, http.MethodPost, "/api/classifier/reclassify", map[string]any{"projects": []string{"clawtivity"}})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var report database.ReclassifyReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if report.Scanned != 4 || report.Changed != 2 || report.SkippedPreserved != 1 || report.SkippedNoSignals != 1 ||
+		report.Before["general"] != 2 || report.After["deploy"] != 2 {
+		t.Fatalf("unexpected report %s", rr.Body.String())
+	}
+
+	listed := performRaw(t, handler, http.MethodGet, "/api/activity?category=deploy", "", "")
+	var activities []database.ActivityFeed
+	if err := json.Unmarshal(listed.Body.Bytes(), &activities); err != nil {
+		t.Fatal(err)
+	}
+	if len(activities) != 2 {
+		t.Fatalf("expected the text and digest activities to be reclassified, got %s", listed.Body.String())
+	}
+	for _, activity := range activities {
+		if activity.CategoryReason != "prompt_keyword_score:deploy=2" || activity.Categories[0].Category != "deploy" {
+			t.Fatalf("unexpected reclassified activity %#v", activity)
+		}
+	}
+
+	rr = performJSON(t, handler, http.MethodPost, "/api/classifier/reclassify", map[string]any{"from": "not-a-time"})
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
+
+func TestActivityFeaturesFollowSignalStorageMode(t *testing.T) {
+	signals := classifier.Signals{PromptText: "fix the flaky test", AssistantText: "done", ToolsUsed: []string{"bash"}}
+
+	if features := activityFeatures(signals, resolveClassifierSignalConfig()); features.Signals != "" || features.PromptText != "" || features.AssistantText != "" || len(features.PromptTokens) != 3 {
+		t.Fatalf("expected only prompt tokens by default, got %#v", features)
+	}
+
+	t.Setenv("CLAWTIVITY_CLASSIFIER_SIGNALS", "digest")
+	features := activityFeatures(signals, resolveClassifierSignalConfig())
+	if features.Signals != database.SignalStorageDigest || features.PromptText != "" || len(features.PromptDigest) != 64 || features.AssistantText != "done" || len(features.ToolsUsed) != 1 || features.Truncated {
+		t.Fatalf("expected a prompt digest, got %#v", features)
+	}
+
+	t.Setenv("CLAWTIVITY_CLASSIFIER_SIGNALS", "text")
+	t.Setenv("CLAWTIVITY_CLASSIFIER_SIGNALS_MAX_CHARS", "7")
+	features = activityFeatures(signals, resolveClassifierSignalConfig())
+	if features.Signals != database.SignalStorageText || features.PromptText != "fix the" || features.PromptDigest != "" || !features.Truncated {
+		t.Fatalf("expected truncated prompt text, got %#v", features)
+	}
+
+	t.Setenv("CLAWTIVITY_CLASSIFIER_SIGNALS", "verbose")
+	if config := resolveClassifierSignalConfig(); config.Mode != "" {
+		t.Fatalf("expected an invalid mode to turn signal storage off, got %#v", config)
+	}
+}
+
+func TestExplainClassificationReturnsDecisionTrace(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	rr := performJSON(t, handler, http.MethodPost, "/api/classifier/explain", map[string]any{
+		"prompt_text":    "research and compare the options",
+		"assistant_text": "findings attached",
+		"tools_used":     []string{"web_search"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var explanation classifier.Explanation
+	if err := json.Unmarshal(rr.Body.Bytes(), &explanation); err != nil {
+		t.Fatal(err)
+	}
+	if !explanation.Prompt.Applied || strings.Join(explanation.Prompt.Hits["research"], ",") != "research,compare" {
+		t.Fatalf("expected prompt keywords to decide, got %s", rr.Body.String())
+	}
+	if explanation.Tools.Category != "research" || explanation.Tools.Applied || explanation.Assistant.Scores["research"] != 1 {
+		t.Fatalf("expected later stages to be traced without applying, got %s", rr.Body.String())
+	}
+	if explanation.Decision.Category != "research" || explanation.Decision.Reason != "prompt_keyword_score:research=2" || len(explanation.Decision.Labels) != 1 {
+		t.Fatalf("unexpected decision %s", rr.Body.String())
+	}
+
+	listed := performRaw(t, handler, http.MethodGet, "/api/activity", "", "")
+	if listed.Body.String() != "[]" {
+		t.Fatalf("expected explain not to store activities, got %s", listed.Body.String())
+	}
+
+	rr = performRaw(t, handler, http.MethodPost, "/api/classifier/explain", "application/json", "{")
+	if rr.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, rr.Code, rr.Body.String())
+	}
+}
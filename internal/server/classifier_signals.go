@@ -0,0 +1,121 @@
+package server
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"strconv"
+	"strings"
+
+	"clawtivity/internal/classifier"
+	"clawtivity/internal/database"
+)
+
+const defaultClassifierSignalMaxChars = 2000
+
+// classifierSignalConfig controls which classifier signals are stored with
+// each activity for later reclassification.
+type classifierSignalConfig struct {
+	// Mode is "", database.SignalStorageDigest or database.SignalStorageText.
+	Mode string
+	// MaxChars truncates stored prompt and assistant text.
+	MaxChars int
+}
+
+// resolveClassifierSignalConfig reads CLAWTIVITY_CLASSIFIER_SIGNALS (off,
+// digest or text; default off) and CLAWTIVITY_CLASSIFIER_SIGNALS_MAX_CHARS.
+// Invalid values are logged and fall back to the defaults. It runs once at
+// startup.
+func resolveClassifierSignalConfig() classifierSignalConfig {
+	config := classifierSignalConfig{MaxChars: defaultClassifierSignalMaxChars}
+
+	switch mode := strings.ToLower(strings.TrimSpace(os.Getenv("CLAWTIVITY_CLASSIFIER_SIGNALS"))); mode {
+	case "", "off":
+	case database.SignalStorageDigest, database.SignalStorageText:
+		config.Mode = mode
+	default:
+		logEvent("warn", "classifier_signals_config_invalid", map[string]any{
+			"key":   "CLAWTIVITY_CLASSIFIER_SIGNALS",
+			"value": mode,
+		}, currentQueueDepth())
+	}
+
+	if raw := strings.TrimSpace(os.Getenv("CLAWTIVITY_CLASSIFIER_SIGNALS_MAX_CHARS")); raw != "" {
+		maxChars, err := strconv.Atoi(raw)
+		if err != nil || maxChars <= 0 {
+			logEvent("warn", "classifier_signals_config_invalid", map[string]any{
+				"key":   "CLAWTIVITY_CLASSIFIER_SIGNALS_MAX_CHARS",
+				"value": raw,
+			}, currentQueueDepth())
+		} else {
+			config.MaxChars = maxChars
+		}
+	}
+	return config
+}
+
+// activityFeatures returns what is stored for an activity's signals: always
+// the prompt tokens, and the signals themselves when signal storage is on.
+func activityFeatures(signals classifier.Signals, config classifierSignalConfig) *database.ActivityFeature {
+	features := &database.ActivityFeature{PromptTokens: classifier.Tokenize(signals.PromptText)}
+
+	if config.Mode == "" {
+		return features
+	}
+	features.Signals = config.Mode
+	features.AssistantText = truncateRunes(signals.AssistantText, config.MaxChars)
+	features.Truncated = features.AssistantText != signals.AssistantText
+	features.ToolsUsed = append(database.StringList{}, signals.ToolsUsed...)
+	switch config.Mode {
+	case database.SignalStorageDigest:
+		if signals.PromptText != "" {
+			digest := sha256.Sum256([]byte(signals.PromptText))
+			features.PromptDigest = hex.EncodeToString(digest[:])
+		}
+	case database.SignalStorageText:
+		features.PromptText = truncateRunes(signals.PromptText, config.MaxChars)
+		features.Truncated = features.Truncated || features.PromptText != signals.PromptText
+	}
+	return features
+}
+
+// storedSignals rebuilds classifier signals from stored features. Without the
+// prompt text, the prompt tokens stand in for it, so multi-word keywords and
+// words shorter than three letters can no longer match the prompt.
+func storedSignals(features database.ActivityFeature) classifier.Signals {
+	promptText := features.PromptText
+	if features.Signals != database.SignalStorageText {
+		promptText = strings.Join(features.PromptTokens, " ")
+	}
+	return classifier.Signals{
+		PromptText:    promptText,
+		AssistantText: features.AssistantText,
+		ToolsUsed:     features.ToolsUsed,
+	}
+}
+
+// classifyStoredSignals is the database.ReclassifyRequest classifier.
+func classifyStoredSignals(features database.ActivityFeature) database.ActivityClassification {
+	classification := classifier.ClassifyLabels(storedSignals(features))
+	return database.ActivityClassification{
+		Category:   classification.Category,
+		Reason:     classification.Reason,
+		Categories: activityCategoryLabels(classification.Labels),
+	}
+}
+
+func activityCategoryLabels(labels []classifier.Label) []database.ActivityCategory {
+	categories := make([]database.ActivityCategory, 0, len(labels))
+	for rank, label := range labels {
+		categories = append(categories, database.ActivityCategory{Category: label.Category, Rank: rank, Score: label.Score})
+	}
+	return categories
+}
+
+func truncateRunes(value string, maxChars int) string {
+	runes := []rune(value)
+	if len(runes) <= maxChars {
+		return value
+	}
+	return string(runes[:maxChars])
+}
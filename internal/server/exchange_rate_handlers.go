@@ -0,0 +1,66 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+const defaultExchangeRateSource = "csv import"
+
+// listExchangeRatesHandler godoc
+// @Summary List exchange rates
+// @Description List exchange rates (units of currency per USD) ordered by currency and newest effective_from first.
+// @Tags pricing
+// @Produce json
+// @Param currency query string false "Filter by currency"
+// @Success 200 {array} database.ExchangeRate
+// @Failure 500 {object} APIError
+// @Router /api/exchange-rates [get]
+func (s *Server) listExchangeRatesHandler(c *gin.Context) {
+	rates, err := s.db.ListExchangeRates(c.Request.Context(), c.Query("currency"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list exchange rates"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rates)
+}
+
+// importExchangeRatesHandler godoc
+// @Summary Import exchange rates
+// @Description Import effective-dated exchange rates from a CSV body with a currency,effective_from,rate header and an optional source column. rate is units of currency per USD; effective_from is RFC3339 or YYYY-MM-DD. Rows for an existing currency and effective_from replace its rate. The import is all-or-nothing.
+// @Tags pricing
+// @Accept text/csv
+// @Produce json
+// @Param source query string false "Provenance recorded on rows without a source column"
+// @Success 200 {object} database.ExchangeRateImport
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/exchange-rates [post]
+func (s *Server) importExchangeRatesHandler(c *gin.Context) {
+	source := strings.TrimSpace(c.Query("source"))
+	if source == "" {
+		source = defaultExchangeRateSource
+	}
+
+	result, err := s.db.ImportExchangeRatesCSV(c.Request.Context(), c.Request.Body, source)
+	if err != nil {
+		if errors.Is(err, database.ErrInvalidExchangeRate) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to import exchange rates"})
+		return
+	}
+
+	logEvent("info", "exchange_rates_import", map[string]any{
+		"imported": result.Imported,
+		"updated":  result.Updated,
+		"source":   source,
+	}, currentQueueDepth())
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,71 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestExchangeRateImportAndCurrencySummary(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	createActivity(t, handler, map[string]any{
+		"session_key": "fx-1",
+		"model":       "gpt-5-mini",
+		"tokens_in":   1000,
+		"tokens_out":  1000,
+		"project_tag": "clawtivity",
+		"category":    "general",
+		"thinking":    "low",
+		"channel":     "webchat",
+		"status":      "success",
+		"user_id":     "u1",
+	})
+
+	missing := performRaw(t, handler, http.MethodGet, "/api/activity/summary?currency=EUR", "", "")
+	if missing.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d without EUR rates, got %d body=%s", http.StatusBadRequest, missing.Code, missing.Body.String())
+	}
+
+	invalid := performRaw(t, handler, http.MethodPost, "/api/exchange-rates", "text/csv", "currency,effective_from,rate\nEUR,2026-01-01,zero\n")
+	if invalid.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, invalid.Code, invalid.Body.String())
+	}
+
+	imported := performRaw(t, handler, http.MethodPost, "/api/exchange-rates?source=ecb", "text/csv", "currency,effective_from,rate\nEUR,2020-01-01,0.5\n")
+	if imported.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, imported.Code, imported.Body.String())
+	}
+
+	list := performRaw(t, handler, http.MethodGet, "/api/exchange-rates?currency=eur", "", "")
+	var rates []database.ExchangeRate
+	if err := json.Unmarshal(list.Body.Bytes(), &rates); err != nil {
+		t.Fatal(err)
+	}
+	if len(rates) != 1 || rates[0].Source != "ecb" {
+		t.Fatalf("expected the imported EUR rate, got %s", list.Body.String())
+	}
+
+	usd := summaryInCurrency(t, handler, "")
+	eur := summaryInCurrency(t, handler, "EUR")
+	if usd.Currency != "USD" || eur.Currency != "EUR" || usd.CostTotal <= 0 || !nearlyEqual(eur.CostTotal, usd.CostTotal*0.5) {
+		t.Fatalf("expected EUR total to be half the USD total, got %#v and %#v", usd, eur)
+	}
+}
+
+func summaryInCurrency(t *testing.T, handler http.Handler, currency string) database.ActivitySummary {
+	t.Helper()
+
+	rr := performRaw(t, handler, http.MethodGet, "/api/activity/summary?currency="+currency, "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var summary database.ActivitySummary
+	if err := json.Unmarshal(rr.Body.Bytes(), &summary); err != nil {
+		t.Fatal(err)
+	}
+	return summary
+}
@@ -0,0 +1,181 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// createMemoryHandler godoc
+// @Summary Create turn memory
+// @Description Store a compact summary of a session turn. tools_used, files_touched, key_decisions and tags must be arrays of strings.
+// @Tags memory
+// @Accept json
+// @Produce json
+// @Param memory body database.TurnMemory true "Turn memory"
+// @Success 201 {object} database.TurnMemory
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/memory [post]
+func (s *Server) createMemoryHandler(c *gin.Context) {
+	var memory database.TurnMemory
+	if err := c.ShouldBindJSON(&memory); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	memory.ID = ""
+
+	if err := s.db.CreateTurnMemory(c.Request.Context(), &memory); err != nil {
+		if errors.Is(err, database.ErrInvalidTurnMemory) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to create memory"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, memory)
+}
+
+// listMemoriesHandler godoc
+// @Summary List turn memories
+// @Description List turn memories, newest first, with optional filters.
+// @Tags memory
+// @Produce json
+// @Param session_key query string false "Filter by session_key"
+// @Param project query string false "Only memories from sessions with activity in this project"
+// @Param tag query string false "Only memories carrying this tag"
+// @Param file query string false "Only memories that touched this file"
+// @Param date query string false "Filter by created_at date (YYYY-MM-DD)"
+// @Param from query string false "Inclusive lower bound on created_at (RFC3339)"
+// @Param to query string false "Exclusive upper bound on created_at (RFC3339)"
+// @Param limit query int false "Maximum rows (1-1000)"
+// @Success 200 {array} database.TurnMemory
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/memory [get]
+func (s *Server) listMemoriesHandler(c *gin.Context) {
+	filters, err := memoryFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	memories, err := s.db.ListTurnMemories(c.Request.Context(), filters)
+	if err != nil {
+		if isActivityQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list memories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, memories)
+}
+
+// searchMemoriesHandler godoc
+// @Summary Search turn memories
+// @Description Full-text search over memory summary, key_decisions, context_snippet and tags. Every term must match; results are ranked best first with matched terms wrapped in <mark> inside the HTML-escaped snippet.
+// @Tags memory
+// @Produce json
+// @Param q query string true "Search terms"
+// @Param project query string false "Only memories from sessions with activity in this project"
+// @Param session_key query string false "Filter by session_key"
+// @Param tag query string false "Only memories carrying this tag"
+// @Param file query string false "Only memories that touched this file"
+// @Param date query string false "Filter by created_at date (YYYY-MM-DD)"
+// @Param from query string false "Inclusive lower bound on created_at (RFC3339)"
+// @Param to query string false "Exclusive upper bound on created_at (RFC3339)"
+// @Param limit query int false "Maximum results (1-1000, default 20)"
+// @Success 200 {array} database.TurnMemorySearchResult
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/memory/search [get]
+func (s *Server) searchMemoriesHandler(c *gin.Context) {
+	filters, err := memoryFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	results, err := s.db.SearchTurnMemories(c.Request.Context(), c.Query("q"), filters)
+	if err != nil {
+		if isActivityQueryError(err) || errors.Is(err, database.ErrInvalidSearchQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to search memories"})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
+
+func memoryFiltersFromQuery(c *gin.Context) (database.MemoryFilters, error) {
+	filters := database.MemoryFilters{
+		SessionKey: c.Query("session_key"),
+		Project:    c.Query("project"),
+		Tag:        c.Query("tag"),
+		File:       c.Query("file"),
+		Date:       c.Query("date"),
+		From:       c.Query("from"),
+		To:         c.Query("to"),
+	}
+	if raw := strings.TrimSpace(c.Query("limit")); raw != "" {
+		limit, err := strconv.Atoi(raw)
+		if err != nil || limit <= 0 {
+			return database.MemoryFilters{}, database.ErrInvalidLimit
+		}
+		filters.Limit = limit
+	}
+	return filters, nil
+}
+
+// getMemoryHandler godoc
+// @Summary Get turn memory
+// @Tags memory
+// @Produce json
+// @Param id path string true "Memory ID"
+// @Success 200 {object} database.TurnMemory
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/memory/{id} [get]
+func (s *Server) getMemoryHandler(c *gin.Context) {
+	memory, err := s.db.GetTurnMemory(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		if errors.Is(err, database.ErrTurnMemoryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load memory"})
+		return
+	}
+
+	c.JSON(http.StatusOK, memory)
+}
+
+// deleteMemoryHandler godoc
+// @Summary Delete turn memory
+// @Tags memory
+// @Param id path string true "Memory ID"
+// @Success 204
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/memory/{id} [delete]
+func (s *Server) deleteMemoryHandler(c *gin.Context) {
+	if err := s.db.DeleteTurnMemory(c.Request.Context(), c.Param("id")); err != nil {
+		if errors.Is(err, database.ErrTurnMemoryNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to delete memory"})
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
@@ -0,0 +1,103 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestMemoryEndpointsCreateListGetAndDelete(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	created := performJSON(t, handler, http.MethodPost, "/api/memory", map[string]any{
+		"session_key":   "mem-session",
+		"summary":       "added memory endpoints",
+		"tools_used":    []string{"go"},
+		"files_touched": []string{"internal/server/memory_handlers.go"},
+		"tags":          []string{"api"},
+	})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, created.Code, created.Body.String())
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(created.Body.Bytes(), &raw); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := raw["key_decisions"].([]any); !ok {
+		t.Fatalf("expected key_decisions to be a JSON array, got %#v", raw["key_decisions"])
+	}
+	id, _ := raw["id"].(string)
+
+	list := performRaw(t, handler, http.MethodGet, "/api/memory?tag=api&file=internal/server/memory_handlers.go", "", "")
+	var memories []database.TurnMemory
+	if err := json.Unmarshal(list.Body.Bytes(), &memories); err != nil {
+		t.Fatalf("expected valid json response: %v body=%s", err, list.Body.String())
+	}
+	if len(memories) != 1 || memories[0].ID != id || memories[0].ToolsUsed[0] != "go" {
+		t.Fatalf("expected created memory in list, got %#v", memories)
+	}
+
+	if got := performRaw(t, handler, http.MethodGet, "/api/memory/"+id, "", ""); got.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, got.Code, got.Body.String())
+	}
+	if deleted := performRaw(t, handler, http.MethodDelete, "/api/memory/"+id, "", ""); deleted.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusNoContent, deleted.Code, deleted.Body.String())
+	}
+	if missing := performRaw(t, handler, http.MethodGet, "/api/memory/"+id, "", ""); missing.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusNotFound, missing.Code, missing.Body.String())
+	}
+}
+
+func TestCreateMemoryRejectsNonArrayJSONFields(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for _, payload := range []map[string]any{
+		{"session_key": "mem-bad", "summary": "s", "tags": "[\"api\"]"},
+		{"session_key": "mem-bad", "summary": "s", "tools_used": []any{1, 2}},
+		{"summary": "missing session"},
+	} {
+		rr := performJSON(t, handler, http.MethodPost, "/api/memory", payload)
+		if rr.Code != http.StatusBadRequest {
+			t.Fatalf("expected status %d for %#v, got %d body=%s", http.StatusBadRequest, payload, rr.Code, rr.Body.String())
+		}
+	}
+}
+
+func TestSearchMemoryEndpointFiltersByProject(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	createActivity(t, handler, map[string]any{"session_key": "search-alpha", "project_tag": "proj-alpha"})
+	createActivity(t, handler, map[string]any{"session_key": "search-beta", "project_tag": "proj-beta"})
+	for _, sessionKey := range []string{"search-alpha", "search-beta"} {
+		rr := performJSON(t, handler, http.MethodPost, "/api/memory", map[string]any{
+			"session_key":   sessionKey,
+			"summary":       "decided the queue format",
+			"key_decisions": []string{"markdown queue"},
+		})
+		if rr.Code != http.StatusCreated {
+			t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := performRaw(t, handler, http.MethodGet, "/api/memory/search?q=queue+format&project=proj-beta", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var results []database.TurnMemorySearchResult
+	if err := json.Unmarshal(rr.Body.Bytes(), &results); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	if len(results) != 1 || results[0].SessionKey != "search-beta" || results[0].Snippet == "" {
+		t.Fatalf("expected one highlighted beta result, got %#v", results)
+	}
+
+	if missing := performRaw(t, handler, http.MethodGet, "/api/memory/search", "", ""); missing.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d without q, got %d", http.StatusBadRequest, missing.Code)
+	}
+}
@@ -0,0 +1,98 @@
+package server
+
+import (
+	"bytes"
+	"net/http"
+	"strconv"
+	"time"
+
+	"clawtivity/internal/database"
+	"clawtivity/internal/metrics"
+	"github.com/gin-gonic/gin"
+)
+
+var (
+	httpRequestDurationSeconds = metrics.Default.NewHistogramVec(
+		"clawtivity_http_request_duration_seconds",
+		"HTTP request latency by method, route template and status code.",
+		metrics.DefaultBuckets,
+		"method", "route", "status",
+	)
+	ingestDurationSeconds = metrics.Default.NewHistogramVec(
+		"clawtivity_ingest_duration_seconds",
+		"Time to validate, resolve and store an activity ingest request by mode (single, batch).",
+		metrics.DefaultBuckets,
+		"mode",
+	)
+)
+
+// metricsMiddleware records request latency per route template so path
+// parameters such as memory IDs do not explode the series count.
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		startedAt := time.Now()
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestDurationSeconds.Observe(time.Since(startedAt).Seconds(), c.Request.Method, route, strconv.Itoa(c.Writer.Status()))
+	}
+}
+
+func observeIngestDuration(mode string, startedAt time.Time) {
+	ingestDurationSeconds.Observe(time.Since(startedAt).Seconds(), mode)
+}
+
+// metricsHandler godoc
+// @Summary Prometheus metrics
+// @Description Ingest and queue counters, HTTP, ingest and database latency histograms, pricing refresh outcomes, and activity, token and cost gauges per project and model, in Prometheus text format.
+// @Tags health
+// @Produce plain
+// @Success 200 {string} string
+// @Router /metrics [get]
+func (s *Server) metricsHandler(c *gin.Context) {
+	var out bytes.Buffer
+	metrics.Default.Write(&out)
+
+	metrics.WriteFamily(&out, "clawtivity_activities_created_total", "Activities stored through the API.", "counter", nil,
+		[]metrics.Sample{{Value: float64(metricsCounters.activitiesCreated.Load())}})
+	metrics.WriteFamily(&out, "clawtivity_queue_flush_total", "Plugin queue flush attempts by result.", "counter", []string{"result"},
+		[]metrics.Sample{
+			{Labels: []string{"attempted"}, Value: float64(metricsCounters.queueFlushAttempted.Load())},
+			{Labels: []string{"succeeded"}, Value: float64(metricsCounters.queueFlushSucceeded.Load())},
+			{Labels: []string{"failed"}, Value: float64(metricsCounters.queueFlushFailed.Load())},
+		})
+	metrics.WriteFamily(&out, "clawtivity_queue_depth", "Activities waiting in the fallback queue.", "gauge", nil,
+		[]metrics.Sample{{Value: float64(currentQueueDepth())}})
+
+	summary, err := s.db.SummarizeActivityGroups(c.Request.Context(), database.ActivityFilters{}, []string{"project", "model"})
+	if err != nil {
+		logEvent("warn", "metrics_activity_totals_failed", map[string]any{"error": err.Error()}, currentQueueDepth())
+	} else {
+		writeActivityGauges(&out, summary.Groups)
+	}
+
+	c.Data(http.StatusOK, "text/plain; version=0.0.4; charset=utf-8", out.Bytes())
+}
+
+func writeActivityGauges(out *bytes.Buffer, groups []database.ActivityGroup) {
+	labels := []string{"project", "model"}
+	activities := make([]metrics.Sample, 0, len(groups))
+	tokens := make([]metrics.Sample, 0, 2*len(groups))
+	cost := make([]metrics.Sample, 0, len(groups))
+	for _, group := range groups {
+		project, model := group.Keys["project"], group.Keys["model"]
+		activities = append(activities, metrics.Sample{Labels: []string{project, model}, Value: float64(group.Count)})
+		tokens = append(tokens,
+			metrics.Sample{Labels: []string{project, model, "in"}, Value: float64(group.TokensInTotal)},
+			metrics.Sample{Labels: []string{project, model, "out"}, Value: float64(group.TokensOutTotal)},
+		)
+		cost = append(cost, metrics.Sample{Labels: []string{project, model}, Value: group.CostTotal})
+	}
+
+	metrics.WriteFamily(out, "clawtivity_activities", "Stored activities by project and model.", "gauge", labels, activities)
+	metrics.WriteFamily(out, "clawtivity_tokens", "Stored token totals by project, model and direction.", "gauge", []string{"project", "model", "direction"}, tokens)
+	metrics.WriteFamily(out, "clawtivity_cost", "Stored estimated cost totals by project and model.", "gauge", labels, cost)
+}
@@ -0,0 +1,51 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+)
+
+func TestMetricsEndpointExposesCountersHistogramsAndActivityGauges(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	createActivity(t, handler, map[string]any{
+		"session_key": "metrics-1",
+		"model":       "gpt-5",
+		"tokens_in":   120,
+		"tokens_out":  30,
+		"project_tag": "clawtivity",
+		"channel":     "webchat",
+		"status":      "success",
+		"user_id":     "u-1",
+	})
+	performJSON(t, handler, http.MethodGet, "/api/memory/missing", nil)
+
+	rr := performRaw(t, handler, http.MethodGet, "/metrics", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d body=%s", rr.Code, rr.Body.String())
+	}
+	if contentType := rr.Header().Get("Content-Type"); !strings.HasPrefix(contentType, "text/plain; version=0.0.4") {
+		t.Fatalf("expected Prometheus text content type, got %q", contentType)
+	}
+
+	body := rr.Body.String()
+	for _, want := range []string{
+		"# TYPE clawtivity_activities_created_total counter",
+		`clawtivity_queue_flush_total{result="failed"}`,
+		"# TYPE clawtivity_queue_depth gauge",
+		`clawtivity_http_request_duration_seconds_count{method="POST",route="/api/activity",status="201"}`,
+		`clawtivity_http_request_duration_seconds_count{method="GET",route="/api/memory/:id",status="404"}`,
+		`clawtivity_ingest_duration_seconds_count{mode="single"}`,
+		`clawtivity_db_query_duration_seconds_count{operation="create",table="activity_feed"}`,
+		`clawtivity_activities{project="clawtivity",model="gpt-5"} 1`,
+		`clawtivity_tokens{project="clawtivity",model="gpt-5",direction="in"} 120`,
+		`clawtivity_tokens{project="clawtivity",model="gpt-5",direction="out"} 30`,
+		`clawtivity_cost{project="clawtivity",model="gpt-5"}`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected metrics to contain %q, got:\n%s", want, body)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+type modelPricingInput struct {
+	database.ModelPricing
+	// Verified stamps last_verified_at with the current time.
+	Verified bool `json:"verified"`
+}
+
+// listModelPricingHandler godoc
+// @Summary List model pricing
+// @Description List the pricing catalog ordered by provider, model and newest effective_from first.
+// @Tags pricing
+// @Produce json
+// @Param provider query string false "Filter by provider"
+// @Success 200 {array} database.ModelPricing
+// @Failure 500 {object} APIError
+// @Router /api/pricing [get]
+func (s *Server) listModelPricingHandler(c *gin.Context) {
+	rows, err := s.db.ListModelPricing(c.Request.Context(), c.Query("provider"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list pricing"})
+		return
+	}
+
+	c.JSON(http.StatusOK, rows)
+}
+
+// listPricingSourcesHandler godoc
+// @Summary List pricing sources
+// @Description List the configured pricing sources in refresh order with the time and outcome of their latest refresh.
+// @Tags pricing
+// @Produce json
+// @Success 200 {array} database.PricingSourceStatus
+// @Failure 500 {object} APIError
+// @Router /api/pricing/sources [get]
+func (s *Server) listPricingSourcesHandler(c *gin.Context) {
+	statuses, err := s.db.ListPricingSources(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list pricing sources"})
+		return
+	}
+
+	c.JSON(http.StatusOK, statuses)
+}
+
+// createModelPricingHandler godoc
+// @Summary Create model pricing
+// @Description Add an effective-dated pricing row for a provider/model. effective_from defaults to now, currency to USD and source to manual. A row with the same provider, model and effective_from is a conflict.
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param pricing body modelPricingInput true "Pricing row"
+// @Success 201 {object} database.ModelPricing
+// @Failure 400 {object} APIError
+// @Failure 409 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing [post]
+func (s *Server) createModelPricingHandler(c *gin.Context) {
+	var input modelPricingInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pricing := input.ModelPricing
+	pricing.ID = ""
+	pricing.LastVerifiedAt = nil
+	if input.Verified {
+		verifiedAt := time.Now().UTC()
+		pricing.LastVerifiedAt = &verifiedAt
+	}
+
+	if err := s.db.CreateModelPricing(c.Request.Context(), &pricing); err != nil {
+		writeModelPricingError(c, err, "failed to create pricing")
+		return
+	}
+
+	c.JSON(http.StatusCreated, pricing)
+}
+
+// updateModelPricingHandler godoc
+// @Summary Update model pricing
+// @Description Correct rates or metadata on a pricing row. Omitted fields are unchanged; "verified": true stamps last_verified_at and clears is_stale.
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param id path string true "Pricing ID"
+// @Param update body database.ModelPricingUpdate true "Fields to change"
+// @Success 200 {object} database.ModelPricing
+// @Failure 400 {object} APIError
+// @Failure 404 {object} APIError
+// @Failure 409 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing/{id} [patch]
+func (s *Server) updateModelPricingHandler(c *gin.Context) {
+	var update database.ModelPricingUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	pricing, err := s.db.UpdateModelPricing(c.Request.Context(), c.Param("id"), update)
+	if err != nil {
+		writeModelPricingError(c, err, "failed to update pricing")
+		return
+	}
+
+	c.JSON(http.StatusOK, pricing)
+}
+
+// deleteModelPricingHandler godoc
+// @Summary Delete model pricing
+// @Tags pricing
+// @Param id path string true "Pricing ID"
+// @Success 204
+// @Failure 404 {object} APIError
+// @Failure 409 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing/{id} [delete]
+func (s *Server) deleteModelPricingHandler(c *gin.Context) {
+	if err := s.db.DeleteModelPricing(c.Request.Context(), c.Param("id")); err != nil {
+		writeModelPricingError(c, err, "failed to delete pricing")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listUnmatchedModelsHandler godoc
+// @Summary List unpriced models
+// @Description Models seen in activities that no pricing row or alias matched (cost_match=missing), with activity counts and token volume, most frequent first. Accepts the activity list filters.
+// @Tags pricing
+// @Produce json
+// @Param project query []string false "Filter by project_tag (repeated or comma-separated)"
+// @Param model query []string false "Filter by model (repeated or comma-separated)"
+// @Param from query string false "Inclusive lower bound on created_at (RFC3339)"
+// @Param to query string false "Exclusive upper bound on created_at (RFC3339)"
+// @Success 200 {array} database.UnmatchedModel
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing/unmatched [get]
+func (s *Server) listUnmatchedModelsHandler(c *gin.Context) {
+	filters, err := activityFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	models, err := s.db.ListUnmatchedModels(c.Request.Context(), filters)
+	if err != nil {
+		if isActivityQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list unmatched models"})
+		return
+	}
+
+	c.JSON(http.StatusOK, models)
+}
+
+// listModelAliasesHandler godoc
+// @Summary List model aliases
+// @Tags pricing
+// @Produce json
+// @Success 200 {array} database.ModelAlias
+// @Failure 500 {object} APIError
+// @Router /api/pricing/aliases [get]
+func (s *Server) listModelAliasesHandler(c *gin.Context) {
+	aliases, err := s.db.ListModelAliases(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list model aliases"})
+		return
+	}
+
+	c.JSON(http.StatusOK, aliases)
+}
+
+// createModelAliasHandler godoc
+// @Summary Create model alias
+// @Description Price activities reported under alias with the pricing rows of target. New activities pick it up immediately; run POST /api/pricing/recompute to reprice existing ones.
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param alias body database.ModelAlias true "Alias"
+// @Success 201 {object} database.ModelAlias
+// @Failure 400 {object} APIError
+// @Failure 409 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing/aliases [post]
+func (s *Server) createModelAliasHandler(c *gin.Context) {
+	var alias database.ModelAlias
+	if err := c.ShouldBindJSON(&alias); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias.ID = ""
+	if err := s.db.CreateModelAlias(c.Request.Context(), &alias); err != nil {
+		writeModelAliasError(c, err, "failed to create model alias")
+		return
+	}
+
+	c.JSON(http.StatusCreated, alias)
+}
+
+// updateModelAliasHandler godoc
+// @Summary Update model alias
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param id path string true "Alias ID"
+// @Param update body database.ModelAliasUpdate true "Fields to change"
+// @Success 200 {object} database.ModelAlias
+// @Failure 400 {object} APIError
+// @Failure 404 {object} APIError
+// @Failure 409 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing/aliases/{id} [patch]
+func (s *Server) updateModelAliasHandler(c *gin.Context) {
+	var update database.ModelAliasUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	alias, err := s.db.UpdateModelAlias(c.Request.Context(), c.Param("id"), update)
+	if err != nil {
+		writeModelAliasError(c, err, "failed to update model alias")
+		return
+	}
+
+	c.JSON(http.StatusOK, alias)
+}
+
+// deleteModelAliasHandler godoc
+// @Summary Delete model alias
+// @Tags pricing
+// @Param id path string true "Alias ID"
+// @Success 204
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing/aliases/{id} [delete]
+func (s *Server) deleteModelAliasHandler(c *gin.Context) {
+	if err := s.db.DeleteModelAlias(c.Request.Context(), c.Param("id")); err != nil {
+		writeModelAliasError(c, err, "failed to delete model alias")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+type costRecomputeInput struct {
+	Models       []string `json:"models"`
+	Projects     []string `json:"projects"`
+	From         string   `json:"from"`
+	To           string   `json:"to"`
+	OnlyZeroCost bool     `json:"only_zero_cost"`
+	DryRun       bool     `json:"dry_run"`
+	BatchSize    int      `json:"batch_size"`
+	Currency     string   `json:"currency"`
+}
+
+// recomputeCostsHandler godoc
+// @Summary Recompute activity costs
+// @Description Re-resolve cost_estimate and pricing_id for matching activities against the current pricing catalog, in batches. from is inclusive and to exclusive (RFC3339). only_zero_cost limits the run to uncosted rows; dry_run reports the changes without writing them. Report totals are converted to currency (default USD) at the rate in effect at each activity's created_at.
+// @Tags pricing
+// @Accept json
+// @Produce json
+// @Param request body costRecomputeInput false "Recompute scope"
+// @Success 200 {object} database.CostRecomputeReport
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/pricing/recompute [post]
+func (s *Server) recomputeCostsHandler(c *gin.Context) {
+	var input costRecomputeInput
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&input); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+
+	report, err := s.db.RecomputeActivityCosts(c.Request.Context(), database.CostRecomputeRequest{
+		Filters: database.ActivityFilters{
+			Models:      input.Models,
+			ProjectTags: input.Projects,
+			From:        input.From,
+			To:          input.To,
+			Currency:    input.Currency,
+		},
+		OnlyZeroCost: input.OnlyZeroCost,
+		DryRun:       input.DryRun,
+		BatchSize:    input.BatchSize,
+	})
+	if err != nil {
+		if isActivityQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to recompute costs"})
+		return
+	}
+
+	logEvent("info", "pricing_recompute", map[string]any{
+		"dry_run":    report.DryRun,
+		"scanned":    report.Scanned,
+		"changed":    report.Changed,
+		"cost_delta": report.CostDelta,
+		"currency":   report.Currency,
+	}, currentQueueDepth())
+	c.JSON(http.StatusOK, report)
+}
+
+func writeModelPricingError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrInvalidModelPricing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrModelPricingNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrModelPricingConflict), errors.Is(err, database.ErrModelPricingInUse):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+func writeModelAliasError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrInvalidModelAlias):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrModelAliasNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrModelAliasConflict):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
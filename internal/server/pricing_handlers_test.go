@@ -0,0 +1,196 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestPricingEndpointsCreateListUpdateAndDelete(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	payload := map[string]any{
+		"provider":           "self-hosted",
+		"model":              "llama-4-70b",
+		"effective_from":     "2026-03-01T00:00:00Z",
+		"input_cost_per_1m":  0.2,
+		"output_cost_per_1m": 0.8,
+	}
+	created := performJSON(t, handler, http.MethodPost, "/api/pricing", payload)
+	if created.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, created.Code, created.Body.String())
+	}
+	var pricing database.ModelPricing
+	if err := json.Unmarshal(created.Body.Bytes(), &pricing); err != nil {
+		t.Fatal(err)
+	}
+
+	if conflict := performJSON(t, handler, http.MethodPost, "/api/pricing", payload); conflict.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusConflict, conflict.Code, conflict.Body.String())
+	}
+	if invalid := performJSON(t, handler, http.MethodPost, "/api/pricing", map[string]any{"provider": "self-hosted"}); invalid.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, invalid.Code, invalid.Body.String())
+	}
+
+	list := performRaw(t, handler, http.MethodGet, "/api/pricing?provider=self-hosted", "", "")
+	var rows []database.ModelPricing
+	if err := json.Unmarshal(list.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("expected valid json response: %v body=%s", err, list.Body.String())
+	}
+	if len(rows) != 1 || rows[0].ID != pricing.ID {
+		t.Fatalf("expected created row in provider listing, got %#v", rows)
+	}
+
+	patched := performJSON(t, handler, http.MethodPatch, "/api/pricing/"+pricing.ID, map[string]any{"output_cost_per_1m": 1.0, "verified": true})
+	if patched.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, patched.Code, patched.Body.String())
+	}
+	var updated database.ModelPricing
+	if err := json.Unmarshal(patched.Body.Bytes(), &updated); err != nil {
+		t.Fatal(err)
+	}
+	if updated.OutputCostPer1M != 1.0 || updated.LastVerifiedAt == nil {
+		t.Fatalf("expected corrected and verified row, got %#v", updated)
+	}
+
+	if deleted := performRaw(t, handler, http.MethodDelete, "/api/pricing/"+pricing.ID, "", ""); deleted.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusNoContent, deleted.Code, deleted.Body.String())
+	}
+	if missing := performJSON(t, handler, http.MethodPatch, "/api/pricing/"+pricing.ID, map[string]any{"verified": true}); missing.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusNotFound, missing.Code, missing.Body.String())
+	}
+}
+
+func TestRecomputeCostsEndpointReportsDryRun(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	createActivity(t, handler, map[string]any{
+		"session_key": "recompute-1",
+		"model":       "self-hosted-model",
+		"tokens_in":   1000000,
+		"tokens_out":  0,
+		"project_tag": "clawtivity",
+		"channel":     "webchat",
+		"status":      "success",
+		"user_id":     "u-1",
+	})
+	created := performJSON(t, handler, http.MethodPost, "/api/pricing", map[string]any{
+		"provider":          "self-hosted",
+		"model":             "self-hosted-model",
+		"effective_from":    "2020-01-01T00:00:00Z",
+		"input_cost_per_1m": 3.0,
+	})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, created.Code, created.Body.String())
+	}
+
+	rr := performJSON(t, handler, http.MethodPost, "/api/pricing/recompute", map[string]any{
+		"models":         []string{"self-hosted-model"},
+		"only_zero_cost": true,
+		"dry_run":        true,
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var report database.CostRecomputeReport
+	if err := json.Unmarshal(rr.Body.Bytes(), &report); err != nil {
+		t.Fatal(err)
+	}
+	if !report.DryRun || report.Changed != 1 || report.CostDelta != 3.0 {
+		t.Fatalf("expected one row to change by 3.00, got %#v", report)
+	}
+
+	if invalid := performJSON(t, handler, http.MethodPost, "/api/pricing/recompute", map[string]any{"from": "soon"}); invalid.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, invalid.Code, invalid.Body.String())
+	}
+}
+
+func TestPricingSourcesEndpointReportsRefreshStatus(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "pricing.yaml")
+	content := "models:\n  - provider: local\n    model: qwen3-coder\n    input_cost_per_1m: 0.1\n    output_cost_per_1m: 0.4\n"
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("CLAWTIVITY_PRICING_SOURCES", "yaml="+path)
+
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	rr := performRaw(t, handler, http.MethodGet, "/api/pricing/sources", "", "")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+	var statuses []database.PricingSourceStatus
+	if err := json.Unmarshal(rr.Body.Bytes(), &statuses); err != nil {
+		t.Fatal(err)
+	}
+	if len(statuses) != 1 || statuses[0].Name != "yaml:"+path || statuses[0].LastSuccessAt == nil || statuses[0].ModelsImported != 1 {
+		t.Fatalf("expected the yaml source to report a successful import, got %#v", statuses)
+	}
+}
+
+func TestModelAliasEndpointsAndUnmatchedReport(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	createActivity(t, handler, map[string]any{
+		"session_key": "unmatched-1",
+		"model":       "in-house-7b",
+		"tokens_in":   120,
+		"tokens_out":  30,
+		"project_tag": "clawtivity",
+		"category":    "general",
+		"thinking":    "low",
+		"channel":     "webchat",
+		"status":      "success",
+		"user_id":     "u1",
+	})
+
+	rr := performRaw(t, handler, http.MethodGet, "/api/pricing/unmatched?project=clawtivity", "", "")
+	var unmatched []database.UnmatchedModel
+	if err := json.Unmarshal(rr.Body.Bytes(), &unmatched); err != nil {
+		t.Fatal(err)
+	}
+	if rr.Code != http.StatusOK || len(unmatched) != 1 || unmatched[0].Model != "in-house-7b" || unmatched[0].TokensInTotal != 120 {
+		t.Fatalf("expected in-house-7b in the unmatched report, got %d %s", rr.Code, rr.Body.String())
+	}
+
+	created := performJSON(t, handler, http.MethodPost, "/api/pricing/aliases", map[string]any{"alias": "in-house-7b", "target": "gpt-5-mini"})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, created.Code, created.Body.String())
+	}
+	var alias database.ModelAlias
+	if err := json.Unmarshal(created.Body.Bytes(), &alias); err != nil {
+		t.Fatal(err)
+	}
+	if conflict := performJSON(t, handler, http.MethodPost, "/api/pricing/aliases", map[string]any{"alias": "in-house-7b", "target": "gpt-5"}); conflict.Code != http.StatusConflict {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusConflict, conflict.Code, conflict.Body.String())
+	}
+
+	list := performRaw(t, handler, http.MethodGet, "/api/pricing/aliases", "", "")
+	var aliases []database.ModelAlias
+	if err := json.Unmarshal(list.Body.Bytes(), &aliases); err != nil {
+		t.Fatal(err)
+	}
+	// The two seeded codex-mini aliases plus the new one.
+	if len(aliases) != 3 {
+		t.Fatalf("expected 3 aliases, got %s", list.Body.String())
+	}
+
+	updated := performJSON(t, handler, http.MethodPatch, "/api/pricing/aliases/"+alias.ID, map[string]any{"target": "gpt-5"})
+	if updated.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, updated.Code, updated.Body.String())
+	}
+	if deleted := performRaw(t, handler, http.MethodDelete, "/api/pricing/aliases/"+alias.ID, "", ""); deleted.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, deleted.Code)
+	}
+	if missing := performRaw(t, handler, http.MethodDelete, "/api/pricing/aliases/"+alias.ID, "", ""); missing.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, missing.Code)
+	}
+}
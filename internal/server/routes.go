@@ -21,17 +21,54 @@ func (s *Server) RegisterRoutes() http.Handler {
 	r.Use(cors.New(cors.Config{
 		AllowOrigins:     resolveCorsOrigins(),
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS", "PATCH"},
-		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-API-Key"},
+		AllowHeaders:     []string{"Accept", "Authorization", "Content-Type", "X-API-Key", "Idempotency-Key"},
 		AllowCredentials: true, // Enable cookies/auth
 	}))
+	r.Use(metricsMiddleware())
 
 	r.GET("/", s.HelloWorldHandler)
 
 	r.GET("/health", s.healthHandler)
+	r.GET("/metrics", s.metricsHandler)
 	r.POST("/api/activity", activityAPIKeyMiddleware(), s.createActivityHandler)
+	r.POST("/api/activity/batch", activityAPIKeyMiddleware(), s.createActivityBatchHandler)
 	r.GET("/api/activity", s.listActivitiesHandler)
 	r.GET("/api/activity/summary", s.activitySummaryHandler)
+	r.PATCH("/api/activity/:id/category", activityAPIKeyMiddleware(), s.correctActivityCategoryHandler)
 	r.GET("/api/projects", s.listProjectsHandler)
+	r.POST("/api/memory", activityAPIKeyMiddleware(), s.createMemoryHandler)
+	r.GET("/api/memory", s.listMemoriesHandler)
+	r.GET("/api/memory/search", s.searchMemoriesHandler)
+	r.GET("/api/memory/:id", s.getMemoryHandler)
+	r.DELETE("/api/memory/:id", activityAPIKeyMiddleware(), s.deleteMemoryHandler)
+	r.GET("/api/sessions", s.listSessionsHandler)
+	r.GET("/api/sessions/:key", s.getSessionHandler)
+	r.GET("/api/pricing", s.listModelPricingHandler)
+	r.GET("/api/pricing/sources", s.listPricingSourcesHandler)
+	r.GET("/api/pricing/unmatched", s.listUnmatchedModelsHandler)
+	r.GET("/api/pricing/aliases", s.listModelAliasesHandler)
+	r.POST("/api/pricing/aliases", activityAPIKeyMiddleware(), s.createModelAliasHandler)
+	r.PATCH("/api/pricing/aliases/:id", activityAPIKeyMiddleware(), s.updateModelAliasHandler)
+	r.DELETE("/api/pricing/aliases/:id", activityAPIKeyMiddleware(), s.deleteModelAliasHandler)
+	r.POST("/api/pricing", activityAPIKeyMiddleware(), s.createModelPricingHandler)
+	r.POST("/api/pricing/recompute", activityAPIKeyMiddleware(), s.recomputeCostsHandler)
+	r.PATCH("/api/pricing/:id", activityAPIKeyMiddleware(), s.updateModelPricingHandler)
+	r.DELETE("/api/pricing/:id", activityAPIKeyMiddleware(), s.deleteModelPricingHandler)
+	r.GET("/api/exchange-rates", s.listExchangeRatesHandler)
+	r.POST("/api/exchange-rates", activityAPIKeyMiddleware(), s.importExchangeRatesHandler)
+	r.GET("/api/budgets", s.listBudgetsHandler)
+	r.POST("/api/budgets", activityAPIKeyMiddleware(), s.createBudgetHandler)
+	r.PATCH("/api/budgets/:id", activityAPIKeyMiddleware(), s.updateBudgetHandler)
+	r.DELETE("/api/budgets/:id", activityAPIKeyMiddleware(), s.deleteBudgetHandler)
+	r.GET("/api/webhooks", activityAPIKeyMiddleware(), s.listWebhooksHandler)
+	r.POST("/api/webhooks", activityAPIKeyMiddleware(), s.createWebhookHandler)
+	r.PATCH("/api/webhooks/:id", activityAPIKeyMiddleware(), s.updateWebhookHandler)
+	r.DELETE("/api/webhooks/:id", activityAPIKeyMiddleware(), s.deleteWebhookHandler)
+	r.GET("/api/webhooks/:id/deliveries", activityAPIKeyMiddleware(), s.listWebhookDeliveriesHandler)
+	r.POST("/api/webhooks/:id/test", activityAPIKeyMiddleware(), s.testWebhookHandler)
+	r.GET("/api/classifier/rules", s.classifierRulesHandler)
+	r.POST("/api/classifier/explain", s.explainClassificationHandler)
+	r.POST("/api/classifier/reclassify", activityAPIKeyMiddleware(), s.reclassifyHandler)
 	r.GET("/swagger/*any", ginSwagger.WrapHandler(swaggerFiles.Handler))
 
 	staticFiles, _ := fs.Sub(web.Files, "assets")
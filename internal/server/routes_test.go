@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/gin-gonic/gin"
@@ -72,3 +73,25 @@ func TestResolveCorsOriginsUsesFallback(t *testing.T) {
 		t.Fatalf("expected fallback %v, got %v", want, got)
 	}
 }
+
+func TestCorsPreflightAllowsIdempotencyKey(t *testing.T) {
+	s := &Server{}
+	r := s.RegisterRoutes()
+
+	req, err := http.NewRequest(http.MethodOptions, "/api/activity", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	req.Header.Set("Origin", "http://localhost:5173")
+	req.Header.Set("Access-Control-Request-Method", http.MethodPost)
+	req.Header.Set("Access-Control-Request-Headers", "Content-Type, Idempotency-Key")
+	rr := httptest.NewRecorder()
+	r.ServeHTTP(rr, req)
+
+	if rr.Code != http.StatusNoContent {
+		t.Fatalf("expected preflight to succeed, got %d", rr.Code)
+	}
+	if allowed := rr.Header().Get("Access-Control-Allow-Headers"); !strings.Contains(strings.ToLower(allowed), "idempotency-key") {
+		t.Fatalf("expected Idempotency-Key to be allowed, got %q", allowed)
+	}
+}
@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"fmt"
 	"net/http"
 	"os"
@@ -16,6 +17,8 @@ type Server struct {
 	port int
 
 	db database.Service
+
+	classifierSignals classifierSignalConfig
 }
 
 func NewServer() (*http.Server, error) {
@@ -25,11 +28,14 @@ func NewServer() (*http.Server, error) {
 		return nil, err
 	}
 	NewServer := &Server{
-		port: port,
-		db:   db,
+		port:              port,
+		db:                db,
+		classifierSignals: resolveClassifierSignalConfig(),
 	}
 
-	flushQueueOnStartup(NewServer.db)
+	watchClassifierRules(context.Background())
+	trainClassifierModel(context.Background(), NewServer.db)
+	flushQueueOnStartup(NewServer.db, NewServer.classifierSignals)
 
 	// Declare Server config
 	server := &http.Server{
@@ -0,0 +1,69 @@
+package server
+
+import (
+	"errors"
+	"net/http"
+
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+// listSessionsHandler godoc
+// @Summary List sessions
+// @Description List sessions, most recently active first, aggregating activity rows by session_key. Accepts the activity filters from /api/activity to narrow which rows count.
+// @Tags sessions
+// @Produce json
+// @Param project query []string false "Filter by project_tag (repeated or comma-separated)"
+// @Param model query []string false "Filter by model (repeated or comma-separated)"
+// @Param user_id query []string false "Filter by user_id (repeated or comma-separated)"
+// @Param channel query []string false "Filter by channel (repeated or comma-separated)"
+// @Param from query string false "Inclusive lower bound on created_at (RFC3339)"
+// @Param to query string false "Exclusive upper bound on created_at (RFC3339)"
+// @Param limit query int false "Maximum sessions (1-1000, default 100)"
+// @Success 200 {array} database.SessionSummary
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/sessions [get]
+func (s *Server) listSessionsHandler(c *gin.Context) {
+	filters, err := activityFiltersFromQuery(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sessions, err := s.db.ListSessions(c.Request.Context(), filters)
+	if err != nil {
+		if isActivityQueryError(err) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list sessions"})
+		return
+	}
+
+	c.JSON(http.StatusOK, sessions)
+}
+
+// getSessionHandler godoc
+// @Summary Get session
+// @Description Session totals plus the ordered timeline of activity rows interleaved with turn memories.
+// @Tags sessions
+// @Produce json
+// @Param key path string true "Session key"
+// @Success 200 {object} database.SessionDetail
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/sessions/{key} [get]
+func (s *Server) getSessionHandler(c *gin.Context) {
+	session, err := s.db.GetSession(c.Request.Context(), c.Param("key"))
+	if err != nil {
+		if errors.Is(err, database.ErrSessionNotFound) {
+			c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			return
+		}
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to load session"})
+		return
+	}
+
+	c.JSON(http.StatusOK, session)
+}
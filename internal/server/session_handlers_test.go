@@ -0,0 +1,46 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestSessionEndpointsListAndShowTimeline(t *testing.T) {
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	createActivity(t, handler, map[string]any{"session_key": "sess-1", "project_tag": "proj-alpha", "tokens_in": 10, "created_at": "2026-02-18T10:00:00Z"})
+	createActivity(t, handler, map[string]any{"session_key": "sess-1", "project_tag": "proj-alpha", "status": "failed", "created_at": "2026-02-18T10:05:00Z"})
+	performJSON(t, handler, http.MethodPost, "/api/memory", map[string]any{"session_key": "sess-1", "summary": "between turns", "created_at": "2026-02-18T10:01:00Z"})
+
+	list := performRaw(t, handler, http.MethodGet, "/api/sessions?project=proj-alpha", "", "")
+	if list.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, list.Code, list.Body.String())
+	}
+	var sessions []database.SessionSummary
+	if err := json.Unmarshal(list.Body.Bytes(), &sessions); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	if len(sessions) != 1 || sessions[0].TurnCount != 2 || sessions[0].FailureCount != 1 || sessions[0].DominantProject != "proj-alpha" {
+		t.Fatalf("expected one aggregated session, got %#v", sessions)
+	}
+
+	show := performRaw(t, handler, http.MethodGet, "/api/sessions/sess-1", "", "")
+	if show.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, show.Code, show.Body.String())
+	}
+	var detail database.SessionDetail
+	if err := json.Unmarshal(show.Body.Bytes(), &detail); err != nil {
+		t.Fatalf("expected valid json response: %v", err)
+	}
+	if len(detail.Timeline) != 3 || detail.Timeline[1].Type != "memory" || detail.Timeline[1].Memory == nil {
+		t.Fatalf("expected memory between the two activities, got %#v", detail.Timeline)
+	}
+
+	if missing := performRaw(t, handler, http.MethodGet, "/api/sessions/nope", "", ""); missing.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, missing.Code)
+	}
+}
@@ -0,0 +1,199 @@
+package server
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"clawtivity/internal/database"
+	"github.com/gin-gonic/gin"
+)
+
+type webhookInput struct {
+	database.Webhook
+	// Enabled defaults to true when omitted.
+	Enabled *bool `json:"enabled"`
+}
+
+// listWebhooksHandler godoc
+// @Summary List webhooks
+// @Description List outbound webhooks; secrets are never returned, has_secret reports whether deliveries are signed.
+// @Tags webhooks
+// @Produce json
+// @Success 200 {array} database.Webhook
+// @Failure 500 {object} APIError
+// @Router /api/webhooks [get]
+func (s *Server) listWebhooksHandler(c *gin.Context) {
+	webhooks, err := s.db.ListWebhooks(c.Request.Context())
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "failed to list webhooks"})
+		return
+	}
+
+	redacted := make([]database.Webhook, 0, len(webhooks))
+	for _, webhook := range webhooks {
+		redacted = append(redacted, webhook.Redacted())
+	}
+	c.JSON(http.StatusOK, redacted)
+}
+
+// createWebhookHandler godoc
+// @Summary Create webhook
+// @Description Register a URL to receive events (activity.failed, queue.replay_failed, pricing.refresh_failed, budget.threshold_crossed); an empty events list subscribes to all of them. With a secret, each request carries X-Clawtivity-Signature: sha256=<hex HMAC-SHA256 of the body>.
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param webhook body database.Webhook true "Webhook"
+// @Success 201 {object} database.Webhook
+// @Failure 400 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/webhooks [post]
+func (s *Server) createWebhookHandler(c *gin.Context) {
+	var input webhookInput
+	if err := c.ShouldBindJSON(&input); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook := input.Webhook
+	webhook.ID = ""
+	webhook.Enabled = input.Enabled == nil || *input.Enabled
+	if err := s.db.CreateWebhook(c.Request.Context(), &webhook); err != nil {
+		writeWebhookError(c, err, "failed to create webhook")
+		return
+	}
+
+	c.JSON(http.StatusCreated, webhook.Redacted())
+}
+
+// updateWebhookHandler godoc
+// @Summary Update webhook
+// @Tags webhooks
+// @Accept json
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param update body database.WebhookUpdate true "Fields to change"
+// @Success 200 {object} database.Webhook
+// @Failure 400 {object} APIError
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/webhooks/{id} [patch]
+func (s *Server) updateWebhookHandler(c *gin.Context) {
+	var update database.WebhookUpdate
+	if err := c.ShouldBindJSON(&update); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	webhook, err := s.db.UpdateWebhook(c.Request.Context(), c.Param("id"), update)
+	if err != nil {
+		writeWebhookError(c, err, "failed to update webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, webhook.Redacted())
+}
+
+// deleteWebhookHandler godoc
+// @Summary Delete webhook
+// @Description Delete a webhook together with its delivery log.
+// @Tags webhooks
+// @Param id path string true "Webhook ID"
+// @Success 204
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/webhooks/{id} [delete]
+func (s *Server) deleteWebhookHandler(c *gin.Context) {
+	if err := s.db.DeleteWebhook(c.Request.Context(), c.Param("id")); err != nil {
+		writeWebhookError(c, err, "failed to delete webhook")
+		return
+	}
+
+	c.Status(http.StatusNoContent)
+}
+
+// listWebhookDeliveriesHandler godoc
+// @Summary List webhook deliveries
+// @Description Delivery log of a webhook, newest first, with attempts, response status and the next retry time.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Param limit query int false "Maximum deliveries (1-1000, default 100)"
+// @Success 200 {array} database.WebhookDelivery
+// @Failure 400 {object} APIError
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/webhooks/{id}/deliveries [get]
+func (s *Server) listWebhookDeliveriesHandler(c *gin.Context) {
+	limit := 0
+	if raw := c.Query("limit"); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 || parsed > 1000 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": database.ErrInvalidLimit.Error()})
+			return
+		}
+		limit = parsed
+	}
+
+	deliveries, err := s.db.ListWebhookDeliveries(c.Request.Context(), c.Param("id"), limit)
+	if err != nil {
+		writeWebhookError(c, err, "failed to list webhook deliveries")
+		return
+	}
+
+	c.JSON(http.StatusOK, deliveries)
+}
+
+// testWebhookHandler godoc
+// @Summary Send test webhook
+// @Description Send a webhook.test event immediately, even to a disabled webhook, and return the delivery after the first attempt. A failed attempt is retried with backoff like any other delivery.
+// @Tags webhooks
+// @Produce json
+// @Param id path string true "Webhook ID"
+// @Success 200 {object} database.WebhookDelivery
+// @Failure 404 {object} APIError
+// @Failure 500 {object} APIError
+// @Router /api/webhooks/{id}/test [post]
+func (s *Server) testWebhookHandler(c *gin.Context) {
+	delivery, err := s.db.TestWebhook(c.Request.Context(), c.Param("id"))
+	if err != nil {
+		writeWebhookError(c, err, "failed to send test webhook")
+		return
+	}
+
+	c.JSON(http.StatusOK, delivery)
+}
+
+func writeWebhookError(c *gin.Context, err error, fallback string) {
+	switch {
+	case errors.Is(err, database.ErrInvalidWebhook):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, database.ErrWebhookNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	default:
+		c.JSON(http.StatusInternalServerError, gin.H{"error": fallback})
+	}
+}
+
+// notifyWebhooks queues event for the subscribed webhooks. Failing to queue
+// is logged and never fails the caller.
+func notifyWebhooks(ctx context.Context, db database.Service, event string, data any) {
+	if err := db.EnqueueWebhookEvent(ctx, event, data); err != nil {
+		logEvent("warn", "webhook_enqueue_failed", map[string]any{
+			"event": event,
+			"error": err.Error(),
+		}, currentQueueDepth())
+	}
+}
+
+// activitiesStored runs the follow-ups of every newly stored activity:
+// budget evaluation and the activity.failed webhook.
+func activitiesStored(ctx context.Context, db database.Service, activities ...*database.ActivityFeed) {
+	evaluateBudgets(ctx, db, activities...)
+	for _, activity := range activities {
+		if activity.Status == "failed" {
+			notifyWebhooks(ctx, db, database.WebhookEventActivityFailed, activity)
+		}
+	}
+}
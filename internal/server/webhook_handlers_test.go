@@ -0,0 +1,106 @@
+package server
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"clawtivity/internal/database"
+)
+
+func TestWebhookEndpointsSendSignedTestAndQueueFailedActivities(t *testing.T) {
+	var signature, body string
+	receiver := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		payload, _ := io.ReadAll(r.Body)
+		signature, body = r.Header.Get(database.WebhookSignatureHeader), string(payload)
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer receiver.Close()
+
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	if invalid := performJSON(t, handler, http.MethodPost, "/api/webhooks", map[string]any{"url": "not a url"}); invalid.Code != http.StatusBadRequest {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusBadRequest, invalid.Code, invalid.Body.String())
+	}
+	created := performJSON(t, handler, http.MethodPost, "/api/webhooks", map[string]any{
+		"url":    receiver.URL,
+		"secret": "s3cret",
+		"events": []string{"activity.failed"},
+	})
+	if created.Code != http.StatusCreated {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusCreated, created.Code, created.Body.String())
+	}
+	var webhook database.Webhook
+	if err := json.Unmarshal(created.Body.Bytes(), &webhook); err != nil {
+		t.Fatal(err)
+	}
+	if webhook.Secret != "" || !webhook.HasSecret || !webhook.Enabled {
+		t.Fatalf("expected an enabled webhook with a redacted secret, got %s", created.Body.String())
+	}
+
+	tested := performRaw(t, handler, http.MethodPost, "/api/webhooks/"+webhook.ID+"/test", "", "")
+	if tested.Code != http.StatusOK {
+		t.Fatalf("expected status %d, got %d body=%s", http.StatusOK, tested.Code, tested.Body.String())
+	}
+	var delivery database.WebhookDelivery
+	if err := json.Unmarshal(tested.Body.Bytes(), &delivery); err != nil {
+		t.Fatal(err)
+	}
+	if delivery.Status != database.WebhookDeliverySucceeded || delivery.ResponseStatus != http.StatusOK {
+		t.Fatalf("expected the test delivery to succeed, got %s", tested.Body.String())
+	}
+	if signature != database.SignWebhookPayload("s3cret", []byte(body)) {
+		t.Fatalf("expected a valid signature, got %q for %s", signature, body)
+	}
+
+	for _, status := range []string{"success", "failed"} {
+		createActivity(t, handler, map[string]any{
+			"session_key": "webhook-" + status,
+			"model":       "gpt-5-mini",
+			"tokens_in":   10,
+			"tokens_out":  10,
+			"project_tag": "clawtivity",
+			"category":    "general",
+			"thinking":    "low",
+			"channel":     "webchat",
+			"status":      status,
+			"user_id":     "u1",
+		})
+	}
+
+	list := performRaw(t, handler, http.MethodGet, "/api/webhooks/"+webhook.ID+"/deliveries", "", "")
+	var deliveries []database.WebhookDelivery
+	if err := json.Unmarshal(list.Body.Bytes(), &deliveries); err != nil {
+		t.Fatal(err)
+	}
+	if len(deliveries) != 2 || deliveries[0].Event != database.WebhookEventActivityFailed || deliveries[0].Status != database.WebhookDeliveryPending {
+		t.Fatalf("expected a queued activity.failed delivery after the test one, got %s", list.Body.String())
+	}
+
+	if missing := performRaw(t, handler, http.MethodPost, "/api/webhooks/missing/test", "", ""); missing.Code != http.StatusNotFound {
+		t.Fatalf("expected status %d, got %d", http.StatusNotFound, missing.Code)
+	}
+	if deleted := performRaw(t, handler, http.MethodDelete, "/api/webhooks/"+webhook.ID, "", ""); deleted.Code != http.StatusNoContent {
+		t.Fatalf("expected status %d, got %d", http.StatusNoContent, deleted.Code)
+	}
+}
+
+func TestWebhookListingsRequireAPIKeyWhenConfigured(t *testing.T) {
+	t.Setenv("CLAWTIVITY_API_KEY", "secret-123")
+	handler, cleanup := newTestHandler(t)
+	defer cleanup()
+
+	for _, path := range []string{"/api/webhooks", "/api/webhooks/any/deliveries"} {
+		if rr := performRaw(t, handler, http.MethodGet, path, "", ""); rr.Code != http.StatusUnauthorized {
+			t.Fatalf("expected status %d for %s, got %d body=%s", http.StatusUnauthorized, path, rr.Code, rr.Body.String())
+		}
+	}
+
+	rr := performJSONWithHeaders(t, handler, http.MethodGet, "/api/webhooks", nil, map[string]string{"X-API-Key": "secret-123"})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("expected status %d with the key, got %d body=%s", http.StatusOK, rr.Code, rr.Body.String())
+	}
+}
@@ -451,9 +451,19 @@ function extractUsage(event) {
     || (event.result && event.result.usage)
     || event.tokenUsage
     || (event.metrics && event.metrics.usage))) || {};
+  const inputDetails = usage.prompt_tokens_details || usage.input_tokens_details || {};
+  const outputDetails = usage.completion_tokens_details || usage.output_tokens_details || {};
+  // OpenAI counts cached prompt tokens inside prompt/input tokens; split them
+  // out so the API prices each bucket once. Anthropic and OpenClaw report
+  // cache reads separately already.
+  const cachedInput = asInt(inputDetails.cached_tokens, 0);
+  const input = asInt(usage.input ?? usage.input_tokens ?? usage.prompt_tokens, 0);
   return {
-    tokensIn: asInt(usage.input ?? usage.input_tokens ?? usage.prompt_tokens, 0),
+    tokensIn: Math.max(input - cachedInput, 0),
     tokensOut: asInt(usage.output ?? usage.output_tokens ?? usage.completion_tokens, 0),
+    cacheReadTokens: asInt(usage.cacheRead ?? usage.cache_read_input_tokens, cachedInput),
+    cacheWriteTokens: asInt(usage.cacheWrite ?? usage.cache_creation_input_tokens, 0),
+    reasoningTokens: asInt(usage.reasoning ?? usage.reasoning_tokens ?? outputDetails.reasoning_tokens, 0),
   };
 }
 
@@ -505,6 +515,9 @@ function coalesceSnapshot(params) {
     model: isKnownModel(currentModel) ? currentModel : priorModel,
     tokensIn: Math.max(asInt(safeCurrent.tokensIn, 0), asInt(safePrior.tokensIn, 0)),
     tokensOut: Math.max(asInt(safeCurrent.tokensOut, 0), asInt(safePrior.tokensOut, 0)),
+    cacheReadTokens: Math.max(asInt(safeCurrent.cacheReadTokens, 0), asInt(safePrior.cacheReadTokens, 0)),
+    cacheWriteTokens: Math.max(asInt(safeCurrent.cacheWriteTokens, 0), asInt(safePrior.cacheWriteTokens, 0)),
+    reasoningTokens: Math.max(asInt(safeCurrent.reasoningTokens, 0), asInt(safePrior.reasoningTokens, 0)),
     durationMs: Math.max(asInt(safeCurrent.durationMs, 0), asInt(safePrior.durationMs, 0)),
     thinking: currentThinking || priorThinking || 'low',
     reasoning: currentReasoning === undefined ? priorReasoning : currentReasoning,
@@ -538,6 +551,9 @@ function buildActivityPayload(params) {
     model,
     tokensIn,
     tokensOut,
+    cacheReadTokens,
+    cacheWriteTokens,
+    reasoningTokens,
     durationMs,
     projectTag,
     projectReason,
@@ -562,6 +578,9 @@ function buildActivityPayload(params) {
     model: asString(model, 'unknown-model'),
     tokens_in: asInt(tokensIn, 0),
     tokens_out: asInt(tokensOut, 0),
+    cache_read_tokens: asInt(cacheReadTokens, 0),
+    cache_write_tokens: asInt(cacheWriteTokens, 0),
+    reasoning_tokens: asInt(reasoningTokens, 0),
     cost_estimate: 0,
     duration_ms: asInt(durationMs, 0),
     project_tag: asString(projectTag, 'workspace'),
@@ -601,6 +620,9 @@ function mergeRecentByChannel(params) {
     model: useRecent ? recent.model : '',
     tokensIn: useRecent ? recent.tokensIn : 0,
     tokensOut: useRecent ? recent.tokensOut : 0,
+    cacheReadTokens: useRecent ? recent.cacheReadTokens : 0,
+    cacheWriteTokens: useRecent ? recent.cacheWriteTokens : 0,
+    reasoningTokens: useRecent ? recent.reasoningTokens : 0,
     durationMs: useRecent ? recent.durationMs : 0,
     projectTag: asString(projectTag, useRecent ? recent.projectTag : 'workspace'),
     projectReason: useRecent ? asString(recent.projectReason, 'fallback:workspace') : 'fallback:workspace',
@@ -926,6 +948,9 @@ module.exports = {
           model: modelFromEvent(event, ctx),
           tokensIn: usage.tokensIn,
           tokensOut: usage.tokensOut,
+          cacheReadTokens: usage.cacheReadTokens,
+          cacheWriteTokens: usage.cacheWriteTokens,
+          reasoningTokens: usage.reasoningTokens,
           durationMs: asInt(event && event.durationMs, 0),
           thinking: cognition.thinking,
           reasoning: cognition.reasoning,
@@ -985,6 +1010,9 @@ module.exports = {
           model: modelFromEvent(event, ctx),
           tokensIn: usage.tokensIn,
           tokensOut: usage.tokensOut,
+          cacheReadTokens: usage.cacheReadTokens,
+          cacheWriteTokens: usage.cacheWriteTokens,
+          reasoningTokens: usage.reasoningTokens,
           durationMs: asInt(event && event.durationMs, 0),
           thinking: cognition.thinking,
           reasoning: cognition.reasoning,
@@ -1020,6 +1048,9 @@ module.exports = {
         model: settled.model,
         tokensIn: settled.tokensIn,
         tokensOut: settled.tokensOut,
+        cacheReadTokens: settled.cacheReadTokens,
+        cacheWriteTokens: settled.cacheWriteTokens,
+        reasoningTokens: settled.reasoningTokens,
         durationMs: settled.durationMs,
         projectTag: settled.projectTag,
         projectReason: settled.projectReason,
@@ -48,6 +48,8 @@ test('mergeRecentByChannel merges llm metadata into sent payload', () => {
     model: 'gpt-5',
     tokensIn: 120,
     tokensOut: 45,
+    cacheReadTokens: 900,
+    reasoningTokens: 12,
     projectTag: 'clawtivity',
     userId: 'art',
   };
@@ -65,6 +67,9 @@ test('mergeRecentByChannel merges llm metadata into sent payload', () => {
   assert.equal(merged.model, 'gpt-5');
   assert.equal(merged.tokens_in, 120);
   assert.equal(merged.tokens_out, 45);
+  assert.equal(merged.cache_read_tokens, 900);
+  assert.equal(merged.cache_write_tokens, 0);
+  assert.equal(merged.reasoning_tokens, 12);
   assert.equal(merged.status, 'success');
   assert.equal(merged.channel, 'telegram');
   assert.equal(merged.user_id, 'art');
@@ -396,17 +401,61 @@ test('cacheInboundPrompt stores prompt even when event.from is missing', () => {
 });
 
 test('extractUsage supports multiple event usage shapes', () => {
+  const noExtras = { cacheReadTokens: 0, cacheWriteTokens: 0, reasoningTokens: 0 };
   assert.deepEqual(
     extractUsage({ usage: { input: 10, output: 20 } }),
-    { tokensIn: 10, tokensOut: 20 },
+    { tokensIn: 10, tokensOut: 20, ...noExtras },
   );
   assert.deepEqual(
     extractUsage({ usage: { input_tokens: 7, output_tokens: 9 } }),
-    { tokensIn: 7, tokensOut: 9 },
+    { tokensIn: 7, tokensOut: 9, ...noExtras },
   );
   assert.deepEqual(
     extractUsage({ usage: { prompt_tokens: 3, completion_tokens: 4 } }),
-    { tokensIn: 3, tokensOut: 4 },
+    { tokensIn: 3, tokensOut: 4, ...noExtras },
+  );
+});
+
+test('extractUsage splits cache and reasoning tokens', () => {
+  assert.deepEqual(
+    extractUsage({ usage: { input: 10, output: 20, cacheRead: 300, cacheWrite: 40 } }),
+    { tokensIn: 10, tokensOut: 20, cacheReadTokens: 300, cacheWriteTokens: 40, reasoningTokens: 0 },
+  );
+  assert.deepEqual(
+    extractUsage({
+      usage: {
+        input_tokens: 12,
+        output_tokens: 30,
+        cache_read_input_tokens: 500,
+        cache_creation_input_tokens: 60,
+      },
+    }),
+    { tokensIn: 12, tokensOut: 30, cacheReadTokens: 500, cacheWriteTokens: 60, reasoningTokens: 0 },
+  );
+  // OpenAI includes cached tokens in prompt_tokens; they must not be priced twice.
+  assert.deepEqual(
+    extractUsage({
+      usage: {
+        prompt_tokens: 1000,
+        completion_tokens: 200,
+        prompt_tokens_details: { cached_tokens: 800 },
+        completion_tokens_details: { reasoning_tokens: 150 },
+      },
+    }),
+    { tokensIn: 200, tokensOut: 200, cacheReadTokens: 800, cacheWriteTokens: 0, reasoningTokens: 150 },
+  );
+  assert.deepEqual(
+    extractUsage({
+      result: {
+        usage: {
+          input_tokens: 90,
+          output_tokens: 70,
+          input_tokens_details: { cached_tokens: 30 },
+          output_tokens_details: { reasoning_tokens: 50 },
+        },
+      },
+    }),
+    { tokensIn: 60, tokensOut: 70, cacheReadTokens: 30, cacheWriteTokens: 0, reasoningTokens: 50 },
   );
 });
 